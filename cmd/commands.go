@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/danecwalker/hippo"
+	"github.com/danecwalker/hippo/internal/diag"
+	"github.com/danecwalker/hippo/internal/format"
+	"github.com/danecwalker/hippo/internal/lexer"
+	"github.com/danecwalker/hippo/internal/parse"
+	"github.com/danecwalker/hippo/internal/syntax"
+)
+
+// diagnostics reports compile errors to stderr in the format chosen with
+// --diagnostics-format, quoting the source of every file that has been read
+// in the text format.
+var diagnostics diag.Reporter = diag.NewPrinter(os.Stderr, diag.UseColor(os.Stderr))
+
+var diagnosticsFormat string
+
+// report prints err, using diagnostics if it came from the compiler.
+func report(err error) {
+	if d, ok := err.(*diag.Diagnostic); ok {
+		diagnostics.Print(d)
+		return
+	}
+	fmt.Fprintln(os.Stderr, err)
+}
+
+// parseFile parses the program in file_name, exiting with exitUsageError if
+// it cannot be read and with exitCompileError if it does not parse.
+func parseFile(file_name string) *syntax.Program {
+	p, err := parse.NewParser(file_name)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "hippo:", err)
+		exit(exitUsageError)
+	}
+
+	diagnostics.AddFile(p.File())
+
+	prog := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		diagnostics.Print(p.Errors()...)
+		exit(exitCompileError)
+	}
+	return prog
+}
+
+// compileFile compiles the program in file_name with options, exiting with
+// exitUsageError if it cannot be read and with exitCompileError if it does
+// not compile.
+func compileFile(file_name string, options *hippo.Options) *hippo.Result {
+	src, err := os.ReadFile(file_name)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "hippo:", err)
+		exit(exitUsageError)
+	}
+
+	file := syntax.NewSourceFile(file_name, src)
+	diagnostics.AddFile(file)
+
+	result, diags := hippo.Compile([]hippo.Source{{Name: file_name, Text: src}}, options)
+	if len(diags) > 0 {
+		for _, d := range diags {
+			diagnostics.Print(toDiagnostic(file, d))
+		}
+		exit(exitCompileError)
+	}
+	return result
+}
+
+// toDiagnostic converts d back into the form diagnostics renders. Edits
+// only carry byte offsets, so their positions are looked up in file.
+func toDiagnostic(file *syntax.SourceFile, d *hippo.Diagnostic) *diag.Diagnostic {
+	nd := &diag.Diagnostic{
+		Code:     diag.Code(d.Code),
+		Severity: toSeverity(d.Severity),
+		Pos:      toPosition(d.Filename, d.Line, d.Column, d.Offset),
+		End:      toPosition(d.Filename, d.EndLine, d.EndColumn, d.EndOffset),
+		Msg:      d.Message,
+	}
+	for _, r := range d.Related {
+		nd.Related = append(nd.Related, &diag.Label{
+			Pos: toPosition(r.Filename, r.Line, r.Column, r.Offset),
+			End: toPosition(r.Filename, r.EndLine, r.EndColumn, r.EndOffset),
+			Msg: r.Message,
+		})
+	}
+	for _, f := range d.Fixes {
+		var edits []*diag.Edit
+		for _, e := range f.Edits {
+			edits = append(edits, diag.NewEdit(file.Position(e.Offset), file.Position(e.EndOffset), e.NewText))
+		}
+		nd.WithFix(f.Message, edits...)
+	}
+	return nd
+}
+
+// toPosition returns the position at line and column of filename, or the
+// zero Position if line is 0.
+func toPosition(filename string, line, column, offset int) syntax.Position {
+	if line == 0 {
+		return syntax.Position{}
+	}
+	return *syntax.NewPosition(offset, line, column, filename)
+}
+
+func toSeverity(s string) diag.Severity {
+	switch s {
+	case diag.SeverityWarning.String():
+		return diag.SeverityWarning
+	case diag.SeverityNote.String():
+		return diag.SeverityNote
+	default:
+		return diag.SeverityError
+	}
+}
+
+func runBuild(fs *flag.FlagSet, args []string) int {
+	output := fs.String("o", "", "write the executable to `file`")
+	asmOnly := fs.Bool("S", false, "write assembly instead of an executable")
+	file_name, ok := parseArgs(fs, args)
+	if !ok {
+		return exitUsageError
+	}
+
+	result := compileFile(file_name, &hippo.Options{Assembly: true})
+
+	if *asmOnly {
+		if *output == "" {
+			*output = outputName(file_name, ".s")
+		}
+		return writeOutput(*output, result.Assembly)
+	}
+
+	if *output == "" {
+		*output = outputName(file_name, "")
+	}
+	if err := result.Build(*output); err != nil {
+		report(err)
+		return exitCompileError
+	}
+
+	return exitOK
+}
+
+// runRun interprets the program and exits with the value returned by main,
+// the same status a binary produced by build would exit with.
+func runRun(fs *flag.FlagSet, args []string) int {
+	file_name, ok := parseArgs(fs, args)
+	if !ok {
+		return exitUsageError
+	}
+
+	status, err := compileFile(file_name, nil).Run(os.Stdout)
+	if err != nil {
+		report(err)
+		return exitRuntimeError
+	}
+	return status
+}
+
+func runCheck(fs *flag.FlagSet, args []string) int {
+	file_name, ok := parseArgs(fs, args)
+	if !ok {
+		return exitUsageError
+	}
+
+	compileFile(file_name, nil)
+	return exitOK
+}
+
+func runTokens(fs *flag.FlagSet, args []string) int {
+	output := fs.String("o", "", "write to `file` instead of stdout")
+	file_name, ok := parseArgs(fs, args)
+	if !ok {
+		return exitUsageError
+	}
+
+	lex, err := lexer.NewLexer(file_name)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "hippo:", err)
+		return exitUsageError
+	}
+
+	diagnostics.AddFile(lex.File())
+
+	var w bytes.Buffer
+	for {
+		tok := lex.NextToken()
+		fmt.Fprintf(&w, "%s\t%s\t%q\n", tok.Position, tok.Type, tok.Literal)
+		if tok.IsEOF() {
+			break
+		}
+	}
+
+	if errs := lex.Errors(); len(errs) > 0 {
+		diagnostics.Print(errs...)
+		writeOutput(*output, w.Bytes())
+		return exitCompileError
+	}
+
+	return writeOutput(*output, w.Bytes())
+}
+
+func runAST(fs *flag.FlagSet, args []string) int {
+	output := fs.String("o", "", "write to `file` instead of stdout")
+	file_name, ok := parseArgs(fs, args)
+	if !ok {
+		return exitUsageError
+	}
+
+	var w bytes.Buffer
+	parseFile(file_name).Fprint(&w)
+	return writeOutput(*output, w.Bytes())
+}
+
+func runIR(fs *flag.FlagSet, args []string) int {
+	output := fs.String("o", "", "write to `file` instead of stdout")
+	file_name, ok := parseArgs(fs, args)
+	if !ok {
+		return exitUsageError
+	}
+
+	return writeOutput(*output, compileFile(file_name, &hippo.Options{IR: true}).IR)
+}
+
+func runFmt(fs *flag.FlagSet, args []string) int {
+	write := fs.Bool("w", false, "write the result back to the source file")
+	file_name, ok := parseArgs(fs, args)
+	if !ok {
+		return exitUsageError
+	}
+
+	src := format.Program(parseFile(file_name))
+	if *write {
+		return writeOutput(file_name, src)
+	}
+	return writeOutput("", src)
+}
+
+func runHelp(fs *flag.FlagSet, args []string) int {
+	if len(args) == 0 {
+		usage(os.Stdout)
+		return exitOK
+	}
+
+	cmd := lookupCommand(args[0])
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "hippo: unknown command %q\n", args[0])
+		return exitUsageError
+	}
+
+	fmt.Fprintf(os.Stdout, "usage: hippo %s %s\n\n%s\n", cmd.name, cmd.args, cmd.summary)
+	return exitOK
+}
+
+func writeOutput(path string, data []byte) int {
+	w, err := createOutput(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsageError
+	}
+	defer w.Close()
+
+	if _, err := io.Copy(w, bytes.NewReader(data)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitCompileError
+	}
+	return exitOK
+}
@@ -1,32 +1,143 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 
-	"github.com/danecwalker/hippo/internal/intermediate"
-	"github.com/danecwalker/hippo/internal/parse"
+	"github.com/danecwalker/hippo/internal/diag"
 )
 
+// Exit codes.
+const (
+	exitOK           = 0
+	exitCompileError = 1
+	exitUsageError   = 2
+	exitRuntimeError = 2 // as a compiled program exits when it panics
+)
+
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(fs *flag.FlagSet, args []string) int
+}
+
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{"build", "[-o output] [-S] file.x", "compile a program to an executable", runBuild},
+		{"run", "file.x", "interpret a program and exit with main's result", runRun},
+		{"check", "file.x", "report compile errors without producing output", runCheck},
+		{"tokens", "[-o output] file.x", "print the token stream", runTokens},
+		{"ast", "[-o output] file.x", "print the syntax tree", runAST},
+		{"ir", "[-o output] file.x", "print the intermediate representation", runIR},
+		{"fmt", "[-w] file.x", "print a program in canonical format", runFmt},
+		{"help", "[command]", "show usage", runHelp},
+	}
+}
+
 func main() {
-	progam_name := filepath.Base(os.Args[0])
-	_ = progam_name
-	if len(os.Args[1:]) < 1 {
-		fmt.Fprintln(os.Stderr, "No input file")
-		os.Exit(1)
+	exit(dispatch(os.Args[1:]))
+}
+
+// exit writes out any diagnostics still held back and exits with code.
+func exit(code int) {
+	if err := diagnostics.Flush(); err != nil {
+		fmt.Fprintln(os.Stderr, "hippo:", err)
+	}
+	os.Exit(code)
+}
+
+func dispatch(args []string) int {
+	if len(args) < 1 {
+		usage(os.Stderr)
+		return exitUsageError
+	}
+
+	name := args[0]
+	if name == "-h" || name == "--help" {
+		name = "help"
+	}
+
+	cmd := lookupCommand(name)
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "hippo: unknown command %q\n", args[0])
+		usage(os.Stderr)
+		return exitUsageError
+	}
+
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: hippo %s %s\n", cmd.name, cmd.args)
+		fs.PrintDefaults()
 	}
+	if cmd.name != "help" {
+		fs.StringVar(&diagnosticsFormat, "diagnostics-format", "text", "write diagnostics as `format`: "+strings.Join(diag.Formats, ", "))
+	}
+
+	return cmd.run(fs, args[1:])
+}
+
+func lookupCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
 
-	file_name := os.Args[1]
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: hippo <command> [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-8s %s\n", cmd.name, cmd.summary)
+	}
+}
 
-	fmt.Println("Parsing file: ", file_name)
+// parseArgs parses the flags of fs and returns the single input file.
+func parseArgs(fs *flag.FlagSet, args []string) (string, bool) {
+	if err := fs.Parse(args); err != nil {
+		return "", false
+	}
 
-	par := parse.NewParser(file_name)
+	r, err := diag.NewReporter(diagnosticsFormat, os.Stderr, diag.UseColor(os.Stderr))
+	if err != nil {
+		fmt.Fprintln(fs.Output(), "hippo:", err)
+		fs.Usage()
+		return "", false
+	}
+	diagnostics = r
+
+	if fs.NArg() != 1 {
+		fmt.Fprintln(fs.Output(), "hippo: expected exactly one input file")
+		fs.Usage()
+		return "", false
+	}
+
+	return fs.Arg(0), true
+}
+
+// createOutput opens path for writing, or stdout when path is empty.
+func createOutput(path string) (io.WriteCloser, error) {
+	if path == "" || path == "-" {
+		return nopCloser{os.Stdout}, nil
+	}
+	return os.Create(path)
+}
+
+type nopCloser struct {
+	io.Writer
+}
 
-	prog := par.ParseProgram()
-	_ = prog
-	prog.PrettyPrint()
+func (nopCloser) Close() error { return nil }
 
-	ir := intermediate.NewIR()
-	ir.Generate(prog)
+func outputName(file_name string, ext string) string {
+	return strings.TrimSuffix(filepath.Base(file_name), filepath.Ext(file_name)) + ext
 }
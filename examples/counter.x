@@ -1,6 +1,6 @@
 fn main {
   var count = 0
   for i <- 0..upper {
-    count = count + i
+    count += i
   }
 }
\ No newline at end of file
@@ -0,0 +1,257 @@
+// Package hippo compiles Hippo programs. It runs the same pipeline as the
+// hippo command, lexing, parsing, type checking and lowering to IR, but
+// returns every problem it finds as a value instead of printing it and
+// exiting, so the compiler can be embedded in other tools.
+package hippo
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/danecwalker/hippo/internal/codegen"
+	"github.com/danecwalker/hippo/internal/diag"
+	"github.com/danecwalker/hippo/internal/intermediate"
+	"github.com/danecwalker/hippo/internal/interp"
+	"github.com/danecwalker/hippo/internal/parse"
+	"github.com/danecwalker/hippo/internal/syntax"
+	"github.com/danecwalker/hippo/internal/types"
+)
+
+// Source is a named piece of program text. The name is only used in
+// diagnostics.
+type Source struct {
+	Name string
+	Text []byte
+}
+
+// Options controls what Compile produces beyond checking the program.
+type Options struct {
+	// Assembly requests x86_64 assembly for the program in
+	// Result.Assembly.
+	Assembly bool
+
+	// IR requests the intermediate representation of the program, as
+	// printed by hippo ir, in Result.IR.
+	IR bool
+}
+
+// Result is a program that compiled without errors.
+type Result struct {
+	// Assembly holds the GNU assembler source of the program when
+	// Options.Assembly was set.
+	Assembly []byte
+
+	// IR holds the intermediate representation of the program when
+	// Options.IR was set.
+	IR []byte
+
+	prog *syntax.Program
+	ir   *intermediate.IR
+}
+
+// Diagnostic is a problem found in a program. Line and Column count from 1
+// and are 0 when the problem has no position in the source; EndLine and
+// EndColumn mark just past the end of the offending text and are 0 when
+// only its start is known. Offset and EndOffset are the same places as byte
+// offsets into the source.
+type Diagnostic struct {
+	Code      string // stable identifier of the kind of problem, such as "E0101"
+	Severity  string // "error", "warning" or "note"
+	Filename  string
+	Line      int
+	Column    int
+	EndLine   int
+	EndColumn int
+	Offset    int
+	EndOffset int
+	Message   string
+
+	// Related holds notes pointing at other places in the program, such as
+	// the earlier declaration of a redeclared name.
+	Related []*Diagnostic
+
+	// Fixes holds suggested changes that resolve the problem.
+	Fixes []*Fix
+}
+
+// Fix is a suggested change to the program.
+type Fix struct {
+	Message string
+	Edits   []*Edit
+}
+
+// Edit replaces the bytes of Filename from Offset up to EndOffset with
+// NewText.
+type Edit struct {
+	Filename  string
+	Offset    int
+	EndOffset int
+	NewText   string
+}
+
+func (d *Diagnostic) Error() string {
+	if d.Filename == "" {
+		return fmt.Sprintf("%s: %s", d.Severity, d.Message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s: %s", d.Filename, d.Line, d.Column, d.Severity, d.Message)
+}
+
+// Diagnostics is the list of problems found by Compile, in the order they
+// were found.
+type Diagnostics []*Diagnostic
+
+func (ds Diagnostics) Error() string {
+	msgs := make([]string, len(ds))
+	for i, d := range ds {
+		msgs[i] = d.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Err returns ds as an error, or nil if it is empty.
+func (ds Diagnostics) Err() error {
+	if len(ds) == 0 {
+		return nil
+	}
+	return ds
+}
+
+func newDiagnostic(d *diag.Diagnostic) *Diagnostic {
+	nd := newDiagnosticAt(d.Severity, &d.Pos, &d.End, d.Msg)
+	nd.Code = string(d.Code)
+	for _, l := range d.Related {
+		nd.Related = append(nd.Related, newDiagnosticAt(diag.SeverityNote, &l.Pos, &l.End, l.Msg))
+	}
+	for _, f := range d.Fixes {
+		fix := &Fix{
+			Message: f.Msg,
+		}
+		for _, e := range f.Edits {
+			fix.Edits = append(fix.Edits, &Edit{
+				Filename:  e.Pos.Filename,
+				Offset:    e.Pos.Offset,
+				EndOffset: e.End.Offset,
+				NewText:   e.NewText,
+			})
+		}
+		nd.Fixes = append(nd.Fixes, fix)
+	}
+	return nd
+}
+
+func newDiagnosticAt(severity diag.Severity, pos, end *syntax.Position, msg string) *Diagnostic {
+	return &Diagnostic{
+		Severity:  severity.String(),
+		Filename:  pos.Filename,
+		Line:      pos.Line,
+		Column:    pos.Column,
+		EndLine:   end.Line,
+		EndColumn: end.Column,
+		Offset:    pos.Offset,
+		EndOffset: end.Offset,
+		Message:   msg,
+	}
+}
+
+// Compile compiles the program made of sources, whose top level
+// declarations are combined in order. Each stage runs only if the stages
+// before it succeeded, and every error of the first failing stage is
+// returned. The Result is nil if there were any errors.
+func Compile(sources []Source, options *Options) (*Result, Diagnostics) {
+	if options == nil {
+		options = &Options{}
+	}
+
+	var diags Diagnostics
+	prog := syntax.NewProgram()
+	for _, src := range sources {
+		p := parse.NewParserFromBytes(src.Name, src.Text)
+		file := p.ParseProgram()
+		for _, err := range p.Errors() {
+			diags = append(diags, newDiagnostic(err))
+		}
+		prog.Statements = append(prog.Statements, file.Statements...)
+		prog.Comments = append(prog.Comments, file.Comments...)
+	}
+	if len(diags) > 0 {
+		return nil, diags
+	}
+
+	c := types.NewChecker()
+	c.Check(prog)
+	for _, err := range c.Errors() {
+		diags = append(diags, newDiagnostic(err))
+	}
+	if len(diags) > 0 {
+		return nil, diags
+	}
+
+	ir := intermediate.NewIR()
+	ir.Generate(prog)
+	for _, err := range ir.Errors() {
+		diags = append(diags, newDiagnostic(err))
+	}
+	if len(diags) > 0 {
+		return nil, diags
+	}
+
+	result := &Result{
+		prog: prog,
+		ir:   ir,
+	}
+
+	if options.IR {
+		var w bytes.Buffer
+		ir.Fprint(&w)
+		result.IR = w.Bytes()
+	}
+
+	if options.Assembly {
+		gen := codegen.NewGenerator(ir)
+		result.Assembly = gen.Generate()
+		for _, err := range gen.Errors() {
+			diags = append(diags, newDiagnostic(err))
+		}
+		if len(diags) > 0 {
+			return nil, diags
+		}
+	}
+
+	return result, nil
+}
+
+// Run interprets the program, writing the output of print to stdout, and
+// returns the value of main as an exit status. A nil stdout leaves the
+// output on os.Stdout.
+func (r *Result) Run(stdout io.Writer) (int, error) {
+	in := interp.NewInterpreter()
+	if stdout != nil {
+		in.Stdout = stdout
+	}
+
+	v, err := in.Run(r.prog)
+	if err != nil {
+		return 0, err
+	}
+
+	if i, ok := v.(*interp.Int); ok {
+		return int(i.Value), nil
+	}
+	return 0, nil
+}
+
+// Build assembles and links the program into a static executable at
+// output, using the system assembler and linker.
+func (r *Result) Build(output string) error {
+	asm := r.Assembly
+	if asm == nil {
+		gen := codegen.NewGenerator(r.ir)
+		asm = gen.Generate()
+		if errs := gen.Errors(); len(errs) > 0 {
+			return errs[0]
+		}
+	}
+	return codegen.Build(asm, output)
+}
@@ -0,0 +1,538 @@
+package hippo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/danecwalker/hippo/internal/diag"
+)
+
+// run compiles and interprets sources, failing the test if they do not
+// compile, and returns the exit status of main.
+func run(t *testing.T, sources ...Source) int {
+	t.Helper()
+
+	result, diags := Compile(sources, nil)
+	if err := diags.Err(); err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+
+	var stdout bytes.Buffer
+	status, err := result.Run(&stdout)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	return status
+}
+
+// runNative builds the program in src and runs it, returning its exit
+// status and output. It skips the test if the assembler or linker is
+// missing.
+func runNative(t *testing.T, src string) (int, string, string) {
+	t.Helper()
+
+	for _, tool := range []string{"as", "ld"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s not found", tool)
+		}
+	}
+
+	result, diags := Compile([]Source{{Name: "test.x", Text: []byte(src)}}, nil)
+	if err := diags.Err(); err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+
+	exe := filepath.Join(t.TempDir(), "test")
+	if err := result.Build(exe); err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(exe)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	var exit *exec.ExitError
+	if err != nil && !errors.As(err, &exit) {
+		t.Fatalf("running %s: %v", exe, err)
+	}
+	return cmd.ProcessState.ExitCode(), stdout.String(), stderr.String()
+}
+
+// runBoth interprets src and runs it natively, failing the test unless both
+// exit with want.
+func runBoth(t *testing.T, src string, want int) {
+	t.Helper()
+
+	if got := run(t, Source{Name: "test.x", Text: []byte(src)}); got != want {
+		t.Errorf("interpreted: status = %d, want %d", got, want)
+	}
+	if got, _, stderr := runNative(t, src); got != want {
+		t.Errorf("native: status = %d, want %d\n%s", got, want, stderr)
+	}
+}
+
+// runPanic interprets src and runs it natively, failing the test unless the
+// interpreter stops with a runtime error for want and the native program
+// reports the same panic and exits with status 2.
+func runPanic(t *testing.T, src, want string) {
+	t.Helper()
+
+	result, diags := Compile([]Source{{Name: "test.x", Text: []byte(src)}}, nil)
+	if err := diags.Err(); err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	_, err := result.Run(&bytes.Buffer{})
+	var d *diag.Diagnostic
+	if !errors.As(err, &d) || d.Msg != want {
+		t.Fatalf("interpreted: got error %v, want %q", err, want)
+	}
+
+	status, _, stderr := runNative(t, src)
+	if wantErr := fmt.Sprintf("%s: panic: %s\n", d.Pos.String(), want); status != 2 || stderr != wantErr {
+		t.Errorf("native: status = %d, stderr = %q, want 2, %q", status, stderr, wantErr)
+	}
+}
+
+func TestForLoopClauses(t *testing.T) {
+	runBoth(t, `
+fn forever -> i32 {
+  for ; ; {
+    ret 3
+  }
+}
+
+fn main -> i32 {
+  var total = 0
+  var i i32
+  for i = 0; i < 4; i++ {
+    total += i
+  }
+  for ; i < 6; i++ {
+    total += 10
+  }
+  for var k = 0; ; k++ {
+    if k == 2 {
+      break
+    }
+    total += 100
+  }
+  ret total + forever()
+}
+`, 229)
+}
+
+func TestForBoolCondition(t *testing.T) {
+	runBoth(t, `
+fn main -> i32 {
+  var done = false
+  var n = 0
+  for !done {
+    n++
+    done = n == 3
+  }
+  for done {
+    n += 10
+    done = false
+  }
+  ret n
+}
+`, 13)
+}
+
+func TestCompileDeclarationOrder(t *testing.T) {
+	status := run(t, Source{Name: "order.x", Text: []byte(`
+fn main -> i32 {
+  ret twice() + x
+}
+
+fn twice -> i32 {
+  ret x * 2
+}
+
+var x = 7
+`)})
+	if status != 21 {
+		t.Errorf("status = %d, want 21", status)
+	}
+}
+
+func TestCompileDeclarationOrderAcrossSources(t *testing.T) {
+	status := run(t,
+		Source{Name: "main.x", Text: []byte("fn main -> i32 {\n  ret limit + 1\n}\n")},
+		Source{Name: "limit.x", Text: []byte("var limit = 41\n")},
+	)
+	if status != 42 {
+		t.Errorf("status = %d, want 42", status)
+	}
+}
+
+func TestDivideByZero(t *testing.T) {
+	runPanic(t, `
+fn main -> i32 {
+  var n = 7
+  var d = 0
+  ret n / d
+}
+`, "integer divide by zero")
+}
+
+func TestDivideByMinusOne(t *testing.T) {
+	runBoth(t, `
+fn main -> i32 {
+  var min i64 = -9223372036854775807 - 1
+  var d i64 = -1
+  var small i8 = -128
+  var status = 0
+  if min / d == min {
+    status += 1
+  }
+  if small / i8(d) == small {
+    status += 2
+  }
+  var x = 12
+  x /= -1
+  if x == -12 {
+    status += 4
+  }
+  ret status
+}
+`, 7)
+}
+
+func TestRemainderByZero(t *testing.T) {
+	runPanic(t, `
+fn main -> i32 {
+  var n = 7
+  var d = 0
+  n %= d
+  ret n
+}
+`, "integer divide by zero")
+}
+
+func TestRemainderByMinusOne(t *testing.T) {
+	runBoth(t, `
+fn main -> i32 {
+  var min i64 = -9223372036854775807 - 1
+  var d i64 = -1
+  var small i8 = -128
+  var status = 0
+  if min % d == 0 {
+    status += 1
+  }
+  if small % i8(d) == 0 {
+    status += 2
+  }
+  if -7 % 2 == -1 && 7 % -1 == 0 {
+    status += 4
+  }
+  ret status
+}
+`, 7)
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		sources []Source
+		code    string
+		file    string
+		line    int
+	}{
+		{
+			"parse",
+			[]Source{{Name: "parse.x", Text: []byte("fn main -> i32 {\n  var x =\n}\n")}},
+			"E0005", "parse.x", 3,
+		},
+		{
+			"type",
+			[]Source{{Name: "type.x", Text: []byte("fn main -> i32 {\n  var x i32 = \"s\"\n  ret x\n}\n")}},
+			"E0203", "type.x", 2,
+		},
+		{
+			"constant overflow",
+			[]Source{{Name: "const.x", Text: []byte("const c = 300\n\nfn main -> i32 {\n  var b u8 = c\n  ret 0\n}\n")}},
+			"E0212", "const.x", 4,
+		},
+		{
+			"ir",
+			[]Source{{Name: "ir.x", Text: []byte("fn main -> i32 {\n  var r = 0..3\n  ret 0\n}\n")}},
+			"E0300", "ir.x", 2,
+		},
+		{
+			"multi-file",
+			[]Source{
+				{Name: "main.x", Text: []byte("fn main -> i32 {\n  ret limit\n}\n")},
+				{Name: "limit.x", Text: []byte("var limit = 41\n\nfn f -> i32 {\n  ret missing\n}\n")},
+			},
+			"E0101", "limit.x", 4,
+		},
+	}
+
+	for _, test := range tests {
+		result, diags := Compile(test.sources, nil)
+		if result != nil {
+			t.Errorf("%s: got a Result despite errors", test.name)
+		}
+		if len(diags) == 0 {
+			t.Errorf("%s: got no diagnostics", test.name)
+			continue
+		}
+		d := diags[0]
+		if d.Code != test.code || d.Filename != test.file || d.Line != test.line {
+			t.Errorf("%s: got %s %s, want %s at %s:%d", test.name, d.Code, d, test.code, test.file, test.line)
+		}
+	}
+}
+
+func TestManyResults(t *testing.T) {
+	runBoth(t, `
+fn mk : p1, p2, p3, p4, p5, p6, p7, p8 i32 -> i32, i32, i32, i32, i32, i32, i32, i32, i32, i32 {
+  ret p1, p2, p3, p4, p5, p6, p7, p8, 9, 10
+}
+
+fn pass -> i32, i32, i32, i32, i32, i32, i32, i32, i32, i32 {
+  ret mk(1, 2, 3, 4, 5, 6, 7, 8)
+}
+
+fn main -> i32 {
+  var a, b, c, d, e, f, g, h, i, j = pass()
+  mk(1, 1, 1, 1, 1, 1, 1, 1)
+  a, b, c, d, e, f, g, h, i, j = mk(j, i, h, g, f, e, d, c)
+  ret a + b * 2 + c * 3 + d * 4 + e * 5 + f * 6 + g * 7 + h * 8 + i * 9 + j * 10 - 256
+}
+`, 117)
+}
+
+func TestLargeResults(t *testing.T) {
+	runBoth(t, `
+type Big struct {
+  a, b, c, d, e, f, g, h, i, j i32
+}
+
+fn make : n i32 -> Big {
+  ret Big{n, n + 1, n + 2, n + 3, n + 4, n + 5, n + 6, n + 7, n + 8, n + 9}
+}
+
+fn shift : b Big -> Big {
+  b.a = b.j
+  ret b
+}
+
+fn slices -> []i32, []i32, []i32, []i32 {
+  var a [3]i32
+  ret a[0..1], a[0..2], a[0..3], a[1..3]
+}
+
+fn main -> i32 {
+  var b = shift(make(10))
+  var w, x, y, z = slices()
+  ret b.a + b.j + len(w) + len(x) * 10 + len(y) * 100 + len(z) - 256
+}
+`, 105)
+}
+
+func TestNestedFunctionNames(t *testing.T) {
+	runBoth(t, `
+fn h -> i32 {
+  ret 1
+}
+
+fn a -> i32 {
+  fn h -> i32 {
+    ret 10
+  }
+  ret h()
+}
+
+fn b -> i32 {
+  fn h -> i32 {
+    ret 100
+  }
+  ret h()
+}
+
+fn main -> i32 {
+  ret h() + a() + b()
+}
+`, 111)
+}
+
+func TestRangeAssignKey(t *testing.T) {
+	runBoth(t, `
+fn main -> i32 {
+  var total = 0
+  var runs = 0
+  for i <- 0..3 {
+    i += 10
+    total += i
+    runs++
+  }
+  for k <- 2..6 {
+    if k == 3 {
+      k = 100
+      continue
+    }
+    total += k
+  }
+  var n u8 = 250
+  for j <- n..255 {
+    j = 0
+    runs++
+  }
+  ret total * 10 + runs - 256
+}
+`, 192)
+}
+
+func TestArrayValues(t *testing.T) {
+	runBoth(t, `
+fn fill : n i32 -> [4]i32 {
+  var a [4]i32
+  a[0] = n
+  ret a
+}
+
+fn wrap : n i32 -> [4]i32, i32 {
+  ret fill(n), n
+}
+
+fn part : n i32 -> []i32 {
+  var a [3]i32
+  a[0] = n
+  ret a[0..2]
+}
+
+fn rec : n i32, a [4]i32 -> [4]i32 {
+  if n == 0 {
+    ret a
+  }
+  a[n] = n
+  var r = rec(n - 1, a)
+  r[0] += a[n] * 10
+  ret r
+}
+
+fn main -> i32 {
+  var p = fill(1)
+  var q, k = wrap(2)
+  var keep [4]i32
+  for i <- 0..3 {
+    var b = fill(i + 5)
+    if i == 0 {
+      keep = b
+    }
+  }
+  var x = part(3)
+  var y = part(4)
+  var first []i32
+  for i <- 0..3 {
+    var c [2]i32
+    c[0] = i + 1
+    if i == 0 {
+      first = c[0..1]
+    }
+  }
+  var r = rec(3, p)
+  ret p[0] + q[0] * 2 + k + keep[0] + x[0] * 3 + y[0] + first[0] + r[0] + r[3]
+}
+`, 90)
+}
+
+// TestArrayCopiesInFrame checks that copying arrays in a loop does not take
+// memory from the heap, which is never freed.
+func TestArrayCopiesInFrame(t *testing.T) {
+	const src = `
+fn sum : a [64]i32 -> i32 {
+  var t = 0
+  for x <- a {
+    t += x
+  }
+  ret t
+}
+
+fn fill : n i32 -> [64]i32 {
+  var a [64]i32
+  a[0] = n
+  a[63] = 1
+  ret a
+}
+
+fn main -> i32 {
+  var a [64]i32
+  a[5] = 2
+  var total = 0
+  for i <- 0..1000 {
+    var b = fill(i)
+    a = b
+    total = sum(a) + sum(fill(1))
+  }
+  ret total - 1000 + 1
+}
+`
+	runBoth(t, src, 3)
+
+	result, diags := Compile([]Source{{Name: "test.x", Text: []byte(src)}}, &Options{Assembly: true})
+	if err := diags.Err(); err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	if bytes.Contains(result.Assembly, []byte("hippo.rt.alloc")) {
+		t.Errorf("arrays are allocated on the heap:\n%s", result.Assembly)
+	}
+}
+
+func TestUntypedConstants(t *testing.T) {
+	runBoth(t, `
+const big = 1 << 62
+const small = 200
+
+fn main -> i32 {
+  var x i64 = big
+  var b u8 = small
+  b += 100
+  var f f64 = small
+  const neg = -3
+  var y i8 = neg * 2
+  if x >> 60 != 4 || f != 200.0 {
+    ret 1
+  }
+  ret i32(b) + i32(y)
+}
+`, 38)
+}
+
+// TestRunNilStdout checks that Run without a writer prints to os.Stdout.
+func TestRunNilStdout(t *testing.T) {
+	result, diags := Compile([]Source{{Name: "test.x", Text: []byte("fn main -> i32 {\n  print(\"hi\")\n  ret 3\n}\n")}}, nil)
+	if err := diags.Err(); err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	status, err := result.Run(nil)
+	os.Stdout = stdout
+	w.Close()
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != 3 || string(out) != "hi" {
+		t.Errorf("Run(nil) = %d with output %q, want 3 with output %q", status, out, "hi")
+	}
+}
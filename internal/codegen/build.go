@@ -0,0 +1,38 @@
+package codegen
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// Build assembles asm and links it into a static executable at output
+// using the system assembler and linker.
+func Build(asm []byte, output string) error {
+	dir, err := os.MkdirTemp("", "hippo")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "out.s")
+	obj := filepath.Join(dir, "out.o")
+
+	if err := os.WriteFile(src, asm, 0o644); err != nil {
+		return err
+	}
+
+	if err := run("as", "--64", "-o", obj, src); err != nil {
+		return err
+	}
+
+	return run("ld", "-static", "-o", output, obj)
+}
+
+func run(tool string, args ...string) error {
+	out, err := exec.Command(tool, args...).CombinedOutput()
+	if err != nil {
+		return NewToolError(tool, err, out)
+	}
+	return nil
+}
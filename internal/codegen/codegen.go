@@ -0,0 +1,1093 @@
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+
+	"github.com/danecwalker/hippo/internal/intermediate"
+	"github.com/danecwalker/hippo/internal/syntax"
+)
+
+// System V AMD64 integer argument registers, in order.
+var argRegs = []string{"%rdi", "%rsi", "%rdx", "%rcx", "%r8", "%r9"}
+
+// Registers holding the results of a call, in order. A single result is
+// returned in %rax as usual. A call with more results than there are
+// registers returns all of them in memory the caller reserves above its
+// arguments, laid out as if the results had been pushed in order.
+var resultRegs = []string{"%rax", "%rdx", "%rcx", "%rsi", "%rdi", "%r8", "%r9", "%r10", "%r11"}
+
+// setcc maps signed comparison operators to the instruction that stores
+// their result in a byte register.
+var setcc = map[string]string{
+	"<":  "setl",
+	">":  "setg",
+	"<=": "setle",
+	">=": "setge",
+	"==": "sete",
+	"!=": "setne",
+}
+
+// setccUnsigned is setcc for unsigned operands.
+var setccUnsigned = map[string]string{
+	"<":  "setb",
+	">":  "seta",
+	"<=": "setbe",
+	">=": "setae",
+	"==": "sete",
+	"!=": "setne",
+}
+
+// extend holds the instruction that truncates %rax to the width of an
+// integer type and extends it back to 64 bits. Signed values are kept sign
+// extended and unsigned values zero extended.
+var extend = map[string]string{
+	"i8":  "movsbq %al, %rax",
+	"i16": "movswq %ax, %rax",
+	"i32": "movslq %eax, %rax",
+	"u8":  "movzbl %al, %eax",
+	"u16": "movzwl %ax, %eax",
+	"u32": "movl %eax, %eax",
+}
+
+type Generator struct {
+	ir  *intermediate.IR
+	out bytes.Buffer
+
+	globals map[*syntax.Object]string
+	funcs   map[*syntax.FuncStmt]string
+	slots   map[*syntax.Object]int
+	ret     string
+	results int // offset from %rbp of the memory results of the function
+	arrays  int // bytes of array storage below the slots of the function
+	heap    bool
+	labels  int
+
+	strings map[string]string
+	runtime map[string]bool
+
+	errors []*Error
+}
+
+func NewGenerator(ir *intermediate.IR) *Generator {
+	return &Generator{
+		ir:      ir,
+		globals: map[*syntax.Object]string{},
+		funcs:   map[*syntax.FuncStmt]string{},
+		strings: map[string]string{},
+		runtime: map[string]bool{},
+	}
+}
+
+func (g *Generator) Errors() []*Error {
+	return g.errors
+}
+
+// Generate lowers the IR to GNU assembler source for x86_64 Linux. Every
+// value lives in an 8 byte stack slot and expressions are evaluated into
+// %rax, using the machine stack for temporaries. Floats are held as the bits
+// of an f64, with f32 values rounded to single precision.
+func (g *Generator) Generate() []byte {
+	if len(g.ir.Blocks) == 0 {
+		g.errors = append(g.errors, NewMissingMainError())
+		return nil
+	}
+
+	global := g.ir.Blocks[0]
+	for _, obj := range global.Objects {
+		if obj.Kind == syntax.ObjKindVar || obj.Kind == syntax.ObjKindConst {
+			g.globals[obj] = symbol(obj.Name)
+		}
+	}
+
+	main, ok := global.Objects["main"]
+	if !ok || main.Kind != syntax.ObjKindFunc {
+		g.errors = append(g.errors, NewMissingMainError())
+		return nil
+	}
+
+	fns := g.functions()
+	for _, fn := range fns {
+		g.funcs[fn.stmt] = g.funcSymbol(fn)
+	}
+
+	g.emitData()
+
+	g.out.WriteString("\t.text\n")
+	g.generateStart(main)
+
+	for _, fn := range fns {
+		g.generateFunc(fn)
+	}
+
+	g.emitRuntime()
+
+	return g.out.Bytes()
+}
+
+type function struct {
+	stmt   *syntax.FuncStmt
+	blocks []*intermediate.Block
+}
+
+// globalBlocks returns the blocks that initialise globals, starting with the
+// global block itself.
+func (g *Generator) globalBlocks() []*intermediate.Block {
+	var blocks []*intermediate.Block
+	for _, b := range g.ir.Blocks {
+		if b.Func == nil {
+			blocks = append(blocks, b)
+		}
+	}
+	return blocks
+}
+
+// functions groups the blocks after the global block by the function they
+// belong to, keeping each function's entry block first.
+func (g *Generator) functions() []*function {
+	var fns []*function
+	byStmt := map[*syntax.FuncStmt]*function{}
+	for _, b := range g.ir.Blocks[1:] {
+		if b.Func == nil {
+			continue
+		}
+		fn, ok := byStmt[b.Func]
+		if !ok {
+			fn = &function{stmt: b.Func}
+			byStmt[b.Func] = fn
+			fns = append(fns, fn)
+		}
+		fn.blocks = append(fn.blocks, b)
+	}
+	return fns
+}
+
+func (g *Generator) emit(format string, args ...interface{}) {
+	g.out.WriteRune('\t')
+	g.out.WriteString(fmt.Sprintf(format, args...))
+	g.out.WriteRune('\n')
+}
+
+func (g *Generator) label(name string) {
+	g.out.WriteString(name)
+	g.out.WriteString(":\n")
+}
+
+func (g *Generator) newLabel() string {
+	g.labels++
+	return fmt.Sprintf(".L%d", g.labels)
+}
+
+func (g *Generator) emitData() {
+	if len(g.globals) == 0 {
+		return
+	}
+
+	g.out.WriteString("\t.bss\n")
+	g.emit(".balign 8")
+	for _, b := range g.globalBlocks() {
+		for _, inst := range b.Insts {
+			if alloc, ok := inst.(*intermediate.AllocInst); ok {
+				g.label(symbol(alloc.Name))
+				g.emit(".zero 8")
+			}
+		}
+	}
+}
+
+// generateStart emits the process entry point. Globals are initialised in
+// declaration order before main is called, and main's result becomes the
+// exit status.
+func (g *Generator) generateStart(main *syntax.Object) {
+	g.emit(".globl _start")
+	g.label("_start")
+	g.emit("xorl %%ebp, %%ebp")
+
+	g.slots = map[*syntax.Object]int{}
+	g.heap = true
+	blocks := g.globalBlocks()
+	for i, b := range blocks {
+		var next *intermediate.Block
+		if i+1 < len(blocks) {
+			next = blocks[i+1]
+		}
+
+		if i > 0 {
+			g.label(blockLabel(b))
+		}
+		for _, inst := range b.Insts {
+			g.generateInst(b, inst, next)
+		}
+	}
+
+	g.emit("call %s", symbol(main.Name))
+	g.emit("movq %%rax, %%rdi")
+	g.emit("movl $60, %%eax")
+	g.emit("syscall")
+}
+
+func (g *Generator) generateFunc(fn *function) {
+	g.slots = map[*syntax.Object]int{}
+	g.ret = g.newLabel()
+
+	params := fn.blocks[0].Params
+	for _, param := range params {
+		g.allocSlot(param)
+	}
+	// Memory results sit above the arguments the caller pushed and the
+	// copies it made of those passed on the stack.
+	g.results = 16 + 8*(len(params)+stackArgs(len(params)))
+
+	for _, b := range fn.blocks {
+		for _, inst := range b.Insts {
+			if alloc, ok := inst.(*intermediate.AllocInst); ok {
+				g.allocSlot(lookup(b, alloc.Name))
+			}
+		}
+	}
+
+	// Arrays live in the frame unless a slice of one may outlive it. The
+	// body is generated first, as the storage they need sets the size of
+	// the frame.
+	g.arrays = 0
+	g.heap = fn.blocks[0].Sliced
+
+	g.label(g.funcs[fn.stmt])
+	start := g.out.Len()
+
+	for i, param := range params {
+		if i < len(argRegs) {
+			g.emit("movq %s, %s", argRegs[i], g.slot(param))
+		} else {
+			// Stack arguments sit above the return address and saved %rbp.
+			g.emit("movq %d(%%rbp), %%rax", 16+8*(i-len(argRegs)))
+			g.emit("movq %%rax, %s", g.slot(param))
+		}
+	}
+
+	for i, b := range fn.blocks {
+		var next *intermediate.Block
+		if i+1 < len(fn.blocks) {
+			next = fn.blocks[i+1]
+		}
+
+		g.label(blockLabel(b))
+		for _, inst := range b.Insts {
+			g.generateInst(b, inst, next)
+		}
+	}
+
+	g.label(g.ret)
+	g.emit("leave")
+	g.emit("ret")
+
+	body := append([]byte(nil), g.out.Bytes()[start:]...)
+	g.out.Truncate(start)
+	g.emit("pushq %%rbp")
+	g.emit("movq %%rsp, %%rbp")
+	if size := frameSize(8*len(g.slots) + g.arrays); size > 0 {
+		g.emit("subq $%d, %%rsp", size)
+	}
+	g.out.Write(body)
+}
+
+// stackArgs returns how many of n arguments are passed on the stack.
+func stackArgs(n int) int {
+	if n <= len(argRegs) {
+		return 0
+	}
+	return n - len(argRegs)
+}
+
+func (g *Generator) allocSlot(obj *syntax.Object) *syntax.Object {
+	if obj == nil {
+		return nil
+	}
+	if _, ok := g.slots[obj]; !ok {
+		g.slots[obj] = len(g.slots)
+	}
+	return obj
+}
+
+// frameSize rounds size bytes of locals up to keep the stack aligned.
+func frameSize(size int) int {
+	return (size + 15) &^ 15
+}
+
+func (g *Generator) slot(obj *syntax.Object) string {
+	return fmt.Sprintf("%d(%%rbp)", -8*(g.slots[obj]+1))
+}
+
+// address returns the memory operand holding the object called name as seen
+// from block b.
+func (g *Generator) address(b *intermediate.Block, name string) (string, bool) {
+	obj := lookup(b, name)
+	if obj == nil {
+		g.errors = append(g.errors, NewUndefinedObjectError(name))
+		return "", false
+	}
+
+	if _, ok := g.slots[obj]; ok {
+		return g.slot(obj), true
+	}
+
+	if sym, ok := g.globals[obj]; ok {
+		return fmt.Sprintf("%s(%%rip)", sym), true
+	}
+
+	g.errors = append(g.errors, NewUndefinedObjectError(name))
+	return "", false
+}
+
+func lookup(b *intermediate.Block, name string) *syntax.Object {
+	for b != nil {
+		if obj, ok := b.Objects[name]; ok {
+			return obj
+		}
+		b = b.Parent
+	}
+	return nil
+}
+
+func blockLabel(b *intermediate.Block) string {
+	return fmt.Sprintf(".Lb%d", b.Index)
+}
+
+// generateInst emits inst, which belongs to block b. next is the block that
+// will be emitted after b, if any, so jumps to it can fall through.
+func (g *Generator) generateInst(b *intermediate.Block, inst intermediate.Inst, next *intermediate.Block) {
+	switch inst := inst.(type) {
+	case *intermediate.AllocInst:
+		g.generateExpr(b, inst.Value)
+		if addr, ok := g.address(b, inst.Name); ok {
+			g.emit("movq %%rax, %s", addr)
+		}
+	case *intermediate.ReturnInst:
+		switch {
+		case len(inst.Results) == 0:
+			g.emit("xorl %%eax, %%eax")
+		case len(inst.Results) == 1 && !inMemory(results(inst.Results[0])):
+			// A call with its results in registers leaves them there.
+			g.generateExpr(b, inst.Results[0])
+		default:
+			g.generateResults(b, inst.Results)
+		}
+		g.emit("jmp %s", g.ret)
+	case *intermediate.TupleAssignInst:
+		g.generateTupleAssign(b, inst)
+	case *intermediate.SliceCheckInst:
+		g.generateSliceCheck(b, inst)
+	case *intermediate.GrowInst:
+		g.generateGrow(b, inst)
+	case *intermediate.JumpInst:
+		if inst.Target != next {
+			g.emit("jmp %s", blockLabel(inst.Target))
+		}
+	case *intermediate.BranchInst:
+		g.generateExpr(b, inst.Cond)
+		g.emit("testq %%rax, %%rax")
+		if inst.Then == next {
+			g.emit("jz %s", blockLabel(inst.Else))
+		} else {
+			g.emit("jnz %s", blockLabel(inst.Then))
+			if inst.Else != next {
+				g.emit("jmp %s", blockLabel(inst.Else))
+			}
+		}
+	default:
+		g.generateExpr(b, inst)
+	}
+}
+
+// generateExpr leaves the value of inst in %rax.
+func (g *Generator) generateExpr(b *intermediate.Block, inst intermediate.Inst) {
+	switch inst := inst.(type) {
+	case *intermediate.BasicLitInst:
+		switch inst.Value {
+		case "true":
+			g.emit("movl $1, %%eax")
+			return
+		case "false":
+			g.emit("xorl %%eax, %%eax")
+			return
+		}
+		if isFloat(inst.Type) {
+			v, ok := syntax.FloatValue(inst.Value)
+			if !ok {
+				g.errors = append(g.errors, NewError("invalid floating-point literal "+inst.Value))
+				return
+			}
+			if inst.Type.Type == "f32" {
+				v = float64(float32(v))
+			}
+			g.emit("movabsq $%d, %%rax", int64(math.Float64bits(v)))
+			return
+		}
+		if isString(inst.Type) {
+			s, ok := syntax.StringValue(inst.Value)
+			if !ok {
+				g.errors = append(g.errors, NewError("invalid string literal "+inst.Value))
+				return
+			}
+			g.emit("leaq %s(%%rip), %%rax", g.stringLit(s))
+			return
+		}
+		v, ok := syntax.Int64Value(inst.Value)
+		if !ok {
+			g.errors = append(g.errors, NewError("invalid integer literal "+inst.Value))
+			return
+		}
+		g.emit("movq $%d, %%rax", v)
+	case *intermediate.IdentInst:
+		if addr, ok := g.address(b, inst.Name); ok {
+			g.emit("movq %s, %%rax", addr)
+		}
+	case *intermediate.AssignInst:
+		g.generateExpr(b, inst.Right)
+		g.emit("pushq %%rax")
+		g.store(b, inst.Left)
+	case *intermediate.IndexInst:
+		g.elementAddress(b, inst)
+		if !inst.Addr {
+			g.emit("movq (%%rax), %%rax")
+		}
+	case *intermediate.ArrayInst:
+		g.generateArray(b, inst)
+	case *intermediate.CopyInst:
+		g.generateExpr(b, inst.Src)
+		g.emit("pushq %%rax")
+		g.generateExpr(b, inst.Dst)
+		g.emit("movq %%rax, %%rdi")
+		g.emit("popq %%rsi")
+		g.emit("movq $%d, %%rcx", inst.Words)
+		g.emit("rep movsq")
+	case *intermediate.BinaryInst:
+		g.generateBinary(b, inst)
+	case *intermediate.UnaryInst:
+		g.generateUnary(b, inst)
+	case *intermediate.ConvertInst:
+		g.generateExpr(b, inst.X)
+		g.generateConvert(inst)
+	case *intermediate.CallInst:
+		g.generateCall(b, inst)
+	case *intermediate.BuiltinInst:
+		g.generateBuiltin(b, inst)
+	default:
+		g.errors = append(g.errors, NewUnsupportedInstError(inst))
+	}
+}
+
+func (g *Generator) generateUnary(b *intermediate.Block, inst *intermediate.UnaryInst) {
+	g.generateExpr(b, inst.X)
+
+	switch inst.Op {
+	case "!":
+		g.emit("xorq $1, %%rax")
+	case "-":
+		if isFloat(inst.Type) {
+			g.emit("btcq $63, %%rax")
+			return
+		}
+		g.emit("negq %%rax")
+		g.wrap(inst.Type)
+	case "^":
+		g.emit("notq %%rax")
+		g.wrap(inst.Type)
+	default:
+		g.errors = append(g.errors, NewUnsupportedInstError(inst))
+	}
+}
+
+// generateCall calls inst, dropping any results returned in memory.
+func (g *Generator) generateCall(b *intermediate.Block, inst *intermediate.CallInst) {
+	g.call(b, inst)
+	if inMemory(inst.Results()) {
+		g.emit("addq $%d, %%rsp", 8*inst.Results())
+	}
+}
+
+// results returns how many values inst produces.
+func results(inst intermediate.Inst) int {
+	if call, ok := inst.(*intermediate.CallInst); ok {
+		return call.Results()
+	}
+	return 1
+}
+
+// inMemory reports whether a function with n results returns them in
+// memory instead of in resultRegs.
+func inMemory(n int) bool {
+	return n > len(resultRegs)
+}
+
+// call evaluates the arguments left to right onto the stack, moves the
+// first six into their argument registers and copies the rest into place
+// above the return address before calling. Results returned in memory are
+// left on the stack.
+func (g *Generator) call(b *intermediate.Block, inst *intermediate.CallInst) {
+	if inMemory(inst.Results()) {
+		g.emit("subq $%d, %%rsp", 8*inst.Results())
+	}
+
+	n := len(inst.Args)
+	for _, arg := range inst.Args {
+		g.generateExpr(b, arg)
+		g.emit("pushq %%rax")
+	}
+
+	extra := 0
+	for i := n - 1; i >= len(argRegs); i-- {
+		g.emit("pushq %d(%%rsp)", 8*(n-1-i+extra))
+		extra++
+	}
+
+	for i := 0; i < n && i < len(argRegs); i++ {
+		g.emit("movq %d(%%rsp), %s", 8*(n-1-i+extra), argRegs[i])
+	}
+
+	g.emit("call %s", g.funcs[inst.Func])
+	if n+extra > 0 {
+		g.emit("addq $%d, %%rsp", 8*(n+extra))
+	}
+}
+
+func (g *Generator) generateBuiltin(b *intermediate.Block, inst *intermediate.BuiltinInst) {
+	switch inst.Name {
+	case "len":
+		g.generateExpr(b, inst.Args[0])
+		g.nonNull("%rax")
+		g.emit("movq (%%rax), %%rax")
+	case "print":
+		for i, arg := range inst.Args {
+			g.generateExpr(b, arg)
+			g.emit("movq %%rax, %%rdi")
+			switch {
+			case isString(inst.Types[i]):
+				g.nonNull("%rdi")
+				g.use("print")
+				g.emit("call hippo.rt.print")
+			case unsigned(inst.Types[i]):
+				g.use("printint")
+				g.emit("call hippo.rt.printu")
+			default:
+				g.use("printint")
+				g.emit("call hippo.rt.printi")
+			}
+		}
+	default:
+		g.errors = append(g.errors, NewUnsupportedInstError(inst))
+	}
+}
+
+// values pushes every value of list, expanding a single call with several
+// results, and returns how many were pushed.
+func (g *Generator) values(b *intermediate.Block, list []intermediate.Inst) int {
+	if len(list) == 1 {
+		if call, ok := list[0].(*intermediate.CallInst); ok && call.Results() > 1 {
+			g.call(b, call)
+			if inMemory(call.Results()) {
+				return call.Results()
+			}
+			for _, reg := range resultRegs[:call.Results()] {
+				g.emit("pushq %s", reg)
+			}
+			return call.Results()
+		}
+	}
+
+	for _, inst := range list {
+		g.generateExpr(b, inst)
+		g.emit("pushq %%rax")
+	}
+	return len(list)
+}
+
+func (g *Generator) generateResults(b *intermediate.Block, results []intermediate.Inst) {
+	n := g.values(b, results)
+	if inMemory(n) {
+		for i := n - 1; i >= 0; i-- {
+			g.emit("popq %%rcx")
+			g.emit("movq %%rcx, %d(%%rbp)", g.results+8*(n-1-i))
+		}
+		return
+	}
+
+	for i := n - 1; i >= 0; i-- {
+		g.emit("popq %s", resultRegs[i])
+	}
+}
+
+func (g *Generator) generateTupleAssign(b *intermediate.Block, inst *intermediate.TupleAssignInst) {
+	n := g.values(b, inst.Rights)
+	if n != len(inst.Lefts) {
+		g.errors = append(g.errors, NewUnsupportedInstError(inst))
+		return
+	}
+
+	for i := n - 1; i >= 0; i-- {
+		g.store(b, inst.Lefts[i])
+	}
+}
+
+// store pops a value into the variable or element left, leaving it in
+// %rax.
+func (g *Generator) store(b *intermediate.Block, left intermediate.Inst) {
+	switch left := left.(type) {
+	case *intermediate.IdentInst:
+		g.emit("popq %%rax")
+		if addr, ok := g.address(b, left.Name); ok {
+			g.emit("movq %%rax, %s", addr)
+		}
+	case *intermediate.IndexInst:
+		g.elementAddress(b, left)
+		g.emit("popq %%rcx")
+		g.emit("movq %%rcx, (%%rax)")
+		g.emit("movq %%rcx, %%rax")
+	default:
+		g.emit("popq %%rax")
+		g.errors = append(g.errors, NewUnsupportedInstError(left))
+	}
+}
+
+// elementAddress leaves the address of the leaf of an element inst refers
+// to in %rax. The index is compared with the length as an unsigned number,
+// so that a negative index is out of range too.
+func (g *Generator) elementAddress(b *intermediate.Block, inst *intermediate.IndexInst) {
+	g.generateExpr(b, inst.Index)
+	g.emit("pushq %%rax")
+	g.generateExpr(b, inst.Len)
+	g.emit("pushq %%rax")
+	g.generateExpr(b, inst.X)
+	g.emit("popq %%rcx")
+	g.emit("popq %%rdx")
+
+	ok := g.newLabel()
+	g.emit("cmpq %%rcx, %%rdx")
+	g.emit("jb %s", ok)
+	g.use("panic")
+	g.emit("movq %%rdx, %%rdi")
+	g.emit("movq %%rcx, %%rsi")
+	g.emit("leaq %s(%%rip), %%rdx", g.stringLit(inst.Pos.String()+": panic: index out of range ["))
+	g.emit("call hippo.rt.panicindex")
+	g.label(ok)
+
+	g.emit("imulq $%d, %%rdx, %%rdx", inst.Words*8)
+	g.emit("leaq %d(%%rax,%%rdx), %%rax", inst.Offset*8)
+}
+
+// generateSliceCheck panics unless 0 <= lo <= hi <= cap, comparing them as
+// unsigned numbers so that negative bounds fail.
+func (g *Generator) generateSliceCheck(b *intermediate.Block, inst *intermediate.SliceCheckInst) {
+	g.generateExpr(b, inst.Cap)
+	g.emit("pushq %%rax")
+	g.generateExpr(b, inst.Hi)
+	g.emit("pushq %%rax")
+	g.generateExpr(b, inst.Lo)
+	g.emit("popq %%rcx")
+	g.emit("popq %%rdx")
+
+	fail, ok := g.newLabel(), g.newLabel()
+	g.emit("cmpq %%rcx, %%rax")
+	g.emit("ja %s", fail)
+	g.emit("cmpq %%rdx, %%rcx")
+	g.emit("jbe %s", ok)
+	g.label(fail)
+	g.use("panic")
+	g.emit("movq %%rax, %%rdi")
+	g.emit("movq %%rcx, %%rsi")
+	g.emit("leaq %s(%%rip), %%rcx", g.stringLit(inst.Pos.String()+": panic: slice bounds out of range ["))
+	g.emit("call hippo.rt.panicslice")
+	g.label(ok)
+}
+
+// generateArray allocates the storage of an array, copying the elements of
+// inst.From into it if it is set. In a function each ArrayInst has storage
+// of its own in the frame, which is reused every time it runs: the array it
+// produced before is only ever held by a variable that has gone out of
+// scope since, or has been copied. Otherwise the storage comes from the
+// heap.
+func (g *Generator) generateArray(b *intermediate.Block, inst *intermediate.ArrayInst) {
+	if inst.From != nil {
+		g.generateExpr(b, inst.From)
+		g.emit("pushq %%rax")
+	}
+
+	if g.heap {
+		g.use("alloc")
+		g.emit("movq $%d, %%rdi", inst.Words*8)
+		g.emit("call hippo.rt.alloc")
+	} else {
+		g.arrays += int(inst.Words) * 8
+		g.emit("leaq %d(%%rbp), %%rax", -(8*len(g.slots) + g.arrays))
+		if inst.From == nil {
+			g.emit("movq %%rax, %%rdi")
+			g.emit("movq %%rax, %%rdx")
+			g.emit("xorl %%eax, %%eax")
+			g.emit("movq $%d, %%rcx", inst.Words)
+			g.emit("rep stosq")
+			g.emit("movq %%rdx, %%rax")
+		}
+	}
+
+	if inst.From != nil {
+		g.emit("popq %%rsi")
+		g.emit("movq %%rax, %%rdi")
+		g.emit("movq $%d, %%rcx", inst.Words)
+		g.emit("rep movsq")
+	}
+}
+
+// generateGrow makes room for the elements appended to a slice, storing
+// the array and capacity hippo.rt.grow returns back into its leaves.
+func (g *Generator) generateGrow(b *intermediate.Block, inst *intermediate.GrowInst) {
+	ptr, ok1 := g.address(b, inst.Ptr.Name)
+	len_, ok2 := g.address(b, inst.Len.Name)
+	cap_, ok3 := g.address(b, inst.Cap.Name)
+	if !ok1 || !ok2 || !ok3 {
+		return
+	}
+
+	g.use("grow")
+	g.emit("movq %s, %%rdi", ptr)
+	g.emit("movq %s, %%rsi", len_)
+	g.emit("movq %s, %%rdx", cap_)
+	g.emit("movq $%d, %%rcx", inst.Add)
+	g.emit("movq $%d, %%r8", inst.Words)
+	g.emit("call hippo.rt.grow")
+	g.emit("movq %%rax, %s", ptr)
+	g.emit("movq %%rdx, %s", cap_)
+}
+
+// generateBinary evaluates the right operand first so that the left operand
+// ends up in %rax and the right in %rcx. Arithmetic results are wrapped to
+// the width of the operand type and comparisons produce 0 or 1.
+func (g *Generator) generateBinary(b *intermediate.Block, inst *intermediate.BinaryInst) {
+	g.generateExpr(b, inst.Right)
+	g.emit("pushq %%rax")
+	g.generateExpr(b, inst.Left)
+	g.emit("popq %%rcx")
+
+	if isString(inst.Type) {
+		g.generateStringOp(inst)
+		return
+	}
+	if isFloat(inst.Type) {
+		g.generateFloatOp(inst)
+		return
+	}
+
+	switch inst.Op {
+	case "+":
+		g.emit("addq %%rcx, %%rax")
+	case "-":
+		g.emit("subq %%rcx, %%rax")
+	case "*":
+		g.emit("imulq %%rcx, %%rax")
+	case "/", "%":
+		g.generateDivide(inst)
+	case "&":
+		g.emit("andq %%rcx, %%rax")
+	case "|":
+		g.emit("orq %%rcx, %%rax")
+	case "^":
+		g.emit("xorq %%rcx, %%rax")
+	case "&^":
+		g.emit("notq %%rcx")
+		g.emit("andq %%rcx, %%rax")
+	case "<<", ">>":
+		g.generateShift(inst)
+	case "<", ">", "<=", ">=", "==", "!=":
+		g.emit("cmpq %%rcx, %%rax")
+		if unsigned(inst.Type) {
+			g.emit("%s %%al", setccUnsigned[inst.Op])
+		} else {
+			g.emit("%s %%al", setcc[inst.Op])
+		}
+		g.emit("movzbl %%al, %%eax")
+		return
+	default:
+		g.errors = append(g.errors, NewUnsupportedInstError(inst))
+		return
+	}
+
+	g.wrap(inst.Type)
+}
+
+// generateDivide divides %rax by %rcx, leaving the quotient or, for %, the
+// remainder in %rax. A zero divisor panics. A signed division by -1 gives
+// the negated dividend and a remainder of 0, computed without dividing since
+// idiv faults when the dividend is the most negative number.
+func (g *Generator) generateDivide(inst *intermediate.BinaryInst) {
+	ok, done := g.newLabel(), g.newLabel()
+	g.emit("testq %%rcx, %%rcx")
+	g.emit("jnz %s", ok)
+	g.use("panic")
+	g.emit("leaq %s(%%rip), %%rdi", g.stringLit(inst.Pos.String()+": panic: integer divide by zero"))
+	g.emit("call hippo.rt.panicdivide")
+	g.label(ok)
+
+	if !unsigned(inst.Type) {
+		div := g.newLabel()
+		g.emit("cmpq $-1, %%rcx")
+		g.emit("jne %s", div)
+		if inst.Op == "/" {
+			g.emit("negq %%rax")
+		} else {
+			g.emit("xorl %%eax, %%eax")
+		}
+		g.emit("jmp %s", done)
+		g.label(div)
+	}
+
+	if unsigned(inst.Type) {
+		g.emit("xorl %%edx, %%edx")
+		g.emit("divq %%rcx")
+	} else {
+		g.emit("cqto")
+		g.emit("idivq %%rcx")
+	}
+	if inst.Op == "%" {
+		g.emit("movq %%rdx, %%rax")
+	}
+	g.label(done)
+}
+
+// generateShift shifts %rax by the unsigned count in %rcx. The hardware only
+// looks at the low six bits of the count, so larger counts are handled here:
+// they shift out every bit, leaving 0, or copies of the sign bit for a signed
+// right shift.
+func (g *Generator) generateShift(inst *intermediate.BinaryInst) {
+	if inst.Op == ">>" && !unsigned(inst.Type) {
+		g.emit("movl $63, %%edx")
+		g.emit("cmpq %%rdx, %%rcx")
+		g.emit("cmovaq %%rdx, %%rcx")
+		g.emit("sarq %%cl, %%rax")
+		return
+	}
+
+	if inst.Op == "<<" {
+		g.emit("shlq %%cl, %%rax")
+	} else {
+		g.emit("shrq %%cl, %%rax")
+	}
+	g.emit("xorl %%edx, %%edx")
+	g.emit("cmpq $64, %%rcx")
+	g.emit("cmovaeq %%rdx, %%rax")
+}
+
+// generateStringOp applies inst to the strings in %rax and %rcx.
+func (g *Generator) generateStringOp(inst *intermediate.BinaryInst) {
+	g.emit("movq %%rax, %%rdi")
+	g.emit("movq %%rcx, %%rsi")
+	g.nonNull("%rdi")
+	g.nonNull("%rsi")
+
+	switch inst.Op {
+	case "+":
+		g.use("concat")
+		g.emit("call hippo.rt.concat")
+	case "==":
+		g.use("streq")
+		g.emit("call hippo.rt.streq")
+	case "!=":
+		g.use("streq")
+		g.emit("call hippo.rt.streq")
+		g.emit("xorq $1, %%rax")
+	default:
+		g.errors = append(g.errors, NewUnsupportedInstError(inst))
+	}
+}
+
+// nonNull replaces a null pointer in reg with the empty string. Strings in
+// storage no literal has been stored into, such as new elements of an
+// array, are null.
+func (g *Generator) nonNull(reg string) {
+	g.emit("leaq %s(%%rip), %%r11", g.stringLit(""))
+	g.emit("testq %s, %s", reg, reg)
+	g.emit("cmovzq %%r11, %s", reg)
+}
+
+// ucomisd lists the operands of the ucomisd comparing the floats in %xmm0
+// and %xmm1 for each operator, and the setcc storing its result. Unordered
+// operands set the carry flag, so seta and setae are false for NaN.
+var ucomisd = map[string][2]string{
+	"<":  {"%xmm0, %xmm1", "seta"},
+	"<=": {"%xmm0, %xmm1", "setae"},
+	">":  {"%xmm1, %xmm0", "seta"},
+	">=": {"%xmm1, %xmm0", "setae"},
+}
+
+// generateFloatOp applies inst to the floats in %rax and %rcx.
+func (g *Generator) generateFloatOp(inst *intermediate.BinaryInst) {
+	g.emit("movq %%rax, %%xmm0")
+	g.emit("movq %%rcx, %%xmm1")
+
+	switch inst.Op {
+	case "+":
+		g.emit("addsd %%xmm1, %%xmm0")
+	case "-":
+		g.emit("subsd %%xmm1, %%xmm0")
+	case "*":
+		g.emit("mulsd %%xmm1, %%xmm0")
+	case "/":
+		g.emit("divsd %%xmm1, %%xmm0")
+	case "<", "<=", ">", ">=":
+		g.emit("ucomisd %s", ucomisd[inst.Op][0])
+		g.emit("%s %%al", ucomisd[inst.Op][1])
+		g.emit("movzbl %%al, %%eax")
+		return
+	case "==":
+		g.emit("ucomisd %%xmm1, %%xmm0")
+		g.emit("sete %%al")
+		g.emit("setnp %%cl")
+		g.emit("andb %%cl, %%al")
+		g.emit("movzbl %%al, %%eax")
+		return
+	case "!=":
+		g.emit("ucomisd %%xmm1, %%xmm0")
+		g.emit("setne %%al")
+		g.emit("setp %%cl")
+		g.emit("orb %%cl, %%al")
+		g.emit("movzbl %%al, %%eax")
+		return
+	default:
+		g.errors = append(g.errors, NewUnsupportedInstError(inst))
+		return
+	}
+
+	g.roundFloat(inst.Type, "%xmm0")
+	g.emit("movq %%xmm0, %%rax")
+}
+
+// roundFloat rounds the f64 in reg to single precision if t is f32.
+func (g *Generator) roundFloat(t *syntax.Object, reg string) {
+	if t != nil && t.Type == "f32" {
+		g.emit("cvtsd2ss %s, %s", reg, reg)
+		g.emit("cvtss2sd %s, %s", reg, reg)
+	}
+}
+
+// generateConvert converts the value in %rax from inst.From to inst.Type.
+func (g *Generator) generateConvert(inst *intermediate.ConvertInst) {
+	switch {
+	case isFloat(inst.From) && isFloat(inst.Type):
+		g.emit("movq %%rax, %%xmm0")
+		g.roundFloat(inst.Type, "%xmm0")
+		g.emit("movq %%xmm0, %%rax")
+	case isFloat(inst.Type):
+		g.intToFloat(inst.From)
+		g.roundFloat(inst.Type, "%xmm0")
+		g.emit("movq %%xmm0, %%rax")
+	case isFloat(inst.From):
+		g.floatToInt(inst.Type)
+		g.wrap(inst.Type)
+	default:
+		g.wrap(inst.Type)
+	}
+}
+
+// intToFloat converts the integer of type from in %rax to an f64 in %xmm0.
+// Narrower integers are already extended to 64 bits, so only a u64 with the
+// top bit set needs care: it is halved, rounding to odd, and doubled again.
+func (g *Generator) intToFloat(from *syntax.Object) {
+	if from == nil || from.Type != "u64" {
+		g.emit("cvtsi2sdq %%rax, %%xmm0")
+		return
+	}
+
+	big, done := g.newLabel(), g.newLabel()
+	g.emit("testq %%rax, %%rax")
+	g.emit("js %s", big)
+	g.emit("cvtsi2sdq %%rax, %%xmm0")
+	g.emit("jmp %s", done)
+	g.label(big)
+	g.emit("movq %%rax, %%rcx")
+	g.emit("shrq %%rcx")
+	g.emit("andl $1, %%eax")
+	g.emit("orq %%rax, %%rcx")
+	g.emit("cvtsi2sdq %%rcx, %%xmm0")
+	g.emit("addsd %%xmm0, %%xmm0")
+	g.label(done)
+}
+
+// floatToInt truncates the f64 in %rax to an integer of type t. Values of
+// 2^63 and above only fit a u64, so they are converted less 2^63 and the top
+// bit set afterwards.
+func (g *Generator) floatToInt(t *syntax.Object) {
+	g.emit("movq %%rax, %%xmm0")
+	if t == nil || t.Type != "u64" {
+		g.emit("cvttsd2siq %%xmm0, %%rax")
+		return
+	}
+
+	big, done := g.newLabel(), g.newLabel()
+	g.emit("movabsq $%d, %%rcx", int64(math.Float64bits(1<<63)))
+	g.emit("movq %%rcx, %%xmm1")
+	g.emit("ucomisd %%xmm1, %%xmm0")
+	g.emit("jae %s", big)
+	g.emit("cvttsd2siq %%xmm0, %%rax")
+	g.emit("jmp %s", done)
+	g.label(big)
+	g.emit("subsd %%xmm1, %%xmm0")
+	g.emit("cvttsd2siq %%xmm0, %%rax")
+	g.emit("btcq $63, %%rax")
+	g.label(done)
+}
+
+// wrap truncates the integer in %rax to the width of t.
+func (g *Generator) wrap(t *syntax.Object) {
+	if t == nil {
+		return
+	}
+	if ext, ok := extend[t.Type]; ok {
+		g.emit("%s", ext)
+	}
+}
+
+func isFloat(t *syntax.Object) bool {
+	return t != nil && (t.Type == "f32" || t.Type == "f64")
+}
+
+func isString(t *syntax.Object) bool {
+	return t != nil && t.Type == "string"
+}
+
+func unsigned(t *syntax.Object) bool {
+	if t == nil {
+		return false
+	}
+	switch t.Type {
+	case "u8", "u16", "u32", "u64":
+		return true
+	default:
+		return false
+	}
+}
+
+// funcSymbol returns the assembler symbol of fn. A nested function may share
+// its name with other functions, so its symbol is told apart by the index of
+// its entry block, which cannot appear in a mangled name.
+func (g *Generator) funcSymbol(fn *function) string {
+	name := fn.stmt.Name
+	if g.ir.Blocks[0].Objects[name.Name] != name.Obj {
+		return fmt.Sprintf("%s.%d", symbol(name.Name), fn.blocks[0].Index)
+	}
+	return symbol(name.Name)
+}
+
+// symbol mangles a Hippo name into an assembler symbol. Bytes outside the
+// ASCII identifier range are hex encoded between dots, which can never
+// appear in a Hippo identifier.
+func symbol(name string) string {
+	var w bytes.Buffer
+	w.WriteString("hippo.")
+	for _, r := range name {
+		if r == '_' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' {
+			w.WriteRune(r)
+		} else {
+			w.WriteString(fmt.Sprintf(".u%04x.", r))
+		}
+	}
+	return w.String()
+}
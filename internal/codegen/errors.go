@@ -0,0 +1,36 @@
+package codegen
+
+import (
+	"fmt"
+
+	"github.com/danecwalker/hippo/internal/diag"
+)
+
+// Error is a problem found while generating code. It has no position.
+type Error = diag.Diagnostic
+
+func NewError(msg string) *Error {
+	return diag.NewError(diag.CodeCodegen, nil, msg)
+}
+
+func NewUnsupportedInstError(inst interface{}) *Error {
+	msg := "cannot generate code for %T"
+	return NewError(fmt.Sprintf(msg, inst))
+}
+
+func NewUndefinedObjectError(name string) *Error {
+	msg := "undefined object %s"
+	return NewError(fmt.Sprintf(msg, name))
+}
+
+func NewMissingMainError() *Error {
+	return NewError("function main is undeclared")
+}
+
+func NewToolError(tool string, err error, output []byte) *Error {
+	msg := "%s failed: %s"
+	if len(output) > 0 {
+		msg += "\n" + string(output)
+	}
+	return NewError(fmt.Sprintf(msg, tool, err)).WithCode(diag.CodeToolFailed)
+}
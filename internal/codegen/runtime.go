@@ -0,0 +1,349 @@
+package codegen
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// A string value is a pointer to its length in bytes, stored as a quad, and
+// followed directly by the bytes themselves. Literals live in .rodata and
+// strings built at run time are allocated from a heap grown with brk. The
+// heap is never freed.
+
+// runtime holds the assembly of the support routines generated code may
+// call. Each follows the System V calling convention.
+var runtime = map[string]string{
+	// hippo.rt.alloc returns %rdi bytes of fresh, 8 byte aligned memory.
+	"alloc": `hippo.rt.alloc:
+	movq hippo.rt.heap(%rip), %rax
+	testq %rax, %rax
+	jnz 1f
+	pushq %rdi
+	xorl %edi, %edi
+	movl $12, %eax
+	syscall
+	popq %rdi
+1:
+	leaq 7(%rax,%rdi), %rsi
+	andq $-8, %rsi
+	pushq %rax
+	pushq %rsi
+	movq %rsi, %rdi
+	movl $12, %eax
+	syscall
+	popq %rsi
+	cmpq %rsi, %rax
+	jb hippo.rt.oom
+	movq %rsi, hippo.rt.heap(%rip)
+	popq %rax
+	ret
+hippo.rt.oom:
+	movl $2, %edi
+	leaq hippo.rt.oommsg(%rip), %rsi
+	movl $14, %edx
+	movl $1, %eax
+	syscall
+	movl $2, %edi
+	movl $60, %eax
+	syscall
+`,
+
+	// hippo.rt.concat returns a new string holding %rdi followed by %rsi.
+	"concat": `hippo.rt.concat:
+	pushq %rbx
+	pushq %r12
+	pushq %r13
+	movq %rdi, %r12
+	movq %rsi, %r13
+	movq (%r12), %rdi
+	addq (%r13), %rdi
+	addq $8, %rdi
+	call hippo.rt.alloc
+	movq %rax, %rbx
+	movq (%r12), %rcx
+	addq (%r13), %rcx
+	movq %rcx, (%rbx)
+	leaq 8(%rbx), %rdi
+	leaq 8(%r12), %rsi
+	movq (%r12), %rcx
+	rep movsb
+	leaq 8(%r13), %rsi
+	movq (%r13), %rcx
+	rep movsb
+	movq %rbx, %rax
+	popq %r13
+	popq %r12
+	popq %rbx
+	ret
+`,
+
+	// hippo.rt.streq returns 1 if the strings %rdi and %rsi hold the same
+	// bytes and 0 otherwise.
+	"streq": `hippo.rt.streq:
+	xorl %eax, %eax
+	movq (%rdi), %rcx
+	cmpq (%rsi), %rcx
+	jne 1f
+	leaq 8(%rdi), %rdi
+	leaq 8(%rsi), %rsi
+	repe cmpsb
+	sete %al
+1:
+	ret
+`,
+
+	// hippo.rt.print writes the string %rdi to standard output, and
+	// hippo.rt.fprint to the file descriptor %r9.
+	"print": `hippo.rt.print:
+	movl $1, %r9d
+hippo.rt.fprint:
+	movq (%rdi), %rdx
+	leaq 8(%rdi), %rsi
+1:
+	testq %rdx, %rdx
+	jz 2f
+	movl %r9d, %edi
+	movl $1, %eax
+	pushq %rsi
+	pushq %rdx
+	syscall
+	popq %rdx
+	popq %rsi
+	testq %rax, %rax
+	jle 2f
+	addq %rax, %rsi
+	subq %rax, %rdx
+	jmp 1b
+2:
+	ret
+`,
+
+	// hippo.rt.printi and hippo.rt.printu write %rdi to standard output in
+	// decimal, as a signed and an unsigned integer respectively.
+	// hippo.rt.fprinti and hippo.rt.fprintu write it to the file
+	// descriptor %r9.
+	"printint": `hippo.rt.printu:
+	movl $1, %r9d
+hippo.rt.fprintu:
+	movq %rdi, %rax
+	xorl %r8d, %r8d
+	jmp 1f
+hippo.rt.printi:
+	movl $1, %r9d
+hippo.rt.fprinti:
+	movq %rdi, %rax
+	xorl %r8d, %r8d
+	testq %rax, %rax
+	jns 1f
+	negq %rax
+	movl $1, %r8d
+1:
+	subq $32, %rsp
+	leaq 32(%rsp), %rsi
+	movl $10, %ecx
+2:
+	xorl %edx, %edx
+	divq %rcx
+	addb $'0', %dl
+	decq %rsi
+	movb %dl, (%rsi)
+	testq %rax, %rax
+	jnz 2b
+	testl %r8d, %r8d
+	jz 3f
+	decq %rsi
+	movb $'-', (%rsi)
+3:
+	leaq 32(%rsp), %rdx
+	subq %rsi, %rdx
+	movl %r9d, %edi
+	movl $1, %eax
+	syscall
+	addq $32, %rsp
+	ret
+`,
+
+	// hippo.rt.panicindex reports the index %rdi out of range of the length
+	// %rsi, and hippo.rt.panicslice the bounds %rdi..%rsi out of range of
+	// the capacity %rdx, on standard error and exits with status 2. The
+	// message starts with the string %rdx or %rcx respectively.
+	// hippo.rt.panicdivide reports the message %rdi the same way.
+	"panic": `hippo.rt.panicindex:
+	pushq %rsi
+	pushq %rdi
+	movq %rdx, %rdi
+	movl $2, %r9d
+	call hippo.rt.fprint
+	popq %rdi
+	call hippo.rt.fprinti
+	leaq hippo.rt.lenmsg(%rip), %rdi
+	call hippo.rt.fprint
+	popq %rdi
+	jmp 1f
+hippo.rt.panicslice:
+	pushq %rdx
+	pushq %rsi
+	pushq %rdi
+	movq %rcx, %rdi
+	movl $2, %r9d
+	call hippo.rt.fprint
+	popq %rdi
+	call hippo.rt.fprinti
+	leaq hippo.rt.dotsmsg(%rip), %rdi
+	call hippo.rt.fprint
+	popq %rdi
+	call hippo.rt.fprinti
+	leaq hippo.rt.capmsg(%rip), %rdi
+	call hippo.rt.fprint
+	popq %rdi
+1:
+	call hippo.rt.fprinti
+	jmp 2f
+hippo.rt.panicdivide:
+	movl $2, %r9d
+	call hippo.rt.fprint
+2:
+	leaq hippo.rt.nlmsg(%rip), %rdi
+	call hippo.rt.fprint
+	movl $2, %edi
+	movl $60, %eax
+	syscall
+`,
+
+	// hippo.rt.grow makes room for %rcx more elements of %r8 quads in the
+	// array %rdi holding %rsi of a capacity of %rdx elements. It returns the
+	// array in %rax and its capacity in %rdx, copying the elements to a new
+	// array of at least twice the capacity if they do not fit.
+	"grow": `hippo.rt.grow:
+	movq %rdi, %rax
+	addq %rsi, %rcx
+	cmpq %rdx, %rcx
+	jbe 1f
+	shlq $1, %rdx
+	cmpq %rcx, %rdx
+	cmovbq %rcx, %rdx
+	pushq %rdi
+	pushq %rsi
+	pushq %rdx
+	pushq %r8
+	movq %rdx, %rdi
+	imulq %r8, %rdi
+	shlq $3, %rdi
+	call hippo.rt.alloc
+	popq %r8
+	popq %rdx
+	popq %rcx
+	popq %rsi
+	imulq %r8, %rcx
+	movq %rax, %rdi
+	rep movsq
+1:
+	ret
+`,
+}
+
+// requires lists the routines each runtime routine calls.
+var requires = map[string][]string{
+	"concat": {"alloc"},
+	"panic":  {"print", "printint"},
+	"grow":   {"alloc"},
+}
+
+// use marks the runtime routine name as needed by the generated code.
+func (g *Generator) use(name string) {
+	if g.runtime[name] {
+		return
+	}
+	g.runtime[name] = true
+	for _, dep := range requires[name] {
+		g.use(dep)
+	}
+}
+
+// stringLit returns the label of the read-only copy of the string s,
+// emitting each distinct string once.
+func (g *Generator) stringLit(s string) string {
+	if label, ok := g.strings[s]; ok {
+		return label
+	}
+	label := fmt.Sprintf(".Lstr%d", len(g.strings))
+	g.strings[s] = label
+	return label
+}
+
+// emitRuntime writes the string literals and runtime routines the generated
+// code refers to.
+func (g *Generator) emitRuntime() {
+	var names []string
+	for name := range g.runtime {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		g.out.WriteString(runtime[name])
+	}
+
+	if g.runtime["alloc"] {
+		g.out.WriteString("\t.data\n")
+		g.emit(".balign 8")
+		g.label("hippo.rt.heap")
+		g.emit(".quad 0")
+		g.label("hippo.rt.oommsg")
+		g.emit(".ascii \"out of memory\\n\"")
+	}
+
+	if g.runtime["panic"] {
+		g.out.WriteString("\t.section .rodata\n")
+		for _, msg := range []struct{ label, text string }{
+			{"hippo.rt.lenmsg", "] with length "},
+			{"hippo.rt.dotsmsg", ".."},
+			{"hippo.rt.capmsg", "] with capacity "},
+			{"hippo.rt.nlmsg", "\n"},
+		} {
+			g.emit(".balign 8")
+			g.label(msg.label)
+			g.emit(".quad %d", len(msg.text))
+			g.emit(".ascii \"%s\"", asciiEscape(msg.text))
+		}
+	}
+
+	if len(g.strings) == 0 {
+		return
+	}
+
+	lits := make([]string, 0, len(g.strings))
+	for s := range g.strings {
+		lits = append(lits, s)
+	}
+	sort.Slice(lits, func(i, j int) bool { return g.strings[lits[i]] < g.strings[lits[j]] })
+
+	g.out.WriteString("\t.section .rodata\n")
+	for _, s := range lits {
+		g.emit(".balign 8")
+		g.label(g.strings[s])
+		g.emit(".quad %d", len(s))
+		if s != "" {
+			g.emit(".ascii \"%s\"", asciiEscape(s))
+		}
+	}
+}
+
+// asciiEscape quotes the bytes of s for an .ascii directive.
+func asciiEscape(s string) string {
+	var w strings.Builder
+	for i := 0; i < len(s); i++ {
+		b := s[i]
+		switch {
+		case b == '"' || b == '\\':
+			w.WriteByte('\\')
+			w.WriteByte(b)
+		case b < ' ' || b >= 0x7f:
+			fmt.Fprintf(&w, "\\%03o", b)
+		default:
+			w.WriteByte(b)
+		}
+	}
+	return w.String()
+}
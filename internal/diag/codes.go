@@ -0,0 +1,63 @@
+package diag
+
+// Code identifies the kind of a diagnostic in machine-readable output. Codes
+// are stable: once released a code keeps its meaning and is never reused
+// for a different kind of problem, even if the wording of the message
+// changes.
+type Code string
+
+// Source text and syntax.
+const (
+	CodeLexical         Code = "E0001" // malformed character or token
+	CodeUnexpectedEOF   Code = "E0002"
+	CodeSyntax          Code = "E0003" // other syntax errors
+	CodeExpectedToken   Code = "E0004"
+	CodeUnexpectedToken Code = "E0005"
+	CodeInvalidRange    Code = "E0006"
+	CodeMisplacedBranch Code = "E0007" // break or continue outside a loop
+	CodeMisplacedLabel  Code = "E0008" // label not followed by a loop
+	CodeCallName        Code = "E0009" // call of something other than a name
+)
+
+// Names.
+const (
+	CodeRedeclared      Code = "E0100"
+	CodeUndefined       Code = "E0101"
+	CodeNotAType        Code = "E0102"
+	CodeNotAnExpression Code = "E0103"
+	CodeUndefinedLabel  Code = "E0104"
+)
+
+// Types.
+const (
+	CodeType            Code = "E0200" // other type errors
+	CodeMismatchedTypes Code = "E0201"
+	CodeOperator        Code = "E0202"
+	CodeAssignment      Code = "E0203"
+	CodeCannotAssign    Code = "E0204"
+	CodeNotCallable     Code = "E0205"
+	CodeArgumentCount   Code = "E0206"
+	CodeReturnCount     Code = "E0207"
+	CodeMissingReturn   Code = "E0208"
+	CodeNoValue         Code = "E0209"
+	CodeCondition       Code = "E0210"
+	CodeAssignmentCount Code = "E0211"
+	CodeOverflow        Code = "E0212"
+	CodeConversion      Code = "E0213"
+	CodeDivisionByZero  Code = "E0214"
+	CodeBuiltinValue    Code = "E0215"
+	CodeArgument        Code = "E0216"
+	CodeUnknownField    Code = "E0217" // selector or literal naming no field
+	CodeCompositeLit    Code = "E0218" // malformed composite literal
+	CodeRecursiveType   Code = "E0219"
+	CodeIndex           Code = "E0220" // invalid index or slice expression
+	CodeArrayLength     Code = "E0221"
+)
+
+// Later stages.
+const (
+	CodeLowering   Code = "E0300" // lowering to IR
+	CodeCodegen    Code = "E0400" // generating assembly
+	CodeToolFailed Code = "E0401" // running the assembler or linker
+	CodeRuntime    Code = "E0500" // interpreting the program
+)
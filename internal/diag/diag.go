@@ -0,0 +1,140 @@
+// Package diag describes the problems the compiler reports and renders them
+// for people, with the offending source line and an underline beneath the
+// span it refers to.
+package diag
+
+import (
+	"fmt"
+
+	"github.com/danecwalker/hippo/internal/syntax"
+)
+
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+	SeverityNote
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityNote:
+		return "note"
+	default:
+		return "error"
+	}
+}
+
+// Diagnostic is a problem found in a program. Pos is where it starts and End
+// is just past where it ends; End is the zero Position when only the start
+// is known. A Diagnostic without a Filename has no position at all.
+type Diagnostic struct {
+	Code     Code
+	Severity Severity
+	Pos      syntax.Position
+	End      syntax.Position
+	Msg      string
+	Related  []*Label
+	Fixes    []*Fix
+}
+
+// Label is a secondary span attached to a Diagnostic, such as the earlier
+// declaration of a redeclared name. It is reported as a note.
+type Label struct {
+	Pos syntax.Position
+	End syntax.Position
+	Msg string
+}
+
+// Fix is a suggested change to the program that resolves a Diagnostic.
+type Fix struct {
+	Msg   string
+	Edits []*Edit
+}
+
+// Edit replaces the text from Pos up to End with NewText. An edit with Pos
+// equal to End inserts NewText.
+type Edit struct {
+	Pos     syntax.Position
+	End     syntax.Position
+	NewText string
+}
+
+func NewEdit(pos, end *syntax.Position, newText string) *Edit {
+	return &Edit{
+		Pos:     *pos,
+		End:     *end,
+		NewText: newText,
+	}
+}
+
+func New(code Code, severity Severity, pos *syntax.Position, msg string) *Diagnostic {
+	d := &Diagnostic{
+		Code:     code,
+		Severity: severity,
+		Msg:      msg,
+	}
+	if pos != nil {
+		d.Pos = *pos
+	}
+	return d
+}
+
+func NewError(code Code, pos *syntax.Position, msg string) *Diagnostic {
+	return New(code, SeverityError, pos, msg)
+}
+
+func NewWarning(code Code, pos *syntax.Position, msg string) *Diagnostic {
+	return New(code, SeverityWarning, pos, msg)
+}
+
+// WithCode sets the code of d and returns d.
+func (d *Diagnostic) WithCode(code Code) *Diagnostic {
+	d.Code = code
+	return d
+}
+
+// WithEnd sets the end of the span d covers and returns d.
+func (d *Diagnostic) WithEnd(end *syntax.Position) *Diagnostic {
+	if end != nil {
+		d.End = *end
+	}
+	return d
+}
+
+// WithLabel attaches a note about the span from pos to end, which may be
+// nil, and returns d. It does nothing if pos is nil.
+func (d *Diagnostic) WithLabel(pos, end *syntax.Position, msg string) *Diagnostic {
+	if pos == nil {
+		return d
+	}
+
+	l := &Label{
+		Pos: *pos,
+		Msg: msg,
+	}
+	if end != nil {
+		l.End = *end
+	}
+	d.Related = append(d.Related, l)
+	return d
+}
+
+// WithFix attaches a suggested fix made of edits and returns d.
+func (d *Diagnostic) WithFix(msg string, edits ...*Edit) *Diagnostic {
+	d.Fixes = append(d.Fixes, &Fix{
+		Msg:   msg,
+		Edits: edits,
+	})
+	return d
+}
+
+func (d *Diagnostic) Error() string {
+	if d.Pos.Filename == "" {
+		return fmt.Sprintf("%s: %s", d.Severity, d.Msg)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s: %s", d.Pos.Filename, d.Pos.Line, d.Pos.Column, d.Severity, d.Msg)
+}
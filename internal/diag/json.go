@@ -0,0 +1,128 @@
+package diag
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/danecwalker/hippo/internal/syntax"
+)
+
+// JSONWriter writes the diagnostics of a compilation as a single JSON array
+// when it is flushed, so that tools need not parse the text format. Spans
+// are given as lines and columns counting from 1, columns counting
+// characters, and byte offsets counting from 0; an end is just past the
+// span and is left out when only the start is known.
+type JSONWriter struct {
+	w  io.Writer
+	ds []*Diagnostic
+}
+
+func NewJSONWriter(w io.Writer) *JSONWriter {
+	return &JSONWriter{
+		w: w,
+	}
+}
+
+func (j *JSONWriter) AddFile(f *syntax.SourceFile) {}
+
+func (j *JSONWriter) Print(ds ...*Diagnostic) {
+	j.ds = append(j.ds, ds...)
+}
+
+func (j *JSONWriter) Flush() error {
+	out := make([]*jsonDiagnostic, 0, len(j.ds))
+	for _, d := range j.ds {
+		out = append(out, newJSONDiagnostic(d))
+	}
+	j.ds = nil
+
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = j.w.Write(append(data, '\n'))
+	return err
+}
+
+type jsonDiagnostic struct {
+	Code     Code         `json:"code"`
+	Severity string       `json:"severity"`
+	File     string       `json:"file,omitempty"`
+	Span     *jsonSpan    `json:"span,omitempty"`
+	Message  string       `json:"message"`
+	Related  []*jsonLabel `json:"related,omitempty"`
+	Fixes    []*jsonFix   `json:"fixes,omitempty"`
+}
+
+type jsonLabel struct {
+	File    string    `json:"file"`
+	Span    *jsonSpan `json:"span"`
+	Message string    `json:"message"`
+}
+
+type jsonFix struct {
+	Message string      `json:"message"`
+	Edits   []*jsonEdit `json:"edits"`
+}
+
+type jsonEdit struct {
+	File    string    `json:"file"`
+	Span    *jsonSpan `json:"span"`
+	NewText string    `json:"newText"`
+}
+
+type jsonSpan struct {
+	Start *jsonPosition `json:"start"`
+	End   *jsonPosition `json:"end,omitempty"`
+}
+
+type jsonPosition struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	Offset int `json:"offset"`
+}
+
+func newJSONDiagnostic(d *Diagnostic) *jsonDiagnostic {
+	jd := &jsonDiagnostic{
+		Code:     d.Code,
+		Severity: d.Severity.String(),
+		File:     d.Pos.Filename,
+		Span:     newJSONSpan(&d.Pos, &d.End),
+		Message:  d.Msg,
+	}
+	for _, l := range d.Related {
+		jd.Related = append(jd.Related, &jsonLabel{
+			File:    l.Pos.Filename,
+			Span:    newJSONSpan(&l.Pos, &l.End),
+			Message: l.Msg,
+		})
+	}
+	for _, f := range d.Fixes {
+		jf := &jsonFix{
+			Message: f.Msg,
+		}
+		for _, e := range f.Edits {
+			jf.Edits = append(jf.Edits, &jsonEdit{
+				File:    e.Pos.Filename,
+				Span:    newJSONSpan(&e.Pos, &e.End),
+				NewText: e.NewText,
+			})
+		}
+		jd.Fixes = append(jd.Fixes, jf)
+	}
+	return jd
+}
+
+func newJSONSpan(pos, end *syntax.Position) *jsonSpan {
+	if pos.Line == 0 {
+		return nil
+	}
+
+	s := &jsonSpan{
+		Start: &jsonPosition{pos.Line, pos.Column, pos.Offset},
+	}
+	if end.Line != 0 {
+		s.End = &jsonPosition{end.Line, end.Column, end.Offset}
+	}
+	return s
+}
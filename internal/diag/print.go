@@ -0,0 +1,148 @@
+package diag
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/danecwalker/hippo/internal/syntax"
+)
+
+const (
+	colorReset   = "\033[0m"
+	colorBold    = "\033[1m"
+	colorRed     = "\033[1;31m"
+	colorMagenta = "\033[1;35m"
+	colorCyan    = "\033[1;36m"
+	colorGreen   = "\033[1;32m"
+)
+
+// Printer writes diagnostics for people to read. When it has the source
+// file a diagnostic refers to it quotes the line and underlines the span.
+type Printer struct {
+	w     io.Writer
+	color bool
+	files map[string]*syntax.SourceFile
+}
+
+func NewPrinter(w io.Writer, color bool) *Printer {
+	return &Printer{
+		w:     w,
+		color: color,
+		files: make(map[string]*syntax.SourceFile),
+	}
+}
+
+// UseColor reports whether diagnostics written to f should be coloured,
+// which is when f is a terminal and NO_COLOR is not set.
+func UseColor(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// AddFile makes the text of f available for quoting in diagnostics that
+// refer to it by name.
+func (p *Printer) AddFile(f *syntax.SourceFile) {
+	p.files[f.Name] = f
+}
+
+// Print writes each of ds followed by its notes and suggested fixes.
+func (p *Printer) Print(ds ...*Diagnostic) {
+	for _, d := range ds {
+		p.print(d.Severity, &d.Pos, &d.End, d.Msg)
+		for _, l := range d.Related {
+			p.print(SeverityNote, &l.Pos, &l.End, l.Msg)
+		}
+		for _, f := range d.Fixes {
+			p.paint(colorCyan, "help:")
+			fmt.Fprintf(p.w, " %s\n", f.Msg)
+		}
+	}
+}
+
+// Flush does nothing: a Printer writes each diagnostic as it is printed.
+func (p *Printer) Flush() error {
+	return nil
+}
+
+func (p *Printer) print(severity Severity, pos, end *syntax.Position, msg string) {
+	if pos.Filename != "" {
+		p.paint(colorBold, fmt.Sprintf("%s:%d:%d: ", pos.Filename, pos.Line, pos.Column))
+	}
+	p.paint(severityColor(severity), severity.String()+":")
+	p.paint(colorBold, " "+msg)
+	fmt.Fprintln(p.w)
+
+	f, ok := p.files[pos.Filename]
+	if !ok || pos.Line < 1 || pos.Line > f.LineCount() {
+		return
+	}
+
+	line := f.Line(pos.Line)
+	if pos.Line == 1 {
+		line = strings.TrimPrefix(line, "\uFEFF")
+	}
+	gutter := len(fmt.Sprint(pos.Line))
+
+	fmt.Fprintf(p.w, " %*d | %s\n", gutter, pos.Line, line)
+	fmt.Fprintf(p.w, " %*s | ", gutter, "")
+	p.paint(colorGreen, underline(line, pos, end))
+	fmt.Fprintln(p.w)
+}
+
+// underline returns the text that, printed beneath line, puts a caret under
+// pos and tildes under the rest of the span up to end. Tabs before the caret
+// are kept so that it lines up however wide the terminal draws them.
+func underline(line string, pos, end *syntax.Position) string {
+	runes := []rune(line)
+
+	var w strings.Builder
+	for i := 0; i < pos.Column-1; i++ {
+		if i < len(runes) && runes[i] == '\t' {
+			w.WriteByte('\t')
+		} else {
+			w.WriteByte(' ')
+		}
+	}
+	w.WriteByte('^')
+
+	last := pos.Column
+	switch {
+	case end.Line == pos.Line:
+		last = end.Column - 1
+	case end.Line > pos.Line:
+		last = len(runes)
+	}
+	for i := pos.Column; i < last; i++ {
+		w.WriteByte('~')
+	}
+
+	return w.String()
+}
+
+func (p *Printer) paint(color string, s string) {
+	if p.color {
+		fmt.Fprint(p.w, color, s, colorReset)
+		return
+	}
+	fmt.Fprint(p.w, s)
+}
+
+func severityColor(s Severity) string {
+	switch s {
+	case SeverityWarning:
+		return colorMagenta
+	case SeverityNote:
+		return colorCyan
+	default:
+		return colorRed
+	}
+}
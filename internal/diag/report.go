@@ -0,0 +1,37 @@
+package diag
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/danecwalker/hippo/internal/syntax"
+)
+
+// Reporter receives the diagnostics of a compilation as they are found.
+type Reporter interface {
+	// AddFile makes the text of f available to diagnostics that refer to
+	// it by name.
+	AddFile(f *syntax.SourceFile)
+	Print(ds ...*Diagnostic)
+	// Flush writes anything the Reporter holds back until the end of the
+	// compilation.
+	Flush() error
+}
+
+// Formats lists the names NewReporter accepts.
+var Formats = []string{"text", "json", "sarif"}
+
+// NewReporter returns a Reporter writing diagnostics to w in format, one of
+// Formats. color only affects the text format.
+func NewReporter(format string, w io.Writer, color bool) (Reporter, error) {
+	switch format {
+	case "text", "":
+		return NewPrinter(w, color), nil
+	case "json":
+		return NewJSONWriter(w), nil
+	case "sarif":
+		return NewSARIFWriter(w), nil
+	default:
+		return nil, fmt.Errorf("unknown diagnostics format %q", format)
+	}
+}
@@ -0,0 +1,220 @@
+package diag
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"sort"
+
+	"github.com/danecwalker/hippo/internal/syntax"
+)
+
+// SARIFWriter writes the diagnostics of a compilation as a SARIF 2.1.0 log
+// when it is flushed, for code scanning tools. Each diagnostic code is a
+// rule of the log.
+type SARIFWriter struct {
+	w  io.Writer
+	ds []*Diagnostic
+}
+
+func NewSARIFWriter(w io.Writer) *SARIFWriter {
+	return &SARIFWriter{
+		w: w,
+	}
+}
+
+func (s *SARIFWriter) AddFile(f *syntax.SourceFile) {}
+
+func (s *SARIFWriter) Print(ds ...*Diagnostic) {
+	s.ds = append(s.ds, ds...)
+}
+
+func (s *SARIFWriter) Flush() error {
+	codes := make(map[Code]bool)
+	results := make([]*sarifResult, 0, len(s.ds))
+	for _, d := range s.ds {
+		codes[d.Code] = true
+		results = append(results, newSARIFResult(d))
+	}
+	s.ds = nil
+
+	rules := make([]*sarifRule, 0, len(codes))
+	for code := range codes {
+		rules = append(rules, &sarifRule{ID: code})
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+
+	log := &sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []*sarifRun{{
+			Tool: &sarifTool{
+				Driver: &sarifDriver{
+					Name:           "hippo",
+					InformationURI: "https://github.com/danecwalker/hippo",
+					Rules:          rules,
+				},
+			},
+			ColumnKind: "unicodeCodePoints",
+			Results:    results,
+		}},
+	}
+
+	data, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = s.w.Write(append(data, '\n'))
+	return err
+}
+
+type sarifLog struct {
+	Schema  string      `json:"$schema"`
+	Version string      `json:"version"`
+	Runs    []*sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool       *sarifTool     `json:"tool"`
+	ColumnKind string         `json:"columnKind"`
+	Results    []*sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver *sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string       `json:"name"`
+	InformationURI string       `json:"informationUri"`
+	Rules          []*sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID Code `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID           Code             `json:"ruleId"`
+	Level            string           `json:"level"`
+	Message          *sarifMessage    `json:"message"`
+	Locations        []*sarifLocation `json:"locations,omitempty"`
+	RelatedLocations []*sarifLocation `json:"relatedLocations,omitempty"`
+	Fixes            []*sarifFix      `json:"fixes,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	ID               int                    `json:"id,omitempty"`
+	Message          *sarifMessage          `json:"message,omitempty"`
+	PhysicalLocation *sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation *sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine,omitempty"`
+	EndColumn   int `json:"endColumn,omitempty"`
+}
+
+type sarifFix struct {
+	Description     *sarifMessage          `json:"description"`
+	ArtifactChanges []*sarifArtifactChange `json:"artifactChanges"`
+}
+
+type sarifArtifactChange struct {
+	ArtifactLocation *sarifArtifactLocation `json:"artifactLocation"`
+	Replacements     []*sarifReplacement    `json:"replacements"`
+}
+
+type sarifReplacement struct {
+	DeletedRegion   *sarifRegion  `json:"deletedRegion"`
+	InsertedContent *sarifMessage `json:"insertedContent"`
+}
+
+func newSARIFResult(d *Diagnostic) *sarifResult {
+	r := &sarifResult{
+		RuleID:  d.Code,
+		Level:   d.Severity.String(),
+		Message: &sarifMessage{Text: d.Msg},
+	}
+	if d.Pos.Filename != "" {
+		r.Locations = []*sarifLocation{newSARIFLocation(&d.Pos, &d.End)}
+	}
+
+	for i, l := range d.Related {
+		loc := newSARIFLocation(&l.Pos, &l.End)
+		loc.ID = i + 1
+		loc.Message = &sarifMessage{Text: l.Msg}
+		r.RelatedLocations = append(r.RelatedLocations, loc)
+	}
+
+	for _, f := range d.Fixes {
+		fix := &sarifFix{
+			Description: &sarifMessage{Text: f.Msg},
+		}
+		// A fix changes each file once, with its replacements in order.
+		changes := make(map[string]*sarifArtifactChange)
+		for _, e := range f.Edits {
+			change, ok := changes[e.Pos.Filename]
+			if !ok {
+				change = &sarifArtifactChange{
+					ArtifactLocation: &sarifArtifactLocation{URI: sarifURI(e.Pos.Filename)},
+				}
+				changes[e.Pos.Filename] = change
+				fix.ArtifactChanges = append(fix.ArtifactChanges, change)
+			}
+			change.Replacements = append(change.Replacements, &sarifReplacement{
+				DeletedRegion:   newSARIFRegion(&e.Pos, &e.End),
+				InsertedContent: &sarifMessage{Text: e.NewText},
+			})
+		}
+		r.Fixes = append(r.Fixes, fix)
+	}
+
+	return r
+}
+
+func newSARIFLocation(pos, end *syntax.Position) *sarifLocation {
+	return &sarifLocation{
+		PhysicalLocation: &sarifPhysicalLocation{
+			ArtifactLocation: &sarifArtifactLocation{URI: sarifURI(pos.Filename)},
+			Region:           newSARIFRegion(pos, end),
+		},
+	}
+}
+
+// newSARIFRegion returns the region from pos to end, leaving out the end
+// when it is not known. SARIF end columns are exclusive like ours.
+func newSARIFRegion(pos, end *syntax.Position) *sarifRegion {
+	r := &sarifRegion{
+		StartLine:   pos.Line,
+		StartColumn: pos.Column,
+	}
+	if end.Line != 0 {
+		r.EndLine = end.Line
+		r.EndColumn = end.Column
+	}
+	return r
+}
+
+// sarifURI returns filename as a URI reference: relative paths stay
+// relative to the directory the compiler ran in.
+func sarifURI(filename string) string {
+	if filepath.IsAbs(filename) {
+		return "file://" + filepath.ToSlash(filename)
+	}
+	return filepath.ToSlash(filename)
+}
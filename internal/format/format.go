@@ -0,0 +1,460 @@
+package format
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/danecwalker/hippo/internal/syntax"
+)
+
+type printer struct {
+	w      bytes.Buffer
+	indent int
+
+	comments []*syntax.Comment // not yet printed, in source order
+	end      int               // source line on which the last expression printed ends
+}
+
+// Program renders prog as canonical Hippo source: two space indentation,
+// single spaces around binary operators and a blank line between top level
+// declarations that are not both variables. Comments are kept on their own
+// line before the code that follows them, or at the end of the line they
+// appeared on. Comments inside an expression stay where they are.
+func Program(prog *syntax.Program) []byte {
+	p := &printer{comments: prog.Comments}
+
+	for i, stmt := range prog.Statements {
+		if i > 0 {
+			_, prevVar := prog.Statements[i-1].(*syntax.VarStatement)
+			_, curVar := stmt.(*syntax.VarStatement)
+			if !prevVar || !curVar {
+				p.w.WriteString("\n")
+			}
+		}
+		p.stmt(stmt)
+	}
+	p.flush(-1)
+
+	return p.w.Bytes()
+}
+
+func (p *printer) line(s string) {
+	p.w.WriteString(strings.Repeat("  ", p.indent))
+	p.w.WriteString(s)
+	p.w.WriteString("\n")
+}
+
+// flush prints the comments that start before offset on lines of their own.
+// A negative offset flushes every remaining comment.
+func (p *printer) flush(offset int) {
+	last := 0
+	for len(p.comments) > 0 {
+		c := p.comments[0]
+		if offset >= 0 && c.Pos().Offset >= offset {
+			return
+		}
+		if last > 0 && c.Pos().Line > last+1 {
+			// Keep comments separated by blank lines apart.
+			p.w.WriteString("\n")
+		}
+		p.line(c.Text)
+		last = c.Pos().Line + strings.Count(c.Text, "\n")
+		p.comments = p.comments[1:]
+	}
+}
+
+// trailing appends the comments that start on source line line to the last
+// printed line.
+func (p *printer) trailing(line int) {
+	p.trailingBefore(line, -1)
+}
+
+// trailingBefore is trailing for the comments that start before offset, so
+// that those after a closing brace later on the line are left for it. A
+// negative offset does not limit them.
+func (p *printer) trailingBefore(line, offset int) {
+	for len(p.comments) > 0 && p.comments[0].Pos().Line == line {
+		if offset >= 0 && p.comments[0].Pos().Offset >= offset {
+			return
+		}
+		p.w.Truncate(p.w.Len() - 1)
+		p.w.WriteString(" ")
+		p.w.WriteString(p.comments[0].Text)
+		p.w.WriteString("\n")
+		p.comments = p.comments[1:]
+	}
+}
+
+// inline returns the comments that start before offset, which fall inside
+// an expression, to be printed in front of the code at offset. Code after a
+// line comment continues on the next line, indented.
+func (p *printer) inline(offset int) string {
+	var w strings.Builder
+	for len(p.comments) > 0 && p.comments[0].Pos().Offset < offset {
+		w.WriteString(p.comments[0].Text)
+		if isLineComment(p.comments[0]) {
+			w.WriteString("\n" + strings.Repeat("  ", p.indent+1))
+		} else {
+			w.WriteString(" ")
+		}
+		p.comments = p.comments[1:]
+	}
+	return w.String()
+}
+
+// closing is inline for the comments before a closing bracket at offset.
+func (p *printer) closing(offset int) string {
+	var w strings.Builder
+	for len(p.comments) > 0 && p.comments[0].Pos().Offset < offset {
+		w.WriteString(" " + p.comments[0].Text)
+		if isLineComment(p.comments[0]) {
+			w.WriteString("\n" + strings.Repeat("  ", p.indent))
+		}
+		p.comments = p.comments[1:]
+	}
+	return w.String()
+}
+
+// lines returns the comments that start before offset in a list printed one
+// item per line. Those on line, where the previous item ended, trail it and
+// the rest are put on lines of their own.
+func (p *printer) lines(line, offset int) string {
+	var w strings.Builder
+	for len(p.comments) > 0 && p.comments[0].Pos().Line == line && p.comments[0].Pos().Offset < offset {
+		w.WriteString(" " + p.comments[0].Text)
+		p.comments = p.comments[1:]
+	}
+	w.WriteString("\n")
+	for len(p.comments) > 0 && p.comments[0].Pos().Offset < offset {
+		w.WriteString(strings.Repeat("  ", p.indent) + p.comments[0].Text + "\n")
+		p.comments = p.comments[1:]
+	}
+	return w.String()
+}
+
+// lineCommentBefore reports whether a line comment starts before offset.
+func (p *printer) lineCommentBefore(offset int) bool {
+	for _, c := range p.comments {
+		if c.Pos().Offset >= offset {
+			return false
+		}
+		if isLineComment(c) {
+			return true
+		}
+	}
+	return false
+}
+
+func isLineComment(c *syntax.Comment) bool {
+	return strings.HasPrefix(c.Text, "//")
+}
+
+func (p *printer) stmt(stmt syntax.Statement) {
+	p.flush(stmt.Pos().Offset)
+
+	switch stmt := stmt.(type) {
+	case *syntax.BlockStmt:
+		p.line("{")
+		p.trailing(stmt.Pos().Line)
+		p.block(stmt)
+		p.closeBrace(stmt, "}")
+	case *syntax.TypeStmt:
+		p.typeStmt(stmt)
+	default:
+		// The header may span lines when comments inside it were kept.
+		p.end = stmt.Pos().Line
+		p.line(p.header(stmt))
+		body := blockOf(stmt)
+		if body != nil {
+			p.trailing(body.Lbrace.Line)
+			p.block(body)
+			p.closeStmt(stmt)
+		} else {
+			p.trailing(p.end)
+		}
+	}
+}
+
+// closeBrace prints s, which closes block, followed by any comment on the
+// line of the closing brace.
+func (p *printer) closeBrace(block *syntax.BlockStmt, s string) {
+	p.line(s)
+	if block.Rbrace != nil {
+		p.trailing(block.Rbrace.Line)
+	}
+}
+
+// header returns the text of stmt up to and including the opening brace of
+// its body, if it has one.
+func (p *printer) header(stmt syntax.Statement) string {
+	switch stmt := stmt.(type) {
+	case *syntax.VarStatement:
+		return p.varStmt(stmt)
+	case *syntax.FuncStmt:
+		return p.funcHeader(stmt) + " {"
+	case *syntax.ReturnStmt:
+		if len(stmt.Results) == 0 {
+			return "ret"
+		}
+		return "ret " + p.exprList(stmt.Results)
+	case *syntax.ExpressionStmt:
+		return p.expr(stmt.X)
+	case *syntax.TupleAssignStmt:
+		return p.exprList(stmt.Lhs) + " = " + p.exprList(stmt.Rhs)
+	case *syntax.IncDecStmt:
+		return p.expr(stmt.X) + stmt.Tok.String()
+	case *syntax.IfStmt:
+		return "if " + p.expr(stmt.Cond) + " {"
+	case *syntax.ForRangeStmt:
+		if stmt.Value != nil {
+			return "for " + stmt.Key.Name + ", " + stmt.Value.Name + " <- " + p.expr(stmt.X) + " {"
+		}
+		return "for " + stmt.Key.Name + " <- " + p.expr(stmt.X) + " {"
+	case *syntax.WhileStmt:
+		if ident, ok := stmt.Cond.(*syntax.Identifier); ok && ident.Name == "true" {
+			return "for {"
+		}
+		return "for " + p.expr(stmt.Cond) + " {"
+	case *syntax.ForLoopStmt:
+		header := "for " + p.simpleStmt(stmt.Init) + "; " + p.optExpr(stmt.Cond) + ";"
+		if stmt.Post != nil {
+			header += " " + p.simpleStmt(stmt.Post)
+		}
+		return header + " {"
+	case *syntax.LabeledStmt:
+		return stmt.Label.Name + ": " + p.header(stmt.Stmt)
+	case *syntax.BranchStmt:
+		if stmt.Label != nil {
+			return stmt.Tok.String() + " " + stmt.Label.Name
+		}
+		return stmt.Tok.String()
+	default:
+		return "<bad statement>"
+	}
+}
+
+func blockOf(stmt syntax.Statement) *syntax.BlockStmt {
+	switch stmt := stmt.(type) {
+	case *syntax.FuncStmt:
+		return stmt.Body
+	case *syntax.IfStmt:
+		return stmt.Consequence
+	case *syntax.ForRangeStmt:
+		return stmt.Body
+	case *syntax.WhileStmt:
+		return stmt.Body
+	case *syntax.ForLoopStmt:
+		return stmt.Body
+	case *syntax.LabeledStmt:
+		return blockOf(stmt.Stmt)
+	default:
+		return nil
+	}
+}
+
+// closeStmt writes the closing brace of stmt, folding else branches onto the
+// same line.
+func (p *printer) closeStmt(stmt syntax.Statement) {
+	body := blockOf(stmt)
+	is, ok := stmt.(*syntax.IfStmt)
+	if !ok || is.Alternative == nil {
+		p.closeBrace(body, "}")
+		return
+	}
+
+	switch alt := is.Alternative.(type) {
+	case *syntax.IfStmt:
+		p.closeBrace(body, "} else "+p.header(alt))
+		p.block(alt.Consequence)
+		p.closeStmt(alt)
+	case *syntax.BlockStmt:
+		p.closeBrace(body, "} else {")
+		p.block(alt)
+		p.closeBrace(alt, "}")
+	}
+}
+
+func (p *printer) block(block *syntax.BlockStmt) {
+	p.indent++
+	for _, stmt := range block.Stmts {
+		p.stmt(stmt)
+	}
+	if block.Rbrace != nil {
+		p.flush(block.Rbrace.Offset)
+	}
+	p.indent--
+}
+
+func (p *printer) simpleStmt(stmt syntax.Statement) string {
+	if stmt == nil {
+		return ""
+	}
+	return p.header(stmt)
+}
+
+func (p *printer) varStmt(stmt *syntax.VarStatement) string {
+	var w strings.Builder
+	w.WriteString(stmt.Kind)
+	w.WriteString(" ")
+	w.WriteString(p.identList(stmt.Names))
+	if stmt.Type != nil {
+		w.WriteString(" ")
+		w.WriteString(p.typeName(stmt.Type))
+	}
+	if len(stmt.Values) > 0 {
+		w.WriteString(" = ")
+		w.WriteString(p.exprList(stmt.Values))
+	}
+	return w.String()
+}
+
+// typeStmt prints a struct declaration with one line for each run of fields
+// that were declared together.
+func (p *printer) typeStmt(stmt *syntax.TypeStmt) {
+	st := stmt.Type
+	if len(st.Fields) == 0 {
+		p.line("type " + stmt.Name.Name + " struct {}")
+		p.trailing(st.Rbrace.Line)
+		return
+	}
+
+	p.line("type " + stmt.Name.Name + " struct {")
+	p.trailingBefore(stmt.Pos().Line, st.Rbrace.Offset)
+	p.indent++
+	for i := 0; i < len(st.Fields); {
+		f := st.Fields[i]
+		p.flush(f.Name.Pos().Offset)
+
+		names := []*syntax.Identifier{f.Name}
+		for i++; i < len(st.Fields) && st.Fields[i].Type == f.Type; i++ {
+			names = append(names, st.Fields[i].Name)
+		}
+		p.line(p.identList(names) + " " + p.typeName(f.Type))
+		p.trailingBefore(f.Name.Pos().Line, st.Rbrace.Offset)
+	}
+	p.flush(st.Rbrace.Offset)
+	p.indent--
+	p.line("}")
+	p.trailing(st.Rbrace.Line)
+}
+
+func (p *printer) funcHeader(stmt *syntax.FuncStmt) string {
+	var w strings.Builder
+	w.WriteString("fn ")
+	w.WriteString(stmt.Name.Name)
+	if len(stmt.Type.Params) > 0 {
+		w.WriteString(" : ")
+		for i, field := range stmt.Type.Params {
+			if i > 0 {
+				w.WriteString(", ")
+			}
+			w.WriteString(p.identList(field.Names))
+			w.WriteString(" ")
+			w.WriteString(p.typeName(field.Type))
+		}
+	}
+	if len(stmt.Type.Results) > 0 {
+		w.WriteString(" -> ")
+		for i, result := range stmt.Type.Results {
+			if i > 0 {
+				w.WriteString(", ")
+			}
+			w.WriteString(p.typeName(result))
+		}
+	}
+	return w.String()
+}
+
+// typeName spells the type ident names, which the parser spells as written
+// for an array or slice type.
+func (p *printer) typeName(ident *syntax.Identifier) string {
+	if ident.Obj != nil {
+		if at, ok := ident.Obj.Decl.(*syntax.ArrayType); ok {
+			return "[" + p.optExpr(at.Len) + "]" + p.typeName(at.Elem)
+		}
+	}
+	return ident.Name
+}
+
+func (p *printer) identList(idents []*syntax.Identifier) string {
+	names := make([]string, len(idents))
+	for i, ident := range idents {
+		names[i] = ident.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+func (p *printer) exprList(exprs []syntax.Expression) string {
+	parts := make([]string, len(exprs))
+	for i, expr := range exprs {
+		parts[i] = p.expr(expr)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (p *printer) optExpr(expr syntax.Expression) string {
+	if expr == nil {
+		return ""
+	}
+	return p.expr(expr)
+}
+
+func (p *printer) expr(expr syntax.Expression) string {
+	s := p.inline(expr.Pos().Offset)
+	s += p.exprText(expr)
+	p.end = expr.End().Line
+	return s
+}
+
+func (p *printer) exprText(expr syntax.Expression) string {
+	switch expr := expr.(type) {
+	case *syntax.Identifier:
+		return expr.Name
+	case *syntax.BasicLit:
+		return expr.Value
+	case *syntax.BinaryExpr:
+		return p.expr(expr.X) + " " + expr.Op.Literal + " " + p.expr(expr.Y)
+	case *syntax.UnaryExpr:
+		return expr.Op.Literal + p.expr(expr.X)
+	case *syntax.ParenExpr:
+		return "(" + p.expr(expr.X) + p.closing(expr.Rparen.Offset) + ")"
+	case *syntax.AssignmentExpr:
+		return p.expr(expr.Lhs) + " " + expr.Op.Literal + " " + p.expr(expr.Rhs)
+	case *syntax.RangeExpr:
+		return p.expr(expr.Low) + ".." + p.expr(expr.High)
+	case *syntax.CallExpr:
+		return expr.Func.Name + "(" + p.exprList(expr.Args) + p.closing(expr.Rparen.Offset) + ")"
+	case *syntax.SelectorExpr:
+		return p.expr(expr.X) + "." + expr.Sel.Name
+	case *syntax.IndexExpr:
+		return p.expr(expr.X) + "[" + p.expr(expr.Index) + p.closing(expr.Rbrack.Offset) + "]"
+	case *syntax.CompositeLit:
+		return p.compositeLit(expr)
+	case *syntax.KeyValueExpr:
+		return expr.Key.Name + ": " + p.expr(expr.Value)
+	default:
+		return "<bad expression>"
+	}
+}
+
+// compositeLit prints lit on one line unless a line comment inside it needs
+// the elements on lines of their own.
+func (p *printer) compositeLit(lit *syntax.CompositeLit) string {
+	if !p.lineCommentBefore(lit.Rbrace.Offset) {
+		return p.typeName(lit.Type) + "{" + p.exprList(lit.Elts) + p.closing(lit.Rbrace.Offset) + "}"
+	}
+
+	var w strings.Builder
+	w.WriteString(p.typeName(lit.Type) + "{")
+	p.indent++
+	line := lit.Lbrace.Line
+	for _, elt := range lit.Elts {
+		w.WriteString(p.lines(line, elt.Pos().Offset))
+		w.WriteString(strings.Repeat("  ", p.indent) + p.expr(elt) + ",")
+		line = elt.End().Line
+	}
+	w.WriteString(p.lines(line, lit.Rbrace.Offset))
+	p.indent--
+	w.WriteString(strings.Repeat("  ", p.indent) + "}")
+	return w.String()
+}
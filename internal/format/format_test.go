@@ -0,0 +1,52 @@
+package format
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/danecwalker/hippo/internal/parse"
+)
+
+// format parses src as name and returns it formatted, failing the test if
+// it does not parse.
+func format(t *testing.T, name string, src []byte) []byte {
+	t.Helper()
+
+	p := parse.NewParserFromBytes(name, src)
+	prog := p.ParseProgram()
+	if errs := p.Errors(); len(errs) > 0 {
+		t.Fatalf("parse: %v", errs[0])
+	}
+	return Program(prog)
+}
+
+// TestGolden formats each testdata/*.x file and compares the result with
+// the .golden file beside it, which must itself format unchanged.
+func TestGolden(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		golden := strings.TrimSuffix(file, ".x") + ".golden"
+		want, err := os.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := format(t, file, src); !bytes.Equal(got, want) {
+			t.Errorf("%s: got\n%s\nwant\n%s", file, got, want)
+		}
+		if got := format(t, golden, want); !bytes.Equal(got, want) {
+			t.Errorf("%s is not formatted: got\n%s", golden, got)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+type P struct { x, y i32 }
+
+fn f : a, b i32 -> i32 {
+  ret a + /* b */ b
+}
+
+fn main -> i32 {
+  var x = 1 + /* two */ 2 // x
+  var p = P{
+    // first
+    x: 1, // one
+    y: f(x, /* arg */ 2),
+  } // p
+  var q = P{x: 1, /* y */ y: 2}
+  var z = x +
+    // more
+    p.y
+  ret f(z, q.x /* last */)
+}
@@ -0,0 +1,14 @@
+type P struct { a i32 } // end
+
+type S struct {
+  a i32
+  b i32 } // end of S
+
+type Q struct {} // empty
+
+type R struct { // open
+  x, y i32 // xy
+  // before close
+} // close
+
+type T struct { /* inline */ a i32 } // after
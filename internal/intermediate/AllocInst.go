@@ -24,9 +24,11 @@ func (inst *AllocInst) pretty(w *bytes.Buffer, indent int) {
 	w.WriteString(inst.Name)
 	w.WriteString(" = ")
 	inst.Value.pretty(w, indent)
-	w.WriteRune(' ')
-	w.WriteRune(':')
-	w.WriteRune(' ')
-	w.WriteString(inst.Type.Name)
+	if inst.Type != nil {
+		w.WriteRune(' ')
+		w.WriteRune(':')
+		w.WriteRune(' ')
+		w.WriteString(inst.Type.Name)
+	}
 	w.WriteRune('\n')
 }
@@ -0,0 +1,32 @@
+package intermediate
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// ArrayInst allocates storage for an array of Words words and produces a
+// pointer to it. The storage is zero, or a copy of the array From points to
+// if it is set.
+type ArrayInst struct {
+	Words int64
+	From  Inst
+}
+
+func NewArrayInst(words int64, from Inst) *ArrayInst {
+	return &ArrayInst{
+		Words: words,
+		From:  from,
+	}
+}
+
+func (inst *ArrayInst) inst() {}
+
+func (inst *ArrayInst) pretty(w *bytes.Buffer, indent int) {
+	w.WriteString(fmt.Sprintf("array(%d", inst.Words))
+	if inst.From != nil {
+		w.WriteString(", ")
+		inst.From.pretty(w, indent)
+	}
+	w.WriteRune(')')
+}
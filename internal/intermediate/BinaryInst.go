@@ -1,42 +1,123 @@
 package intermediate
 
-import "bytes"
+import (
+	"bytes"
 
+	"github.com/danecwalker/hippo/internal/syntax"
+)
+
+// BinaryInst applies Op to two operands of the same Type. Pos is the
+// position of the operator of a division, reported if the divisor is zero.
 type BinaryInst struct {
 	Op    string
 	Left  Inst
 	Right Inst
+	Type  *syntax.Object
+	Pos   *syntax.Position
 }
 
-func NewBinaryInst(op string, left Inst, right Inst) *BinaryInst {
+func NewBinaryInst(op string, left Inst, right Inst, type_ *syntax.Object) *BinaryInst {
 	return &BinaryInst{
 		Op:    op,
 		Left:  left,
 		Right: right,
+		Type:  type_,
 	}
 }
 
 func (inst *BinaryInst) inst() {}
 func (inst *BinaryInst) pretty(w *bytes.Buffer, indent int) {
-	inst.Left.pretty(w, indent)
+	prettyOperand(w, inst.Left, indent)
 	w.WriteRune(' ')
 	w.WriteString(inst.Op)
 	w.WriteRune(' ')
-	inst.Right.pretty(w, indent)
+	prettyOperand(w, inst.Right, indent)
+}
+
+// prettyOperand prints the operand x of an operator, in parentheses if it is
+// itself a binary operation.
+func prettyOperand(w *bytes.Buffer, x Inst, indent int) {
+	if _, ok := x.(*BinaryInst); ok {
+		w.WriteRune('(')
+		x.pretty(w, indent)
+		w.WriteRune(')')
+		return
+	}
+	x.pretty(w, indent)
+}
+
+func NewAddInst(left Inst, right Inst, type_ *syntax.Object) Inst {
+	return NewBinaryInst("+", left, right, type_)
+}
+
+func NewSubInst(left Inst, right Inst, type_ *syntax.Object) Inst {
+	return NewBinaryInst("-", left, right, type_)
+}
+
+func NewMulInst(left Inst, right Inst, type_ *syntax.Object) Inst {
+	return NewBinaryInst("*", left, right, type_)
+}
+
+func NewDivInst(pos *syntax.Position, left Inst, right Inst, type_ *syntax.Object) Inst {
+	inst := NewBinaryInst("/", left, right, type_)
+	inst.Pos = pos
+	return inst
+}
+
+func NewRemInst(pos *syntax.Position, left Inst, right Inst, type_ *syntax.Object) Inst {
+	inst := NewBinaryInst("%", left, right, type_)
+	inst.Pos = pos
+	return inst
+}
+
+func NewAndInst(left Inst, right Inst, type_ *syntax.Object) Inst {
+	return NewBinaryInst("&", left, right, type_)
+}
+
+func NewOrInst(left Inst, right Inst, type_ *syntax.Object) Inst {
+	return NewBinaryInst("|", left, right, type_)
+}
+
+func NewXorInst(left Inst, right Inst, type_ *syntax.Object) Inst {
+	return NewBinaryInst("^", left, right, type_)
+}
+
+func NewAndNotInst(left Inst, right Inst, type_ *syntax.Object) Inst {
+	return NewBinaryInst("&^", left, right, type_)
+}
+
+// NewShlInst shifts left left by right bits. The count is unsigned and may be
+// wider than the type, in which case the result is 0.
+func NewShlInst(left Inst, right Inst, type_ *syntax.Object) Inst {
+	return NewBinaryInst("<<", left, right, type_)
+}
+
+// NewShrInst shifts left right by right bits, filling with copies of the sign
+// bit for signed types. Counts wider than the type leave only the fill.
+func NewShrInst(left Inst, right Inst, type_ *syntax.Object) Inst {
+	return NewBinaryInst(">>", left, right, type_)
+}
+
+func NewLtInst(left Inst, right Inst, type_ *syntax.Object) Inst {
+	return NewBinaryInst("<", left, right, type_)
+}
+
+func NewGtInst(left Inst, right Inst, type_ *syntax.Object) Inst {
+	return NewBinaryInst(">", left, right, type_)
 }
 
-func NewAddInst(left Inst, right Inst) Inst {
-	return NewBinaryInst("+", left, right)
+func NewLeInst(left Inst, right Inst, type_ *syntax.Object) Inst {
+	return NewBinaryInst("<=", left, right, type_)
 }
 
-func NewSubInst(left Inst, right Inst) Inst {
-	return NewBinaryInst("-", left, right)
+func NewGeInst(left Inst, right Inst, type_ *syntax.Object) Inst {
+	return NewBinaryInst(">=", left, right, type_)
 }
 
-func NewMulInst(left Inst, right Inst) Inst {
-	return NewBinaryInst("*", left, right)
+func NewEqInst(left Inst, right Inst, type_ *syntax.Object) Inst {
+	return NewBinaryInst("==", left, right, type_)
 }
 
-func NewDivInst(left Inst, right Inst) Inst {
-	return NewBinaryInst("/", left, right)
+func NewNeInst(left Inst, right Inst, type_ *syntax.Object) Inst {
+	return NewBinaryInst("!=", left, right, type_)
 }
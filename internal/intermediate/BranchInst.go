@@ -0,0 +1,28 @@
+package intermediate
+
+import (
+	"bytes"
+	"fmt"
+)
+
+type BranchInst struct {
+	Cond Inst
+	Then *Block
+	Else *Block
+}
+
+func NewBranchInst(cond Inst, then *Block, else_ *Block) *BranchInst {
+	return &BranchInst{
+		Cond: cond,
+		Then: then,
+		Else: else_,
+	}
+}
+
+func (inst *BranchInst) inst() {}
+
+func (inst *BranchInst) pretty(w *bytes.Buffer, indent int) {
+	w.WriteString("branch ")
+	inst.Cond.pretty(w, indent)
+	w.WriteString(fmt.Sprintf(", b%d, b%d\n", inst.Then.Index, inst.Else.Index))
+}
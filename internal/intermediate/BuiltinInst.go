@@ -0,0 +1,32 @@
+package intermediate
+
+import (
+	"bytes"
+
+	"github.com/danecwalker/hippo/internal/syntax"
+)
+
+// BuiltinInst calls the predeclared function Name. Types holds the type of
+// each argument, since builtins such as print accept more than one.
+type BuiltinInst struct {
+	Name  string
+	Args  []Inst
+	Types []*syntax.Object
+}
+
+func NewBuiltinInst(name string, args []Inst, types []*syntax.Object) *BuiltinInst {
+	return &BuiltinInst{
+		Name:  name,
+		Args:  args,
+		Types: types,
+	}
+}
+
+func (inst *BuiltinInst) inst() {}
+
+func (inst *BuiltinInst) pretty(w *bytes.Buffer, indent int) {
+	w.WriteString(inst.Name)
+	w.WriteRune('(')
+	prettyList(w, indent, inst.Args)
+	w.WriteRune(')')
+}
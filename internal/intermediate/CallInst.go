@@ -0,0 +1,39 @@
+package intermediate
+
+import (
+	"bytes"
+
+	"github.com/danecwalker/hippo/internal/syntax"
+)
+
+// CallInst calls Func with Args, which hold a value for every leaf of each
+// argument followed by the storage for each array result.
+type CallInst struct {
+	Func    *syntax.FuncStmt
+	Args    []Inst
+	results int
+}
+
+func NewCallInst(fn *syntax.FuncStmt, args []Inst, results int) *CallInst {
+	return &CallInst{
+		Func:    fn,
+		Args:    args,
+		results: results,
+	}
+}
+
+// Results is the number of values the call produces, counting every leaf
+// of a struct result.
+func (inst *CallInst) Results() int {
+	return inst.results
+}
+
+func (inst *CallInst) inst() {}
+
+func (inst *CallInst) pretty(w *bytes.Buffer, indent int) {
+	w.WriteString("call ")
+	w.WriteString(inst.Func.Name.Name)
+	w.WriteRune('(')
+	prettyList(w, indent, inst.Args)
+	w.WriteRune(')')
+}
@@ -0,0 +1,33 @@
+package intermediate
+
+import (
+	"bytes"
+
+	"github.com/danecwalker/hippo/internal/syntax"
+)
+
+// ConvertInst converts X, a value of type From, to Type. Integers are
+// truncated or extended to the width of Type and floats converted to
+// integers are truncated towards zero.
+type ConvertInst struct {
+	X    Inst
+	From *syntax.Object
+	Type *syntax.Object
+}
+
+func NewConvertInst(x Inst, from *syntax.Object, type_ *syntax.Object) *ConvertInst {
+	return &ConvertInst{
+		X:    x,
+		From: from,
+		Type: type_,
+	}
+}
+
+func (inst *ConvertInst) inst() {}
+
+func (inst *ConvertInst) pretty(w *bytes.Buffer, indent int) {
+	w.WriteString(inst.Type.Name)
+	w.WriteRune('(')
+	inst.X.pretty(w, indent)
+	w.WriteRune(')')
+}
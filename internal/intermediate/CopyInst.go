@@ -0,0 +1,34 @@
+package intermediate
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// CopyInst copies the Words words of the array Src points to into the array
+// Dst points to. Arrays are values, so assigning one copies its elements
+// into the storage of the left operand.
+type CopyInst struct {
+	Dst   Inst
+	Src   Inst
+	Words int64
+}
+
+func NewCopyInst(dst Inst, src Inst, words int64) *CopyInst {
+	return &CopyInst{
+		Dst:   dst,
+		Src:   src,
+		Words: words,
+	}
+}
+
+func (inst *CopyInst) inst() {}
+
+func (inst *CopyInst) pretty(w *bytes.Buffer, indent int) {
+	w.WriteString("copy(")
+	inst.Dst.pretty(w, indent)
+	w.WriteString(", ")
+	inst.Src.pretty(w, indent)
+	w.WriteString(fmt.Sprintf(", %d)", inst.Words))
+	w.WriteRune('\n')
+}
@@ -0,0 +1,37 @@
+package intermediate
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// GrowInst makes room for Add more elements of Words words at the end of
+// the slice whose leaves are the variables Ptr, Len and Cap. If they do not
+// fit within its capacity the elements are moved to a new, larger array and
+// Ptr and Cap updated. Len is left for the caller to advance once the new
+// elements are stored.
+type GrowInst struct {
+	Ptr   *IdentInst
+	Len   *IdentInst
+	Cap   *IdentInst
+	Add   int
+	Words int64
+}
+
+func NewGrowInst(ptr *IdentInst, len_ *IdentInst, cap_ *IdentInst, add int, words int64) *GrowInst {
+	return &GrowInst{
+		Ptr:   ptr,
+		Len:   len_,
+		Cap:   cap_,
+		Add:   add,
+		Words: words,
+	}
+}
+
+func (inst *GrowInst) inst() {}
+
+func (inst *GrowInst) pretty(w *bytes.Buffer, indent int) {
+	w.WriteString("grow(")
+	prettyList(w, indent, []Inst{inst.Ptr, inst.Len, inst.Cap})
+	w.WriteString(fmt.Sprintf(", %d, %d)\n", inst.Add, inst.Words))
+}
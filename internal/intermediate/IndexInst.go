@@ -0,0 +1,52 @@
+package intermediate
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/danecwalker/hippo/internal/syntax"
+)
+
+// IndexInst loads a leaf of element Index of the array whose first element
+// X points to. Elements are Words words long and the leaf starts Offset
+// words into its element. If Index is not less than Len the program panics,
+// reporting Pos. A leaf holding an array is stored inline, so when Addr is
+// set the instruction produces the leaf's address instead of loading it.
+type IndexInst struct {
+	X      Inst
+	Index  Inst
+	Len    Inst
+	Words  int64
+	Offset int64
+	Type   *syntax.Object
+	Addr   bool
+	Pos    *syntax.Position
+}
+
+func NewIndexInst(x Inst, index Inst, len_ Inst, words int64, offset int64, type_ *syntax.Object, addr bool, pos *syntax.Position) *IndexInst {
+	return &IndexInst{
+		X:      x,
+		Index:  index,
+		Len:    len_,
+		Words:  words,
+		Offset: offset,
+		Type:   type_,
+		Addr:   addr,
+		Pos:    pos,
+	}
+}
+
+func (inst *IndexInst) inst() {}
+
+func (inst *IndexInst) pretty(w *bytes.Buffer, indent int) {
+	if inst.Addr {
+		w.WriteRune('&')
+	}
+	inst.X.pretty(w, indent)
+	w.WriteRune('[')
+	inst.Index.pretty(w, indent)
+	if inst.Words != 1 || inst.Offset != 0 {
+		w.WriteString(fmt.Sprintf("*%d+%d", inst.Words, inst.Offset))
+	}
+	w.WriteRune(']')
+}
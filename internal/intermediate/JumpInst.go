@@ -0,0 +1,22 @@
+package intermediate
+
+import (
+	"bytes"
+	"fmt"
+)
+
+type JumpInst struct {
+	Target *Block
+}
+
+func NewJumpInst(target *Block) *JumpInst {
+	return &JumpInst{
+		Target: target,
+	}
+}
+
+func (inst *JumpInst) inst() {}
+
+func (inst *JumpInst) pretty(w *bytes.Buffer, indent int) {
+	w.WriteString(fmt.Sprintf("jump b%d\n", inst.Target.Index))
+}
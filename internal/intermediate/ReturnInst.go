@@ -3,12 +3,12 @@ package intermediate
 import "bytes"
 
 type ReturnInst struct {
-	Expr Inst
+	Results []Inst
 }
 
-func NewReturnInst(expr Inst) *ReturnInst {
+func NewReturnInst(results []Inst) *ReturnInst {
 	return &ReturnInst{
-		Expr: expr,
+		Results: results,
 	}
 }
 
@@ -16,9 +16,9 @@ func (inst *ReturnInst) inst() {}
 
 func (inst *ReturnInst) pretty(w *bytes.Buffer, indent int) {
 	w.WriteString("return")
-	if inst.Expr != nil {
+	if len(inst.Results) > 0 {
 		w.WriteRune(' ')
-		inst.Expr.pretty(w, indent)
+		prettyList(w, indent, inst.Results)
 	}
 	w.WriteRune('\n')
 }
@@ -0,0 +1,33 @@
+package intermediate
+
+import (
+	"bytes"
+
+	"github.com/danecwalker/hippo/internal/syntax"
+)
+
+// SliceCheckInst panics, reporting Pos, unless 0 <= Lo <= Hi <= Cap, the
+// bounds of a slice expression and the capacity of what is sliced.
+type SliceCheckInst struct {
+	Lo  Inst
+	Hi  Inst
+	Cap Inst
+	Pos *syntax.Position
+}
+
+func NewSliceCheckInst(lo Inst, hi Inst, cap_ Inst, pos *syntax.Position) *SliceCheckInst {
+	return &SliceCheckInst{
+		Lo:  lo,
+		Hi:  hi,
+		Cap: cap_,
+		Pos: pos,
+	}
+}
+
+func (inst *SliceCheckInst) inst() {}
+
+func (inst *SliceCheckInst) pretty(w *bytes.Buffer, indent int) {
+	w.WriteString("checkslice(")
+	prettyList(w, indent, []Inst{inst.Lo, inst.Hi, inst.Cap})
+	w.WriteString(")\n")
+}
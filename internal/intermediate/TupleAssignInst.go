@@ -0,0 +1,27 @@
+package intermediate
+
+import "bytes"
+
+// TupleAssignInst assigns every right value to the matching left operand.
+// All right values are evaluated before any assignment takes place. A single
+// call on the right supplies all of its results.
+type TupleAssignInst struct {
+	Lefts  []Inst
+	Rights []Inst
+}
+
+func NewTupleAssignInst(lefts []Inst, rights []Inst) *TupleAssignInst {
+	return &TupleAssignInst{
+		Lefts:  lefts,
+		Rights: rights,
+	}
+}
+
+func (inst *TupleAssignInst) inst() {}
+
+func (inst *TupleAssignInst) pretty(w *bytes.Buffer, indent int) {
+	prettyList(w, indent, inst.Lefts)
+	w.WriteString(" = ")
+	prettyList(w, indent, inst.Rights)
+	w.WriteRune('\n')
+}
@@ -0,0 +1,41 @@
+package intermediate
+
+import (
+	"bytes"
+
+	"github.com/danecwalker/hippo/internal/syntax"
+)
+
+// UnaryInst applies Op to an operand of Type.
+type UnaryInst struct {
+	Op   string
+	X    Inst
+	Type *syntax.Object
+}
+
+func NewUnaryInst(op string, x Inst, type_ *syntax.Object) *UnaryInst {
+	return &UnaryInst{
+		Op:   op,
+		X:    x,
+		Type: type_,
+	}
+}
+
+func (inst *UnaryInst) inst() {}
+
+func (inst *UnaryInst) pretty(w *bytes.Buffer, indent int) {
+	w.WriteString(inst.Op)
+	prettyOperand(w, inst.X, indent)
+}
+
+func NewNotInst(x Inst, type_ *syntax.Object) Inst {
+	return NewUnaryInst("!", x, type_)
+}
+
+func NewNegInst(x Inst, type_ *syntax.Object) Inst {
+	return NewUnaryInst("-", x, type_)
+}
+
+func NewComplementInst(x Inst, type_ *syntax.Object) Inst {
+	return NewUnaryInst("^", x, type_)
+}
@@ -0,0 +1,480 @@
+package intermediate
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/danecwalker/hippo/internal/syntax"
+)
+
+// Arrays and slices are lowered to pointers into flat storage allocated at
+// run time. An array value is a single leaf pointing at its elements, which
+// are laid out one after another, each as the words of its leaves. An array
+// within an element is stored inline rather than by pointer, so a value
+// takes words(t) words however deeply arrays nest. Arrays are values: they
+// are copied whenever they are stored, so no two variables ever share one.
+//
+// A slice is three leaves: a pointer to its first element, its length and
+// its capacity. Slices share the elements they point to.
+
+// arrayOf returns the length and element type of the array type t, and
+// false if t is not an array type.
+func (ir *IR) arrayOf(t *syntax.Object) (int64, *syntax.Object, bool) {
+	if t == nil || t.Kind != syntax.ObjKindType {
+		return 0, nil, false
+	}
+	n, elem, ok := syntax.SplitArrayTypeName(t.Type)
+	if !ok || n < 0 {
+		return 0, nil, false
+	}
+	return n, ir.GetObject(nil, elem), true
+}
+
+// sliceOf returns the element type of the slice type t, or nil if t is not
+// a slice type.
+func (ir *IR) sliceOf(t *syntax.Object) *syntax.Object {
+	if t == nil || t.Kind != syntax.ObjKindType {
+		return nil
+	}
+	n, elem, ok := syntax.SplitArrayTypeName(t.Type)
+	if !ok || n >= 0 {
+		return nil
+	}
+	return ir.GetObject(nil, elem)
+}
+
+func isArray(t *syntax.Object) bool {
+	if t == nil || t.Kind != syntax.ObjKindType {
+		return false
+	}
+	n, _, ok := syntax.SplitArrayTypeName(t.Type)
+	return ok && n >= 0
+}
+
+func isSlice(t *syntax.Object) bool {
+	if t == nil || t.Kind != syntax.ObjKindType {
+		return false
+	}
+	n, _, ok := syntax.SplitArrayTypeName(t.Type)
+	return ok && n < 0
+}
+
+// scalarised reports whether a value of type t is lowered to the list of
+// its leaves rather than a single value.
+func scalarised(t *syntax.Object) bool {
+	return structOf(t) != nil || isSlice(t)
+}
+
+// basic reports whether a value of type t is a single leaf that is not an
+// array, so that it can be stored and copied like any word.
+func basic(t *syntax.Object) bool {
+	return !scalarised(t) && !isArray(t)
+}
+
+// words returns the number of words a value of type t takes when stored in
+// an array.
+func (ir *IR) words(t *syntax.Object) int64 {
+	var n int64
+	for _, l := range ir.leaves(t) {
+		if length, elem, ok := ir.arrayOf(l.t); ok {
+			n += length * ir.words(elem)
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+// internType returns the type object for the array or slice type called
+// name, making one the first time it is asked for.
+func (ir *IR) internType(name string) *syntax.Object {
+	if _, _, ok := syntax.SplitArrayTypeName(name); !ok {
+		return nil
+	}
+	if obj, ok := ir.types[name]; ok {
+		return obj
+	}
+
+	obj := syntax.NewObject(syntax.ObjKindType, name, nil)
+	obj.Type = name
+	ir.types[name] = obj
+	return obj
+}
+
+func (ir *IR) i32() *syntax.Object {
+	return ir.GetObject(nil, "i32")
+}
+
+func (ir *IR) intLit(n int64) Inst {
+	return NewBasicLitInst(strconv.FormatInt(n, 10), ir.i32())
+}
+
+// once returns inst if evaluating it again gives the same value, and
+// otherwise stores its value in a hidden local and returns that.
+func (ir *IR) once(inst Inst) Inst {
+	if _, ok := inst.(*IdentInst); ok {
+		return inst
+	}
+	return ir.hold(inst)
+}
+
+// hold stores the value of inst in a hidden local and returns the local,
+// unless inst is a literal.
+func (ir *IR) hold(inst Inst) Inst {
+	if inst == nil {
+		return nil
+	}
+	if _, ok := inst.(*BasicLitInst); ok {
+		return inst
+	}
+
+	t := ir.typeOf(inst)
+	ir.temps++
+	tmp := syntax.NewObject(syntax.ObjKindVar, fmt.Sprintf("tmp.%d", ir.temps), nil)
+	if t != nil {
+		tmp.Type = t.Name
+	}
+	ir.SetObject(tmp.Name, tmp)
+	ir.AddInstruction(NewAllocInst(tmp.Name, inst, t))
+	return NewIdentInst(tmp.Name)
+}
+
+// fresh reports whether inst produces an array no variable refers to, which
+// may be stored without copying it.
+func (ir *IR) fresh(inst Inst) bool {
+	switch inst := inst.(type) {
+	case *ArrayInst, *CallInst:
+		return true
+	case *IdentInst:
+		return ir.unshared[inst.Name]
+	default:
+		return false
+	}
+}
+
+// own returns inst, the value of a leaf of type t about to be stored, or a
+// copy of it if it is an array that is not fresh.
+func (ir *IR) own(inst Inst, t *syntax.Object) Inst {
+	if !isArray(t) || ir.fresh(inst) {
+		return inst
+	}
+	return NewArrayInst(ir.words(t), inst)
+}
+
+// isArrayLeaf reports whether the left operand inst is a leaf holding an
+// array, which is assigned by copying into its storage.
+func (ir *IR) isArrayLeaf(inst Inst) bool {
+	switch inst := inst.(type) {
+	case *IdentInst:
+		return isArray(ir.typeOf(inst))
+	case *IndexInst:
+		return inst.Addr
+	default:
+		return false
+	}
+}
+
+// store returns the instruction assigning right to the single leaf left.
+func (ir *IR) store(left Inst, right Inst) Inst {
+	if ir.isArrayLeaf(left) {
+		return NewCopyInst(left, right, ir.words(ir.typeOf(left)))
+	}
+	return NewAssignInst(left, right)
+}
+
+// assign lowers the assignment of the leaf values rights to the leaves
+// lefts. The operands of an element on the left are evaluated before any
+// value on the right, as is every value on the right before any is
+// assigned. Arrays are copied into the storage of the left operand, which
+// needs the values on the right to be held in hidden locals first. An array
+// on the right is copied too, as storing another one may change it.
+func (ir *IR) assign(lefts []Inst, rights []Inst) Inst {
+	if len(lefts) == 1 && len(rights) == 1 {
+		return ir.store(lefts[0], rights[0])
+	}
+
+	copies := false
+	for i, left := range lefts {
+		if index, ok := left.(*IndexInst); ok {
+			lefts[i] = ir.holdIndex(index)
+		}
+		copies = copies || ir.isArrayLeaf(left)
+	}
+	if !copies {
+		return NewTupleAssignInst(lefts, rights)
+	}
+
+	temps := make([]Inst, len(lefts))
+	for i, left := range lefts {
+		// A temporary for an array only holds a pointer to it.
+		ir.temps++
+		tmp := syntax.NewObject(syntax.ObjKindVar, fmt.Sprintf("tmp.%d", ir.temps), nil)
+		tmp.Type = "u64"
+		if t := ir.typeOf(left); t != nil && !isArray(t) {
+			tmp.Type = t.Name
+		}
+		ir.SetObject(tmp.Name, tmp)
+		ir.AddInstruction(NewAllocInst(tmp.Name, NewBasicLitInst("0", ir.GetObject(nil, tmp.Type)), ir.GetObject(nil, tmp.Type)))
+		temps[i] = NewIdentInst(tmp.Name)
+
+		if len(rights) == len(lefts) && ir.isArrayLeaf(left) {
+			rights[i] = ir.own(rights[i], ir.typeOf(left))
+		}
+	}
+	ir.AddInstruction(NewTupleAssignInst(temps, rights))
+
+	for i := range lefts[:len(lefts)-1] {
+		ir.AddInstruction(ir.store(lefts[i], temps[i]))
+	}
+	return ir.store(lefts[len(lefts)-1], temps[len(temps)-1])
+}
+
+// holdIndex returns index with its operands held in hidden locals, so that
+// they keep their values while other operands are assigned.
+func (ir *IR) holdIndex(index *IndexInst) *IndexInst {
+	inst := *index
+	inst.X = ir.hold(index.X)
+	inst.Index = ir.hold(index.Index)
+	inst.Len = ir.hold(index.Len)
+	return &inst
+}
+
+// onceIndex returns inst with the operands of an element held in hidden
+// locals if it is one, so that it may be both loaded and stored.
+func (ir *IR) onceIndex(inst Inst) Inst {
+	index, ok := inst.(*IndexInst)
+	if !ok {
+		return inst
+	}
+	i := *index
+	i.X = ir.once(index.X)
+	i.Index = ir.once(index.Index)
+	i.Len = ir.once(index.Len)
+	return &i
+}
+
+// sequence lowers x, an array or slice, to a pointer to its first element,
+// its length and capacity, and returns them with the element type. The
+// element type is nil if x is neither.
+func (ir *IR) sequence(x syntax.Expression) (Inst, Inst, Inst, *syntax.Object) {
+	t := ir.exprType(x)
+	if n, elem, ok := ir.arrayOf(t); ok {
+		length := ir.intLit(n)
+		return ir.generateExpr(x), length, length, elem
+	}
+
+	if elem := ir.sliceOf(t); elem != nil {
+		values := ir.generateValues(x)
+		if len(values) != 3 {
+			return nil, nil, nil, nil
+		}
+		return values[0], values[1], values[2], elem
+	}
+	return nil, nil, nil, nil
+}
+
+// elements returns the leaves of element index of the array ptr points to,
+// whose elements have type elem.
+func (ir *IR) elements(ptr, index, length Inst, elem *syntax.Object, pos *syntax.Position) []Inst {
+	words := ir.words(elem)
+
+	var values []Inst
+	var offset int64
+	for _, l := range ir.leaves(elem) {
+		values = append(values, NewIndexInst(ptr, index, length, words, offset, l.t, isArray(l.t), pos))
+		if n, e, ok := ir.arrayOf(l.t); ok {
+			offset += n * ir.words(e)
+		} else {
+			offset++
+		}
+	}
+	return values
+}
+
+// generateIndex lowers x[i] to the leaves of the element, or the slice
+// expression x[lo..hi] to the leaves of a slice.
+func (ir *IR) generateIndex(expr *syntax.IndexExpr) []Inst {
+	ptr, length, capacity, elem := ir.sequence(expr.X)
+	if elem == nil {
+		ir.errors = append(ir.errors, NewUnexpectedExpr(expr.Pos()))
+		return nil
+	}
+
+	if r := expr.Range(); r != nil {
+		if isArray(ir.exprType(expr.X)) && ir.entry != nil {
+			ir.entry.Sliced = true
+		}
+		lo := ir.hold(ir.generateExpr(r.Low))
+		hi := ir.hold(ir.generateExpr(r.High))
+		ptr, capacity = ir.once(ptr), ir.once(capacity)
+		ir.AddInstruction(NewSliceCheckInst(lo, hi, capacity, expr.Lbrack))
+
+		u64 := ir.GetObject(nil, "u64")
+		stride := NewBasicLitInst(strconv.FormatInt(ir.words(elem)*8, 10), u64)
+		return []Inst{
+			NewAddInst(ptr, NewMulInst(lo, stride, u64), u64),
+			NewSubInst(hi, lo, ir.i32()),
+			NewSubInst(capacity, lo, ir.i32()),
+		}
+	}
+
+	index := ir.generateExpr(expr.Index)
+	if len(ir.leaves(elem)) > 1 {
+		// Every leaf is loaded separately.
+		ptr, index, length = ir.once(ptr), ir.once(index), ir.once(length)
+	}
+	return ir.elements(ptr, index, length, elem, expr.Lbrack)
+}
+
+// generateArrayLit lowers an array or slice literal of type t to its
+// leaves. The elements are stored into new zero storage one by one.
+func (ir *IR) generateArrayLit(lit *syntax.CompositeLit, t *syntax.Object) []Inst {
+	n, elem, ok := ir.arrayOf(t)
+	if !ok {
+		elem = ir.sliceOf(t)
+		n = int64(len(lit.Elts))
+	}
+
+	ptr := ir.hold(NewArrayInst(n*ir.words(elem), nil))
+	ir.unshared[ptr.(*IdentInst).Name] = true
+	length := ir.intLit(n)
+
+	for i, elt := range lit.Elts {
+		lefts := ir.elements(ptr, ir.intLit(int64(i)), length, elem, elt.Pos())
+		rights := ir.generateValues(elt)
+		if len(lefts) != len(rights) {
+			ir.errors = append(ir.errors, NewUnexpectedExpr(elt.Pos()))
+			return nil
+		}
+		for j := range lefts {
+			ir.AddInstruction(ir.store(lefts[j], rights[j]))
+		}
+	}
+
+	if ok {
+		return []Inst{ptr}
+	}
+	return []Inst{ptr, length, length}
+}
+
+// generateAppend lowers append(s, x...) on a slice of type t. The slice is
+// copied into a hidden local, grown to fit the new elements, which are
+// stored after its last, and then lengthened.
+func (ir *IR) generateAppend(expr *syntax.CallExpr, t *syntax.Object) []Inst {
+	elem := ir.sliceOf(t)
+	if elem == nil || len(expr.Args) == 0 {
+		ir.errors = append(ir.errors, NewUnexpectedExpr(expr.Pos()))
+		return nil
+	}
+
+	ir.temps++
+	tmp := syntax.NewObject(syntax.ObjKindVar, fmt.Sprintf("append.%d", ir.temps), nil)
+	tmp.Type = t.Name
+	s := ir.allocStruct(tmp.Name, tmp, t, ir.generateValues(expr.Args[0]))
+	if len(s) != 3 {
+		return nil
+	}
+
+	args := expr.Args[1:]
+	if len(args) == 0 {
+		return s
+	}
+
+	ptr, length, capacity := s[0].(*IdentInst), s[1].(*IdentInst), s[2].(*IdentInst)
+	ir.AddInstruction(NewGrowInst(ptr, length, capacity, len(args), ir.words(elem)))
+
+	for i, arg := range args {
+		index := NewAddInst(length, ir.intLit(int64(i)), ir.i32())
+		lefts := ir.elements(ptr, index, capacity, elem, arg.Pos())
+		rights := ir.generateValues(arg)
+		if len(lefts) != len(rights) {
+			ir.errors = append(ir.errors, NewUnexpectedExpr(arg.Pos()))
+			return nil
+		}
+		for j := range lefts {
+			ir.AddInstruction(ir.store(lefts[j], rights[j]))
+		}
+	}
+
+	ir.AddInstruction(NewAssignInst(length, NewAddInst(length, ir.intLit(int64(len(args))), ir.i32())))
+	return s
+}
+
+// generateLen lowers len(x) or cap(x) of an array or slice x of type t.
+// The length of an array is known, but x is still evaluated for its
+// effects.
+func (ir *IR) generateLen(name string, x syntax.Expression, t *syntax.Object) Inst {
+	if n, _, ok := ir.arrayOf(t); ok {
+		if _, ok := x.(*syntax.Identifier); !ok {
+			ir.AddInstruction(ir.generateExpr(x))
+		}
+		return ir.intLit(n)
+	}
+
+	values := ir.generateValues(x)
+	if len(values) != 3 {
+		return nil
+	}
+	if name == "cap" {
+		return values[2]
+	}
+	return values[1]
+}
+
+// generateForEach lowers `for x <- s` and `for i, x <- s` over an array or
+// slice s into a counting loop over a hidden index. s is evaluated once,
+// before the first iteration: an array is copied and the leaves of a slice
+// held. The variables are declared afresh in every iteration.
+func (ir *IR) generateForEach(stmt *syntax.ForRangeStmt) {
+	outer := ir.GetBlock()
+	init := ir.newLabeledBlock("for.init", outer)
+	cond := ir.newLabeledBlock("for.cond", init)
+	body := ir.newLabeledBlock("for.body", cond)
+	post := ir.newLabeledBlock("for.post", cond)
+	end := ir.newLabeledBlock("for.end", outer)
+
+	ir.jump(init)
+
+	var ptr, length Inst
+	t := ir.exprType(stmt.X)
+	elem := ir.sliceOf(t)
+	if n, e, ok := ir.arrayOf(t); ok {
+		ptr = ir.hold(ir.own(ir.generateExpr(stmt.X), t))
+		length = ir.intLit(n)
+		elem = e
+	} else if elem != nil {
+		values := ir.generateValues(stmt.X)
+		if len(values) != 3 {
+			return
+		}
+		ptr, length = ir.hold(values[0]), ir.hold(values[1])
+	} else {
+		ir.errors = append(ir.errors, NewUnexpectedExpr(stmt.X.Pos()))
+		return
+	}
+
+	ir.temps++
+	index := syntax.NewObject(syntax.ObjKindVar, fmt.Sprintf("%s.index.%d", stmt.Key.Name, ir.temps), stmt)
+	index.Type = "i32"
+	ir.SetObject(index.Name, index)
+	ir.AddInstruction(NewAllocInst(index.Name, ir.intLit(0), ir.i32()))
+
+	ir.jump(cond)
+	ir.AddInstruction(NewBranchInst(NewLtInst(NewIdentInst(index.Name), length, ir.i32()), body, end))
+
+	ir.SetBlock(body)
+	values := ir.elements(ptr, NewIdentInst(index.Name), length, elem, stmt.X.Pos())
+	if stmt.Value != nil {
+		ir.allocStruct(stmt.Key.Name, stmt.Key.Obj, ir.i32(), []Inst{NewIdentInst(index.Name)})
+		ir.allocStruct(stmt.Value.Name, stmt.Value.Obj, elem, values)
+	} else {
+		ir.allocStruct(stmt.Key.Name, stmt.Key.Obj, elem, values)
+	}
+	ir.generateLoopBody(stmt.Body, end, post)
+	ir.jump(post)
+
+	ir.AddInstruction(NewAssignInst(NewIdentInst(index.Name), NewAddInst(NewIdentInst(index.Name), ir.intLit(1), ir.i32())))
+	ir.jump(cond)
+
+	ir.SetBlock(end)
+}
@@ -3,27 +3,15 @@ package intermediate
 import (
 	"fmt"
 
+	"github.com/danecwalker/hippo/internal/diag"
 	"github.com/danecwalker/hippo/internal/syntax"
 )
 
-type Error struct {
-	Pos syntax.Position
-	Msg string
-}
+// Error is a problem found while lowering a program to IR.
+type Error = diag.Diagnostic
 
 func NewError(pos *syntax.Position, msg string) *Error {
-	return &Error{
-		Pos: *pos,
-		Msg: msg,
-	}
-}
-
-func (e *Error) Error() string {
-	if e.Pos.Filename != "" {
-		return fmt.Sprintf("%s:%d:%d:\n\t\033[31merror:\033[0m %s", e.Pos.Filename, e.Pos.Line, e.Pos.Column, e.Msg)
-	}
-
-	return fmt.Sprintf("\033[31merror:\033[0m %s", e.Msg)
+	return diag.NewError(diag.CodeLowering, pos, msg)
 }
 
 func NewUndefinedObjectError(pos *syntax.Position, name string) *Error {
@@ -32,7 +20,7 @@ func NewUndefinedObjectError(pos *syntax.Position, name string) *Error {
 }
 
 func NewDisallowedTopLevelStatementError(pos *syntax.Position) *Error {
-	return NewError(pos, "only variable and function declarations are allowed at the top level")
+	return NewError(pos, "only type, variable and function declarations are allowed at the top level")
 }
 
 func NewUnexpectedStmt(pos *syntax.Position) *Error {
@@ -46,3 +34,7 @@ func NewUnexpectedExpr(pos *syntax.Position) *Error {
 func NewUnexpectedBasicLit(pos *syntax.Position) *Error {
 	return NewError(pos, "unexpected basic literal")
 }
+
+func NewNotCallableError(pos *syntax.Position, name string) *Error {
+	return NewError(pos, fmt.Sprintf("cannot call non-function %s", name)).WithCode(diag.CodeNotCallable)
+}
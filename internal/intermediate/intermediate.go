@@ -3,15 +3,49 @@ package intermediate
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"os"
+	"sort"
 	"strings"
 
+	"github.com/danecwalker/hippo/internal/parse"
 	"github.com/danecwalker/hippo/internal/syntax"
 )
 
 type IR struct {
 	Blocks []*Block
 	errors []*Error
+
+	cur   *Block
+	fn    *syntax.FuncStmt
+	entry *Block
+	temps int
+
+	// results holds the hidden parameters the function being lowered
+	// copies each array result into, nil for the other leaves of its
+	// results.
+	results []*syntax.Object
+
+	// types holds the array and slice types, which are named by their
+	// spelling rather than declared.
+	types map[string]*syntax.Object
+
+	// unshared holds the hidden locals that hold an array no variable
+	// refers to.
+	unshared map[string]bool
+
+	// loops holds the targets of break and continue in the loops around
+	// the statement being lowered, innermost last. label is the label of
+	// the loop about to be lowered.
+	loops []*loop
+	label string
+}
+
+// loop is where break and continue statements inside a loop jump to.
+type loop struct {
+	label      string
+	breakTo    *Block
+	continueTo *Block
 }
 
 type Inst interface {
@@ -19,29 +53,79 @@ type Inst interface {
 	pretty(*bytes.Buffer, int)
 }
 
+// Block is a basic block. Every block of a function ends in a jump, branch
+// or return, and Succs lists the blocks control may flow to from it. Parent
+// links the blocks into the lexical scopes used to resolve names.
 type Block struct {
+	Index   int
+	Label   string
 	Objects map[string]*syntax.Object
 	Insts   []Inst
 	Parent  *Block
+	Succs   []*Block
+
+	// Func is the function the block belongs to, nil for the global block.
+	// The first block of a function in IR.Blocks is its entry.
+	Func *syntax.FuncStmt
+
+	// Params lists the variables holding the arguments of Func in the
+	// order they are passed, with a struct parameter replaced by its
+	// leaves and followed by the storage for each array result. It is only
+	// set on the entry block.
+	Params []*syntax.Object
+
+	// Sliced is set on the entry block of a function that slices an
+	// array, whose storage may then be used after the function returns.
+	Sliced bool
 }
 
 func NewIR() *IR {
 	return &IR{
-		Blocks: []*Block{},
+		Blocks:   []*Block{},
+		types:    map[string]*syntax.Object{},
+		unshared: map[string]bool{},
 	}
 }
 
 func (ir *IR) NewBlock(parent *Block) *Block {
 	block := &Block{
+		Index:   len(ir.Blocks),
 		Objects: map[string]*syntax.Object{},
 		Parent:  parent,
+		Func:    ir.fn,
 	}
 	ir.Blocks = append(ir.Blocks, block)
 	return block
 }
 
+func (ir *IR) newLabeledBlock(label string, parent *Block) *Block {
+	block := ir.NewBlock(parent)
+	block.Label = label
+	return block
+}
+
+// GetBlock returns the block instructions are currently added to.
 func (ir *IR) GetBlock() *Block {
-	return ir.Blocks[len(ir.Blocks)-1]
+	return ir.cur
+}
+
+func (ir *IR) SetBlock(b *Block) {
+	ir.cur = b
+}
+
+// Terminated reports whether the block already ends in a jump, branch or
+// return.
+func (b *Block) Terminated() bool {
+	if len(b.Insts) == 0 {
+		return false
+	}
+
+	switch b.Insts[len(b.Insts)-1].(type) {
+	case *JumpInst, *BranchInst, *ReturnInst:
+		return true
+	default:
+		return false
+	}
 }
 
 func (ir *IR) GetObject(pos *syntax.Position, name string) *syntax.Object {
@@ -52,53 +136,113 @@ func (ir *IR) GetObject(pos *syntax.Position, name string) *syntax.Object {
 		}
 		b = b.Parent
 	}
+	if obj := ir.internType(name); obj != nil {
+		return obj
+	}
 	NewUndefinedObjectError(pos, name)
 	return nil
 }
 
 func (ir *IR) SetObject(name string, obj *syntax.Object) {
+	if ir.fn == nil && len(ir.Blocks) > 0 {
+		// Global initialisers may span several blocks, but every global
+		// must stay visible to the functions declared after it.
+		ir.Blocks[0].Objects[name] = obj
+		return
+	}
 	ir.GetBlock().Objects[name] = obj
 }
 
 func (ir *IR) AddInstruction(inst Inst) {
-	ir.GetBlock().Insts = append(ir.GetBlock().Insts, inst)
+	b := ir.GetBlock()
+	if b.Func != nil && b.Terminated() {
+		// Code following a terminator is unreachable, but it still needs a
+		// block of its own.
+		b = ir.newLabeledBlock("unreachable", b)
+		ir.SetBlock(b)
+	}
+
+	b.Insts = append(b.Insts, inst)
+
+	switch inst := inst.(type) {
+	case *JumpInst:
+		b.Succs = append(b.Succs, inst.Target)
+	case *BranchInst:
+		b.Succs = append(b.Succs, inst.Then, inst.Else)
+	}
+}
+
+// jump ends the current block with a jump to target, unless it has already
+// been terminated, and continues in target.
+func (ir *IR) jump(target *Block) {
+	if !ir.GetBlock().Terminated() {
+		ir.AddInstruction(NewJumpInst(target))
+	}
+	ir.SetBlock(target)
 }
 
 func (ir *IR) Generate(prog *syntax.Program) {
-	ir.NewBlock(nil)
+	ir.SetBlock(ir.NewBlock(nil))
 	ir.addBuiltins()
 
+	// Top level declarations may be used before the point they are
+	// declared, so every global is lowered before any function body, and
+	// every function declared before either.
+	for _, stmt := range prog.Statements {
+		switch stmt := stmt.(type) {
+		case *syntax.TypeStmt:
+			ir.SetObject(stmt.Name.Name, stmt.Name.Obj)
+		case *syntax.FuncStmt:
+			ir.SetObject(stmt.Name.Name, stmt.Name.Obj)
+		}
+	}
+
 	for _, stmt := range prog.Statements {
 		switch stmt := stmt.(type) {
 		case *syntax.VarStatement:
 			ir.generateVar(stmt)
-		case *syntax.FuncStmt:
-			ir.generateFunc(stmt)
+		case *syntax.FuncStmt, *syntax.TypeStmt:
 		default:
 			ir.errors = append(ir.errors, NewDisallowedTopLevelStatementError(stmt.Pos()))
 		}
 	}
-	ir.Pretty()
 
-	if len(ir.errors) > 0 {
-		for _, err := range ir.errors {
-			fmt.Fprintln(os.Stderr, err)
+	for _, stmt := range prog.Statements {
+		if fn, ok := stmt.(*syntax.FuncStmt); ok {
+			ir.generateFunc(fn)
 		}
-		os.Exit(1)
 	}
+}
 
+// Errors returns the problems found while generating the IR.
+func (ir *IR) Errors() []*Error {
+	return ir.errors
 }
 
 func (ir *IR) addBuiltins() {
-	ir.SetObject("i32", &syntax.Object{
-		Name: "i32",
-		Kind: syntax.ObjKindType,
-		Type: "i32",
-	})
+	for name, obj := range parse.Universe.Objects {
+		ir.SetObject(name, obj)
+	}
 }
 
 func (ir *IR) generateVar(stmt *syntax.VarStatement) {
+	if call := ir.multiValueCall(stmt.Values); call != nil {
+		ir.generateVarCall(stmt, call)
+		return
+	}
+
 	for i, name := range stmt.Names {
+		if t := ir.varType(name.Obj); !basic(t) {
+			var values []Inst
+			if i < len(stmt.Values) {
+				values = ir.generateValues(stmt.Values[i])
+			} else {
+				values = ir.zeroValues(t)
+			}
+			ir.allocStruct(name.Name, name.Obj, t, values)
+			continue
+		}
+
 		ir.SetObject(name.Name, name.Obj)
 		n := name.Name
 
@@ -109,24 +253,71 @@ func (ir *IR) generateVar(stmt *syntax.VarStatement) {
 			} else {
 				t = ir.GetObject(stmt.Type.NamePos, stmt.Type.Name)
 				if t == nil {
-					fmt.Fprintln(os.Stderr, NewError(stmt.Type.NamePos, "Undefined type: "+stmt.Type.Name))
+					ir.errors = append(ir.errors, NewError(stmt.Type.NamePos, "undefined type: "+stmt.Type.Name))
 				}
 			}
 		}
 
 		var v Inst
-		if stmt.Values[i] != nil {
+		if i < len(stmt.Values) && stmt.Values[i] != nil {
 			v = ir.generateExpr(stmt.Values[i])
 		} else {
 			v = ir.NewZeroInst(t)
 		}
 
-		t = InferType(v)
+		if it := InferType(v); it != nil {
+			t = it
+		} else if t == nil && name.Obj != nil && name.Obj.Type != "" {
+			t = ir.GetObject(name.Pos(), name.Obj.Type)
+		}
 
 		ir.AddInstruction(NewAllocInst(n, v, t))
 	}
 }
 
+// multiValueCall returns the call when values is a single call producing more
+// than one result.
+func (ir *IR) multiValueCall(values []syntax.Expression) *syntax.CallExpr {
+	if len(values) != 1 {
+		return nil
+	}
+
+	call, ok := values[0].(*syntax.CallExpr)
+	if !ok {
+		return nil
+	}
+
+	if fn := ir.callee(call); fn == nil || len(fn.Type.Results) < 2 {
+		return nil
+	}
+
+	return call
+}
+
+// generateVarCall declares every name of stmt with its zero value and then
+// assigns the results of call to them.
+func (ir *IR) generateVarCall(stmt *syntax.VarStatement, call *syntax.CallExpr) {
+	var lefts []Inst
+	for _, name := range stmt.Names {
+		t := ir.varType(name.Obj)
+		if t == nil {
+			ir.errors = append(ir.errors, NewError(name.Pos(), "cannot infer type of "+name.Name))
+			return
+		}
+
+		if !basic(t) {
+			lefts = append(lefts, ir.allocStruct(name.Name, name.Obj, t, ir.zeroValues(t))...)
+			continue
+		}
+
+		ir.SetObject(name.Name, name.Obj)
+		ir.AddInstruction(NewAllocInst(name.Name, ir.NewZeroInst(t), t))
+		lefts = append(lefts, NewIdentInst(name.Name))
+	}
+
+	ir.AddInstruction(ir.assign(lefts, []Inst{ir.generateExpr(call)}))
+}
+
 func InferType(inst Inst) *syntax.Object {
 	switch inst := inst.(type) {
 	case *BasicLitInst:
@@ -136,6 +327,42 @@ func InferType(inst Inst) *syntax.Object {
 	}
 }
 
+// typeOf returns the type object of the value inst produces, or nil if it
+// cannot be determined.
+func (ir *IR) typeOf(inst Inst) *syntax.Object {
+	switch inst := inst.(type) {
+	case *BasicLitInst:
+		return inst.Type
+	case *IdentInst:
+		if obj := ir.GetObject(nil, inst.Name); obj != nil && obj.Type != "" {
+			return ir.GetObject(nil, obj.Type)
+		}
+	case *BinaryInst:
+		switch inst.Op {
+		case "<", ">", "<=", ">=", "==", "!=":
+			return ir.GetObject(nil, "bool")
+		}
+		return inst.Type
+	case *UnaryInst:
+		return inst.Type
+	case *ConvertInst:
+		return inst.Type
+	case *AssignInst:
+		return ir.typeOf(inst.Left)
+	case *IndexInst:
+		return inst.Type
+	case *CallInst:
+		if results := inst.Func.Type.Results; len(results) == 1 {
+			return ir.typeObject(results[0])
+		}
+	case *BuiltinInst:
+		if inst.Name == "len" || inst.Name == "cap" {
+			return ir.GetObject(nil, "i32")
+		}
+	}
+	return nil
+}
+
 func (ir *IR) generateExpr(expr syntax.Expression) Inst {
 	switch expr := expr.(type) {
 	case *syntax.Identifier:
@@ -144,24 +371,132 @@ func (ir *IR) generateExpr(expr syntax.Expression) Inst {
 		return ir.generateBasicLit(expr)
 	case *syntax.BinaryExpr:
 		return ir.generateBinaryExpr(expr)
+	case *syntax.UnaryExpr:
+		return ir.generateUnaryExpr(expr)
+	case *syntax.ParenExpr:
+		return ir.generateExpr(expr.X)
 	case *syntax.AssignmentExpr:
 		return ir.generateAssignmentExpr(expr)
+	case *syntax.CallExpr:
+		return ir.generateCallExpr(expr)
+	case *syntax.SelectorExpr, *syntax.IndexExpr, *syntax.CompositeLit:
+		if values := ir.generateValues(expr); len(values) == 1 {
+			return values[0]
+		}
+		ir.errors = append(ir.errors, NewUnexpectedExpr(expr.Pos()))
+		return nil
 	default:
 		ir.errors = append(ir.errors, NewUnexpectedExpr(expr.Pos()))
 		return nil
 	}
 }
 
+func (ir *IR) callee(expr *syntax.CallExpr) *syntax.FuncStmt {
+	obj := expr.Func.Obj
+	if obj == nil {
+		obj = ir.GetObject(expr.Func.Pos(), expr.Func.Name)
+	}
+	if obj == nil || obj.Kind != syntax.ObjKindFunc {
+		return nil
+	}
+
+	fn, _ := obj.Decl.(*syntax.FuncStmt)
+	return fn
+}
+
+func (ir *IR) generateCallExpr(expr *syntax.CallExpr) Inst {
+	if obj := expr.Func.Obj; obj != nil && obj.Kind == syntax.ObjKindType && len(expr.Args) == 1 {
+		x := ir.generateExpr(expr.Args[0])
+		return NewConvertInst(x, ir.typeOf(x), obj)
+	}
+
+	if obj := expr.Func.Obj; obj != nil && obj.Kind == syntax.ObjKindBuiltin {
+		if obj.Name == "len" || obj.Name == "cap" {
+			if t := ir.exprType(expr.Args[0]); isArray(t) || isSlice(t) {
+				return ir.generateLen(obj.Name, expr.Args[0], t)
+			}
+		}
+
+		args := make([]Inst, len(expr.Args))
+		types := make([]*syntax.Object, len(expr.Args))
+		for i, arg := range expr.Args {
+			args[i] = ir.generateExpr(arg)
+			types[i] = ir.typeOf(args[i])
+		}
+		return NewBuiltinInst(obj.Name, args, types)
+	}
+
+	fn := ir.callee(expr)
+	if fn == nil {
+		ir.errors = append(ir.errors, NewNotCallableError(expr.Func.Pos(), expr.Func.Name))
+		return nil
+	}
+
+	var args []Inst
+	for _, arg := range expr.Args {
+		args = append(args, ir.generateValues(arg)...)
+	}
+	for _, l := range ir.resultLeaves(fn) {
+		if isArray(l.t) {
+			args = append(args, NewArrayInst(ir.words(l.t), nil))
+		}
+	}
+
+	return NewCallInst(fn, args, ir.resultCount(fn))
+}
+
 func (ir *IR) generateAssignmentExpr(expr *syntax.AssignmentExpr) Inst {
+	if !basic(ir.exprType(expr.Lhs)) {
+		// Every leaf is assigned, as in a tuple assignment.
+		return ir.assign(ir.generateValues(expr.Lhs), ir.generateValues(expr.Rhs))
+	}
+
 	l := ir.generateExpr(expr.Lhs)
+	if expr.Op.Type == syntax.TokenAssign {
+		return NewAssignInst(l, ir.generateExpr(expr.Rhs))
+	}
+
+	// x op= y is lowered to x = x op y. The left operand is a variable or
+	// an element whose operands are held, so using it twice evaluates
+	// nothing twice.
+	l = ir.onceIndex(l)
 	r := ir.generateExpr(expr.Rhs)
+	if op, ok := syntax.CompoundOp(expr.Op.Type); ok {
+		if bin := binaryInst(op, expr.Op.Position, l, r, ir.typeOf(l)); bin != nil {
+			return NewAssignInst(l, bin)
+		}
+	}
 
-	switch expr.Op.Type {
-	case syntax.TokenAssign:
-		return NewAssignInst(l, r)
-	default:
-		ir.errors = append(ir.errors, NewUnexpectedExpr(expr.Pos()))
-		return nil
+	ir.errors = append(ir.errors, NewUnexpectedExpr(expr.Pos()))
+	return nil
+}
+
+// generateTupleAssign evaluates every value on the right before assigning
+// any of them, so that a, b = b, a swaps a and b.
+func (ir *IR) generateTupleAssign(stmt *syntax.TupleAssignStmt) {
+	var lefts []Inst
+	for _, lhs := range stmt.Lhs {
+		lefts = append(lefts, ir.generateValues(lhs)...)
+	}
+
+	var rights []Inst
+	for _, rhs := range stmt.Rhs {
+		rights = append(rights, ir.generateValues(rhs)...)
+	}
+
+	ir.AddInstruction(ir.assign(lefts, rights))
+}
+
+// generateIncDec lowers x++ to x = x + 1 and x-- to x = x - 1.
+func (ir *IR) generateIncDec(stmt *syntax.IncDecStmt) {
+	x := ir.onceIndex(ir.generateExpr(stmt.X))
+	t := ir.typeOf(x)
+	one := NewBasicLitInst("1", t)
+
+	if stmt.Tok == syntax.TokenInc {
+		ir.AddInstruction(NewAssignInst(x, NewAddInst(x, one, t)))
+	} else {
+		ir.AddInstruction(NewAssignInst(x, NewSubInst(x, one, t)))
 	}
 }
 
@@ -171,6 +506,15 @@ func (ir *IR) generateIdent(ident *syntax.Identifier) Inst {
 		ir.errors = append(ir.errors, NewUndefinedObjectError(ident.Pos(), ident.Name))
 		return nil
 	}
+
+	if obj.Kind == syntax.ObjKindConst && parse.IsPredeclared(obj) {
+		// true and false have no storage; they are literals of their type.
+		return NewBasicLitInst(obj.Name, ir.GetObject(ident.Pos(), obj.Type))
+	}
+	if ident.Const != nil {
+		return ir.generateBasicLit(ident.Const)
+	}
+
 	return NewIdentInst(obj.Name)
 }
 
@@ -179,33 +523,170 @@ func (ir *IR) generateBasicLit(lit *syntax.BasicLit) Inst {
 }
 
 func (ir *IR) generateBinaryExpr(expr *syntax.BinaryExpr) Inst {
+	switch expr.Op.Type {
+	case syntax.TokenLAnd, syntax.TokenLOr:
+		return ir.generateLogical(expr)
+	}
+
 	l := ir.generateExpr(expr.X)
 	r := ir.generateExpr(expr.Y)
+	t := ir.typeOf(l)
 
-	switch expr.Op.Type {
+	if inst := binaryInst(expr.Op.Type, expr.Op.Position, l, r, t); inst != nil {
+		return inst
+	}
+	ir.errors = append(ir.errors, NewUnexpectedExpr(expr.Pos()))
+	return nil
+}
+
+// binaryInst returns the instruction applying the binary operator op at pos
+// to l and r, or nil if op is not one.
+func binaryInst(op syntax.TokenType, pos *syntax.Position, l, r Inst, t *syntax.Object) Inst {
+	switch op {
 	case syntax.TokenPlus:
-		return NewAddInst(l, r)
+		return NewAddInst(l, r, t)
 	case syntax.TokenMinus:
-		return NewSubInst(l, r)
+		return NewSubInst(l, r, t)
 	case syntax.TokenStar:
-		return NewMulInst(l, r)
+		return NewMulInst(l, r, t)
 	case syntax.TokenSlash:
-		return NewDivInst(l, r)
+		return NewDivInst(pos, l, r, t)
+	case syntax.TokenPercent:
+		return NewRemInst(pos, l, r, t)
+	case syntax.TokenAnd:
+		return NewAndInst(l, r, t)
+	case syntax.TokenOr:
+		return NewOrInst(l, r, t)
+	case syntax.TokenXor:
+		return NewXorInst(l, r, t)
+	case syntax.TokenAndNot:
+		return NewAndNotInst(l, r, t)
+	case syntax.TokenShl:
+		return NewShlInst(l, r, t)
+	case syntax.TokenShr:
+		return NewShrInst(l, r, t)
+	case syntax.TokenLt:
+		return NewLtInst(l, r, t)
+	case syntax.TokenGt:
+		return NewGtInst(l, r, t)
+	case syntax.TokenLtEq:
+		return NewLeInst(l, r, t)
+	case syntax.TokenGtEq:
+		return NewGeInst(l, r, t)
+	case syntax.TokenEq:
+		return NewEqInst(l, r, t)
+	case syntax.TokenNotEq:
+		return NewNeInst(l, r, t)
+	default:
+		return nil
+	}
+}
+
+func (ir *IR) generateUnaryExpr(expr *syntax.UnaryExpr) Inst {
+	x := ir.generateExpr(expr.X)
+	t := ir.typeOf(x)
+
+	switch expr.Op.Type {
+	case syntax.TokenNot:
+		return NewNotInst(x, t)
+	case syntax.TokenMinus:
+		return NewNegInst(x, t)
+	case syntax.TokenXor:
+		return NewComplementInst(x, t)
 	default:
 		ir.errors = append(ir.errors, NewUnexpectedExpr(expr.Pos()))
 		return nil
 	}
 }
 
+// generateLogical lowers a && or || used as a value. The left operand is
+// stored in a hidden local which the right operand overwrites only when it
+// has to be evaluated:
+//
+//	t = x; if t { t = y }    // x && y
+//	t = x; if !t { t = y }   // x || y
+func (ir *IR) generateLogical(expr *syntax.BinaryExpr) Inst {
+	name := "and"
+	if expr.Op.Type == syntax.TokenLOr {
+		name = "or"
+	}
+
+	x := ir.generateExpr(expr.X)
+
+	outer := ir.GetBlock()
+	rhs := ir.newLabeledBlock(name+".rhs", outer)
+	end := ir.newLabeledBlock(name+".end", outer)
+
+	ir.temps++
+	tmp := syntax.NewObject(syntax.ObjKindVar, fmt.Sprintf("%s.%d", name, ir.temps), expr)
+	tmp.Type = "bool"
+	ir.SetObject(tmp.Name, tmp)
+	ir.AddInstruction(NewAllocInst(tmp.Name, x, ir.GetObject(nil, "bool")))
+	if expr.Op.Type == syntax.TokenLAnd {
+		ir.AddInstruction(NewBranchInst(NewIdentInst(tmp.Name), rhs, end))
+	} else {
+		ir.AddInstruction(NewBranchInst(NewIdentInst(tmp.Name), end, rhs))
+	}
+
+	ir.SetBlock(rhs)
+	ir.AddInstruction(NewAssignInst(NewIdentInst(tmp.Name), ir.generateExpr(expr.Y)))
+	ir.jump(end)
+
+	return NewIdentInst(tmp.Name)
+}
+
 func (ir *IR) generateFunc(stmt *syntax.FuncStmt) {
-	ir.NewBlock(ir.GetBlock())
+	if ir.fn != nil {
+		// A nested function is only visible in the block declaring it.
+		ir.SetObject(stmt.Name.Name, stmt.Name.Obj)
+	}
+
+	saved, savedFn, savedEntry, savedResults, savedLoops := ir.GetBlock(), ir.fn, ir.entry, ir.results, ir.loops
+	ir.fn = stmt
+	ir.loops = nil
+	ir.SetBlock(ir.newLabeledBlock(stmt.Name.Name, ir.Blocks[0]))
+	defer func() {
+		ir.SetBlock(saved)
+		ir.fn = savedFn
+		ir.entry = savedEntry
+		ir.results = savedResults
+		ir.loops = savedLoops
+	}()
+
+	entry := ir.GetBlock()
+	ir.entry = entry
 	for _, param := range stmt.Type.Params {
 		for _, name := range param.Names {
-			ir.SetObject(name.Name, name.Obj)
+			entry.Params = append(entry.Params, ir.declareLeaves(name.Name, name.Obj, ir.varType(name.Obj))...)
+		}
+	}
+
+	// Arrays are passed by pointer, so the callee makes its own copy.
+	for _, param := range entry.Params {
+		if t := ir.varType(param); isArray(t) {
+			ir.AddInstruction(NewAssignInst(NewIdentInst(param.Name), NewArrayInst(ir.words(t), NewIdentInst(param.Name))))
+		}
+	}
+
+	// The caller passes storage for each array result, so that returning
+	// an array copies it straight into the caller's frame.
+	ir.results = nil
+	for i, l := range ir.resultLeaves(stmt) {
+		var result *syntax.Object
+		if isArray(l.t) {
+			result = syntax.NewObject(syntax.ObjKindVar, fmt.Sprintf("result.%d", i), nil)
+			result.Type = l.t.Name
+			ir.SetObject(result.Name, result)
+			entry.Params = append(entry.Params, result)
 		}
+		ir.results = append(ir.results, result)
 	}
 
 	ir.generateBlockStmt(stmt.Body)
+
+	if !ir.GetBlock().Terminated() {
+		ir.AddInstruction(NewReturnInst(nil))
+	}
 }
 
 func (ir *IR) generateStmt(stmt syntax.Statement) {
@@ -214,13 +695,35 @@ func (ir *IR) generateStmt(stmt syntax.Statement) {
 		ir.generateVar(stmt)
 	case *syntax.FuncStmt:
 		ir.generateFunc(stmt)
+	case *syntax.TypeStmt:
+		ir.SetObject(stmt.Name.Name, stmt.Name.Obj)
 	case *syntax.ReturnStmt:
 		ir.generateReturn(stmt)
+	case *syntax.IfStmt:
+		ir.generateIf(stmt)
 	case *syntax.ForRangeStmt:
 		ir.generateForRange(stmt)
+	case *syntax.WhileStmt:
+		ir.generateWhile(stmt)
+	case *syntax.ForLoopStmt:
+		ir.generateForLoop(stmt)
+	case *syntax.LabeledStmt:
+		ir.label = stmt.Label.Name
+		ir.generateStmt(stmt.Stmt)
+	case *syntax.BranchStmt:
+		ir.generateBranch(stmt)
 	case *syntax.BlockStmt:
-		ir.generateBlockStmt(stmt)
+		ir.generateScope(stmt)
+	case *syntax.TupleAssignStmt:
+		ir.generateTupleAssign(stmt)
+	case *syntax.IncDecStmt:
+		ir.generateIncDec(stmt)
 	case *syntax.ExpressionStmt:
+		if _, ok := stmt.X.(*syntax.AssignmentExpr); !ok && scalarised(ir.exprType(stmt.X)) {
+			// Only a call has an effect.
+			ir.generateValues(stmt.X)
+			return
+		}
 		ir.AddInstruction(ir.generateExpr(stmt.X))
 	default:
 		ir.errors = append(ir.errors, NewUnexpectedStmt(stmt.Pos()))
@@ -233,52 +736,304 @@ func (ir *IR) generateBlockStmt(stmt *syntax.BlockStmt) {
 	}
 }
 
+// generateScope lowers a nested block into blocks of its own so that the
+// names it declares do not leak into the statements that follow it.
+func (ir *IR) generateScope(stmt *syntax.BlockStmt) {
+	outer := ir.GetBlock()
+	ir.jump(ir.newLabeledBlock("block", outer))
+	ir.generateBlockStmt(stmt)
+	ir.jump(ir.newLabeledBlock("block.end", outer))
+}
+
+// generateCond branches to then or else_ depending on cond. && and || are
+// lowered into a chain of branches so the right operand is only evaluated
+// when needed, and ! swaps the targets.
+func (ir *IR) generateCond(cond syntax.Expression, then *Block, else_ *Block) {
+	switch cond := cond.(type) {
+	case *syntax.BinaryExpr:
+		switch cond.Op.Type {
+		case syntax.TokenLAnd:
+			rhs := ir.newLabeledBlock("and.rhs", ir.GetBlock())
+			ir.generateCond(cond.X, rhs, else_)
+			ir.SetBlock(rhs)
+			ir.generateCond(cond.Y, then, else_)
+			return
+		case syntax.TokenLOr:
+			rhs := ir.newLabeledBlock("or.rhs", ir.GetBlock())
+			ir.generateCond(cond.X, then, rhs)
+			ir.SetBlock(rhs)
+			ir.generateCond(cond.Y, then, else_)
+			return
+		}
+	case *syntax.UnaryExpr:
+		if cond.Op.Type == syntax.TokenNot {
+			ir.generateCond(cond.X, else_, then)
+			return
+		}
+	case *syntax.ParenExpr:
+		ir.generateCond(cond.X, then, else_)
+		return
+	}
+
+	ir.AddInstruction(NewBranchInst(ir.generateExpr(cond), then, else_))
+}
+
+func (ir *IR) generateIf(stmt *syntax.IfStmt) {
+	outer := ir.GetBlock()
+	then := ir.newLabeledBlock("if.then", outer)
+	end := ir.newLabeledBlock("if.end", outer)
+
+	else_ := end
+	if stmt.Alternative != nil {
+		else_ = ir.newLabeledBlock("if.else", outer)
+	}
+
+	ir.generateCond(stmt.Cond, then, else_)
+
+	ir.SetBlock(then)
+	ir.generateBlockStmt(stmt.Consequence)
+	ir.jump(end)
+
+	if stmt.Alternative != nil {
+		ir.SetBlock(else_)
+		ir.generateStmt(stmt.Alternative)
+		ir.jump(end)
+	}
+}
+
+func (ir *IR) generateWhile(stmt *syntax.WhileStmt) {
+	outer := ir.GetBlock()
+	cond := ir.newLabeledBlock("for.cond", outer)
+	body := ir.newLabeledBlock("for.body", cond)
+	end := ir.newLabeledBlock("for.end", outer)
+
+	ir.jump(cond)
+	ir.generateCond(stmt.Cond, body, end)
+
+	ir.SetBlock(body)
+	ir.generateLoopBody(stmt.Body, end, cond)
+	ir.jump(cond)
+
+	ir.SetBlock(end)
+}
+
+func (ir *IR) generateForLoop(stmt *syntax.ForLoopStmt) {
+	outer := ir.GetBlock()
+	init := ir.newLabeledBlock("for.init", outer)
+	cond := ir.newLabeledBlock("for.cond", init)
+	body := ir.newLabeledBlock("for.body", cond)
+	post := ir.newLabeledBlock("for.post", cond)
+	end := ir.newLabeledBlock("for.end", outer)
+
+	ir.jump(init)
+	if stmt.Init != nil {
+		ir.generateStmt(stmt.Init)
+	}
+	ir.jump(cond)
+
+	if stmt.Cond != nil {
+		ir.generateCond(stmt.Cond, body, end)
+	} else {
+		ir.AddInstruction(NewJumpInst(body))
+	}
+
+	ir.SetBlock(body)
+	ir.generateLoopBody(stmt.Body, end, post)
+	ir.jump(post)
+
+	if stmt.Post != nil {
+		ir.generateStmt(stmt.Post)
+	}
+	ir.jump(cond)
+
+	ir.SetBlock(end)
+}
+
+// generateForRange lowers `for k <- lo..hi` into a counting loop. The upper
+// bound is evaluated once, before the first iteration, into a hidden local.
 func (ir *IR) generateForRange(stmt *syntax.ForRangeStmt) {
-	ir.generateVar(stmt.Key.Obj.Decl.(*syntax.VarStatement))
-	ir.NewBlock(ir.GetBlock())
+	r, ok := stmt.X.(*syntax.RangeExpr)
+	if !ok {
+		ir.generateForEach(stmt)
+		return
+	}
+
+	outer := ir.GetBlock()
+	init := ir.newLabeledBlock("for.init", outer)
+	cond := ir.newLabeledBlock("for.cond", init)
+	body := ir.newLabeledBlock("for.body", cond)
+	post := ir.newLabeledBlock("for.post", cond)
+	end := ir.newLabeledBlock("for.end", outer)
+
+	kind := stmt.Key.Obj.Type
+	if kind == "" {
+		kind = "i32"
+	}
+	t := ir.GetObject(r.Low.Pos(), kind)
+
+	// The loop counts with a hidden index, declaring the key afresh from it
+	// on every iteration, so assigning to the key does not change how many
+	// times the loop runs.
+	ir.jump(init)
+	ir.temps++
+	index := syntax.NewObject(syntax.ObjKindVar, fmt.Sprintf("%s.index.%d", stmt.Key.Name, ir.temps), stmt)
+	index.Type = kind
+	ir.SetObject(index.Name, index)
+	ir.AddInstruction(NewAllocInst(index.Name, ir.generateExpr(r.Low), t))
+
+	high := syntax.NewObject(syntax.ObjKindVar, stmt.Key.Name+".hi", stmt)
+	high.Type = kind
+	ir.SetObject(high.Name, high)
+	ir.AddInstruction(NewAllocInst(high.Name, ir.generateExpr(r.High), t))
+
+	ir.jump(cond)
+	ir.AddInstruction(NewBranchInst(NewLtInst(NewIdentInst(index.Name), NewIdentInst(high.Name), t), body, end))
+
+	ir.SetBlock(body)
+	ir.allocStruct(stmt.Key.Name, stmt.Key.Obj, t, []Inst{NewIdentInst(index.Name)})
+	ir.generateLoopBody(stmt.Body, end, post)
+	ir.jump(post)
 
-	ir.generateStmt(stmt.Body)
+	ir.AddInstruction(NewAssignInst(NewIdentInst(index.Name), NewAddInst(NewIdentInst(index.Name), NewBasicLitInst("1", t), t)))
+	ir.jump(cond)
 
-	name := syntax.NewIdentifier(nil, stmt.Key.Obj.Name)
-	ir.AddInstruction(ir.generateAssignmentExpr(syntax.NewAssignmentExpr(
-		name,
-		syntax.NewToken(syntax.TokenAssign, "=", nil),
-		syntax.NewBinaryExpr(
-			name,
-			syntax.NewToken(syntax.TokenPlus, "+", nil),
-			syntax.NewBasicLit(nil, "i32", "1"),
-		),
-	)))
+	ir.SetBlock(end)
+}
+
+// generateLoopBody lowers the body of a loop in which break jumps to
+// breakTo and continue to continueTo.
+func (ir *IR) generateLoopBody(body *syntax.BlockStmt, breakTo *Block, continueTo *Block) {
+	ir.loops = append(ir.loops, &loop{
+		label:      ir.label,
+		breakTo:    breakTo,
+		continueTo: continueTo,
+	})
+	ir.label = ""
+	defer func() { ir.loops = ir.loops[:len(ir.loops)-1] }()
+
+	ir.generateBlockStmt(body)
+}
+
+// generateBranch lowers break and continue into a jump out of the loop they
+// refer to. Code after them starts a new, unreachable block.
+func (ir *IR) generateBranch(stmt *syntax.BranchStmt) {
+	for i := len(ir.loops) - 1; i >= 0; i-- {
+		l := ir.loops[i]
+		if stmt.Label != nil && stmt.Label.Name != l.label {
+			continue
+		}
+
+		if stmt.Tok == syntax.TokenBreak {
+			ir.AddInstruction(NewJumpInst(l.breakTo))
+		} else {
+			ir.AddInstruction(NewJumpInst(l.continueTo))
+		}
+		return
+	}
+
+	ir.errors = append(ir.errors, NewUnexpectedStmt(stmt.Pos()))
 }
 
 func (ir *IR) generateReturn(stmt *syntax.ReturnStmt) {
-	var r Inst
-	if stmt.Result != nil {
-		r = ir.generateExpr(stmt.Result)
+	var results []Inst
+	for _, result := range stmt.Results {
+		results = append(results, ir.generateValues(result)...)
 	}
 
-	ir.AddInstruction(NewReturnInst(r))
+	ir.AddInstruction(NewReturnInst(ir.returnArrays(results)))
+}
+
+// returnArrays copies each array among results into the storage the caller
+// passed for it and returns the results with the storage in their place.
+// The other results are held in hidden locals first, so that every result
+// is still evaluated in order.
+func (ir *IR) returnArrays(results []Inst) []Inst {
+	arrays := 0
+	for _, result := range ir.results {
+		if result != nil {
+			arrays++
+		}
+	}
+	if arrays == 0 {
+		return results
+	}
+
+	// A call with the same results is passed the storage to fill.
+	if len(results) == 1 {
+		if call, ok := results[0].(*CallInst); ok && call.Results() == len(ir.results) {
+			args := call.Args[len(call.Args)-arrays:]
+			for _, result := range ir.results {
+				if result != nil {
+					args[0] = NewIdentInst(result.Name)
+					args = args[1:]
+				}
+			}
+			return results
+		}
+	}
+
+	if len(results) != len(ir.results) {
+		return results
+	}
+	for i, result := range ir.results {
+		if result == nil {
+			results[i] = ir.hold(results[i])
+			continue
+		}
+		dst := NewIdentInst(result.Name)
+		ir.AddInstruction(NewCopyInst(dst, results[i], ir.words(ir.typeOf(dst))))
+		results[i] = dst
+	}
+	return results
 }
 
 func (ir *IR) Pretty() {
+	ir.Fprint(os.Stdout)
+}
+
+func (ir *IR) Fprint(out io.Writer) {
 	var w bytes.Buffer
 
-	for i, b := range ir.Blocks {
-		b.pretty(&w, i, 0)
+	for _, b := range ir.Blocks {
+		b.pretty(&w, 0)
 	}
+	w.WriteString("\n")
 
-	fmt.Println(w.String())
+	out.Write(w.Bytes())
 }
 
 func addIndent(w *bytes.Buffer, indent int) {
 	w.WriteString(strings.Repeat("  ", indent))
 }
 
-func (b *Block) pretty(w *bytes.Buffer, count int, indent int) {
-	w.WriteString(fmt.Sprintf("b%d:\n", count))
+func prettyList(w *bytes.Buffer, indent int, insts []Inst) {
+	for i, inst := range insts {
+		if i > 0 {
+			w.WriteString(", ")
+		}
+		inst.pretty(w, indent)
+	}
+}
+
+func (b *Block) pretty(w *bytes.Buffer, indent int) {
+	if b.Label != "" {
+		w.WriteString(fmt.Sprintf("b%d (%s):\n", b.Index, b.Label))
+	} else {
+		w.WriteString(fmt.Sprintf("b%d:\n", b.Index))
+	}
 	addIndent(w, indent+1)
 	w.WriteString("Objects:\n")
-	for _, o := range b.Objects {
+	// Predeclared objects are left out, and the rest sorted so that the
+	// output is the same on every run.
+	var names []string
+	for name, o := range b.Objects {
+		if !parse.IsPredeclared(o) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		o := b.Objects[name]
 		addIndent(w, indent+2)
 		w.WriteString(fmt.Sprintf("<%s> %s\n", o.Name, o.Kind))
 	}
@@ -287,5 +1042,18 @@ func (b *Block) pretty(w *bytes.Buffer, count int, indent int) {
 	for _, inst := range b.Insts {
 		addIndent(w, indent+2)
 		inst.pretty(w, indent)
+		if !bytes.HasSuffix(w.Bytes(), []byte{'\n'}) {
+			// Expressions evaluated for their effects print without a
+			// trailing newline.
+			w.WriteRune('\n')
+		}
+	}
+	if len(b.Succs) > 0 {
+		addIndent(w, indent+1)
+		w.WriteString("Succs:")
+		for _, succ := range b.Succs {
+			w.WriteString(fmt.Sprintf(" b%d", succ.Index))
+		}
+		w.WriteString("\n")
 	}
 }
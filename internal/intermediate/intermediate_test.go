@@ -0,0 +1,73 @@
+package intermediate
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/danecwalker/hippo/internal/parse"
+	"github.com/danecwalker/hippo/internal/types"
+)
+
+// generate parses, checks and lowers src, failing the test on any error.
+func generate(t *testing.T, src string) *IR {
+	t.Helper()
+
+	p := parse.NewParserFromBytes("test.x", []byte(src))
+	prog := p.ParseProgram()
+	if errs := p.Errors(); len(errs) > 0 {
+		t.Fatalf("parse: %v", errs[0])
+	}
+
+	c := types.NewChecker()
+	c.Check(prog)
+	if errs := c.Errors(); len(errs) > 0 {
+		t.Fatalf("check: %v", errs[0])
+	}
+
+	ir := NewIR()
+	ir.Generate(prog)
+	if errs := ir.Errors(); len(errs) > 0 {
+		t.Fatalf("generate: %v", errs[0])
+	}
+	return ir
+}
+
+func TestFprintStable(t *testing.T) {
+	const src = `
+var total = 0
+var count = 0
+
+fn add : a, b i32 -> i32 {
+  var sum = a + b
+  var twice = sum * 2
+  var unused = twice - 1
+  ret twice
+}
+
+fn main -> i32 {
+  var x = 1
+  var y = 2
+  var z = add(x, y)
+  total = z
+  ret total + count
+}
+`
+
+	var first bytes.Buffer
+	generate(t, src).Fprint(&first)
+
+	for i := 0; i < 20; i++ {
+		var w bytes.Buffer
+		generate(t, src).Fprint(&w)
+		if !bytes.Equal(w.Bytes(), first.Bytes()) {
+			t.Fatalf("output changed between runs:\n%s\nthen:\n%s", first.Bytes(), w.Bytes())
+		}
+	}
+
+	for _, name := range []string{"<print>", "<len>", "<i32>", "<true>"} {
+		if strings.Contains(first.String(), name) {
+			t.Errorf("output lists predeclared object %s:\n%s", name, first.Bytes())
+		}
+	}
+}
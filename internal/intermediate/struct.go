@@ -0,0 +1,291 @@
+package intermediate
+
+import (
+	"fmt"
+
+	"github.com/danecwalker/hippo/internal/syntax"
+)
+
+// Struct values are lowered field by field. A variable of struct type
+// becomes one hidden variable for every field of basic type it holds,
+// however deeply nested, named by the path to the field: p.x, or line.a.y
+// for a struct within a struct. A struct value is the list of those fields'
+// values in declaration order, so copying a struct, passing it to a function
+// or returning it moves each field separately and the backend only ever sees
+// single word values.
+
+// leaf is a field of basic type within a struct, reached from the struct by
+// path, or the value itself with an empty path when it is not a struct.
+type leaf struct {
+	path string
+	t    *syntax.Object
+}
+
+// structOf returns the declaration of the struct type t, or nil if t is not
+// a struct type.
+func structOf(t *syntax.Object) *syntax.StructType {
+	if t == nil || t.Kind != syntax.ObjKindType {
+		return nil
+	}
+	if ts, ok := t.Decl.(*syntax.TypeStmt); ok {
+		return ts.Type
+	}
+	return nil
+}
+
+// typeObject returns the type object the type name ident refers to.
+func (ir *IR) typeObject(ident *syntax.Identifier) *syntax.Object {
+	if ident.Obj != nil {
+		return ident.Obj
+	}
+	return ir.GetObject(ident.Pos(), ident.Name)
+}
+
+// varType returns the type object of the variable obj, or nil if it has
+// none.
+func (ir *IR) varType(obj *syntax.Object) *syntax.Object {
+	if obj == nil || obj.Type == "" {
+		return nil
+	}
+	return ir.GetObject(nil, obj.Type)
+}
+
+// leaves lists the fields of basic type a value of type t is made of. A
+// slice is made of a pointer to its elements, its length and its capacity.
+func (ir *IR) leaves(t *syntax.Object) []leaf {
+	if isSlice(t) {
+		return []leaf{
+			{".ptr", ir.GetObject(nil, "u64")},
+			{".len", ir.i32()},
+			{".cap", ir.i32()},
+		}
+	}
+
+	st := structOf(t)
+	if st == nil {
+		return []leaf{{"", t}}
+	}
+
+	var ls []leaf
+	for _, f := range st.Fields {
+		for _, l := range ir.leaves(ir.typeObject(f.Type)) {
+			ls = append(ls, leaf{"." + f.Name.Name + l.path, l.t})
+		}
+	}
+	return ls
+}
+
+// fieldLeaves returns where the leaves of the field called name start among
+// the leaves of the struct type t, and how many there are.
+func (ir *IR) fieldLeaves(t *syntax.Object, name string) (int, int) {
+	start := 0
+	for _, f := range structOf(t).Fields {
+		n := len(ir.leaves(ir.typeObject(f.Type)))
+		if f.Name.Name == name {
+			return start, n
+		}
+		start += n
+	}
+	return start, 0
+}
+
+// resultCount returns the number of values a call to fn produces, counting
+// every leaf of a struct result.
+func (ir *IR) resultCount(fn *syntax.FuncStmt) int {
+	return len(ir.resultLeaves(fn))
+}
+
+// resultLeaves returns the leaves of the results of fn, in order.
+func (ir *IR) resultLeaves(fn *syntax.FuncStmt) []leaf {
+	var ls []leaf
+	for _, result := range fn.Type.Results {
+		ls = append(ls, ir.leaves(ir.typeObject(result))...)
+	}
+	return ls
+}
+
+// exprType returns the type object of the value expr produces, or nil if it
+// cannot be determined without lowering expr. It finds the type of every
+// expression that can produce a struct.
+func (ir *IR) exprType(expr syntax.Expression) *syntax.Object {
+	switch expr := expr.(type) {
+	case *syntax.Identifier:
+		return ir.varType(ir.GetObject(expr.Pos(), expr.Name))
+	case *syntax.ParenExpr:
+		return ir.exprType(expr.X)
+	case *syntax.AssignmentExpr:
+		return ir.exprType(expr.Lhs)
+	case *syntax.SelectorExpr:
+		st := structOf(ir.exprType(expr.X))
+		if st == nil {
+			return nil
+		}
+		if i := st.Field(expr.Sel.Name); i >= 0 {
+			return ir.typeObject(st.Fields[i].Type)
+		}
+	case *syntax.IndexExpr:
+		t := ir.exprType(expr.X)
+		if expr.Range() != nil {
+			if _, elem, ok := ir.arrayOf(t); ok {
+				return ir.GetObject(nil, syntax.ArrayTypeName(-1, elem.Name))
+			}
+			return t
+		}
+		if _, elem, ok := ir.arrayOf(t); ok {
+			return elem
+		}
+		return ir.sliceOf(t)
+	case *syntax.CompositeLit:
+		return ir.typeObject(expr.Type)
+	case *syntax.CallExpr:
+		if obj := expr.Func.Obj; obj != nil && obj.Kind == syntax.ObjKindType {
+			return obj
+		}
+		if obj := expr.Func.Obj; obj != nil && obj.Kind == syntax.ObjKindBuiltin {
+			if obj.Name == "append" && len(expr.Args) > 0 {
+				return ir.exprType(expr.Args[0])
+			}
+			return nil
+		}
+		if fn := ir.callee(expr); fn != nil && len(fn.Type.Results) == 1 {
+			return ir.typeObject(fn.Type.Results[0])
+		}
+	}
+	return nil
+}
+
+// declareLeaves makes the variable obj called name visible along with the
+// hidden variable of each of its leaves, and returns the leaves' objects. A
+// variable that is not a struct is its own single leaf.
+func (ir *IR) declareLeaves(name string, obj *syntax.Object, t *syntax.Object) []*syntax.Object {
+	ir.SetObject(name, obj)
+	if !scalarised(t) {
+		return []*syntax.Object{obj}
+	}
+
+	var objs []*syntax.Object
+	for _, l := range ir.leaves(t) {
+		field := syntax.NewObject(syntax.ObjKindVar, name+l.path, obj.Decl)
+		field.Type = l.t.Name
+		ir.SetObject(field.Name, field)
+		objs = append(objs, field)
+	}
+	return objs
+}
+
+// allocStruct declares the variable obj called name of struct type t with
+// the leaf values values, and returns its leaves.
+func (ir *IR) allocStruct(name string, obj *syntax.Object, t *syntax.Object, values []Inst) []Inst {
+	objs := ir.declareLeaves(name, obj, t)
+	if len(values) != len(objs) {
+		ir.errors = append(ir.errors, NewError(declPos(obj), fmt.Sprintf("cannot initialise %s with %d values", name, len(values))))
+		return nil
+	}
+
+	lefts := make([]Inst, len(objs))
+	for i, field := range objs {
+		t := ir.GetObject(nil, field.Type)
+		ir.AddInstruction(NewAllocInst(field.Name, ir.own(values[i], t), t))
+		lefts[i] = NewIdentInst(field.Name)
+	}
+	return lefts
+}
+
+// generateValues lowers expr to the values of its leaves: a single value
+// unless expr is a struct.
+func (ir *IR) generateValues(expr syntax.Expression) []Inst {
+	switch expr := expr.(type) {
+	case *syntax.ParenExpr:
+		return ir.generateValues(expr.X)
+	case *syntax.IndexExpr:
+		return ir.generateIndex(expr)
+	case *syntax.SelectorExpr:
+		base := ir.generateValues(expr.X)
+		start, n := ir.fieldLeaves(ir.exprType(expr.X), expr.Sel.Name)
+		if n == 0 || start+n > len(base) {
+			ir.errors = append(ir.errors, NewUnexpectedExpr(expr.Sel.Pos()))
+			return nil
+		}
+		return base[start : start+n]
+	}
+
+	t := ir.exprType(expr)
+	if lit, ok := expr.(*syntax.CompositeLit); ok && (isArray(t) || isSlice(t)) {
+		return ir.generateArrayLit(lit, t)
+	}
+	if !scalarised(t) {
+		return []Inst{ir.generateExpr(expr)}
+	}
+
+	switch expr := expr.(type) {
+	case *syntax.Identifier:
+		var values []Inst
+		for _, l := range ir.leaves(t) {
+			values = append(values, NewIdentInst(expr.Name+l.path))
+		}
+		return values
+	case *syntax.CompositeLit:
+		return ir.generateCompositeLit(expr, t)
+	case *syntax.CallExpr:
+		if obj := expr.Func.Obj; obj != nil && obj.Kind == syntax.ObjKindType && len(expr.Args) == 1 {
+			return ir.generateValues(expr.Args[0])
+		}
+		if obj := expr.Func.Obj; obj != nil && obj.Kind == syntax.ObjKindBuiltin && obj.Name == "append" {
+			return ir.generateAppend(expr, t)
+		}
+		return ir.spill(ir.generateCallExpr(expr), t)
+	case *syntax.AssignmentExpr:
+		ir.AddInstruction(ir.generateAssignmentExpr(expr))
+		return ir.generateValues(expr.Lhs)
+	default:
+		ir.errors = append(ir.errors, NewUnexpectedExpr(expr.Pos()))
+		return nil
+	}
+}
+
+// generateCompositeLit lowers a struct literal of type t to the values of
+// its fields in declaration order. Fields it leaves out are zero.
+func (ir *IR) generateCompositeLit(lit *syntax.CompositeLit, t *syntax.Object) []Inst {
+	var values []Inst
+	for i, f := range structOf(t).Fields {
+		var elt syntax.Expression
+		for j, e := range lit.Elts {
+			if kv, ok := e.(*syntax.KeyValueExpr); ok {
+				if kv.Key.Name == f.Name.Name {
+					elt = kv.Value
+				}
+			} else if j == i {
+				elt = e
+			}
+		}
+
+		if elt != nil {
+			values = append(values, ir.generateValues(elt)...)
+		} else {
+			values = append(values, ir.zeroValues(ir.typeObject(f.Type))...)
+		}
+	}
+	return values
+}
+
+// spill stores the results of call, which returns a struct of type t, in a
+// hidden local and returns its leaves.
+func (ir *IR) spill(call Inst, t *syntax.Object) []Inst {
+	if call == nil {
+		return nil
+	}
+	if len(ir.leaves(t)) == 0 {
+		ir.AddInstruction(call)
+		return nil
+	}
+
+	ir.temps++
+	tmp := syntax.NewObject(syntax.ObjKindVar, fmt.Sprintf("%s.%d", t.Name, ir.temps), nil)
+	tmp.Type = t.Name
+	lefts := ir.allocStruct(tmp.Name, tmp, t, ir.zeroValues(t))
+	ir.AddInstruction(NewTupleAssignInst(lefts, []Inst{call}))
+	for _, left := range lefts {
+		ir.unshared[left.(*IdentInst).Name] = true
+	}
+	return lefts
+}
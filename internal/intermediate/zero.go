@@ -1,27 +1,55 @@
 package intermediate
 
-import (
-	"fmt"
-	"os"
-
-	"github.com/danecwalker/hippo/internal/syntax"
-)
+import "github.com/danecwalker/hippo/internal/syntax"
 
+// NewZeroInst returns the zero value of the basic or array type t. The zero
+// value of a struct or slice is made of one for each of its leaves; see
+// zeroValues.
 func (ir *IR) NewZeroInst(t *syntax.Object) Inst {
 	if t.Kind != syntax.ObjKindType {
-		fmt.Fprintln(os.Stderr, NewError(t.Decl.Pos(), "Cannot create zero value for non-type: "+t.Name))
+		ir.errors = append(ir.errors, NewError(declPos(t), "cannot create zero value for non-type: "+t.Name))
 		return nil
 	}
 
+	if isArray(t) {
+		return NewArrayInst(ir.words(t), nil)
+	}
+
 	switch t.Type {
-	case "i32":
-		return ir.NewIntZeroInst()
+	case "i8", "i16", "i32", "i64", "u8", "u16", "u32", "u64", "f32", "f64":
+		return NewBasicLitInst("0", t)
+	case "bool":
+		return ir.NewBoolZeroInst()
+	case "string":
+		return NewBasicLitInst(`""`, t)
 	default:
-		fmt.Fprintln(os.Stderr, NewError(t.Decl.Pos(), "Cannot create zero value for type: "+t.Name))
+		ir.errors = append(ir.errors, NewError(declPos(t), "cannot create zero value for type: "+t.Name))
 		return nil
 	}
 }
 
+// zeroValues returns the zero value of every leaf of a value of type t.
+func (ir *IR) zeroValues(t *syntax.Object) []Inst {
+	var values []Inst
+	for _, l := range ir.leaves(t) {
+		values = append(values, ir.NewZeroInst(l.t))
+	}
+	return values
+}
+
 func (ir *IR) NewIntZeroInst() Inst {
 	return NewBasicLitInst("0", ir.GetObject(nil, "i32"))
 }
+
+func (ir *IR) NewBoolZeroInst() Inst {
+	return NewBasicLitInst("false", ir.GetObject(nil, "bool"))
+}
+
+// declPos returns the position obj was declared at, or nil for predeclared
+// objects.
+func declPos(obj *syntax.Object) *syntax.Position {
+	if obj.Decl == nil {
+		return nil
+	}
+	return obj.Decl.Pos()
+}
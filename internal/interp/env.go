@@ -0,0 +1,29 @@
+package interp
+
+type Env struct {
+	Parent *Env
+	Values map[string]*Value
+}
+
+func NewEnv(parent *Env) *Env {
+	return &Env{
+		Parent: parent,
+		Values: make(map[string]*Value),
+	}
+}
+
+func (e *Env) Define(name string, v Value) {
+	e.Values[name] = &v
+}
+
+func (e *Env) Lookup(name string) *Value {
+	if v, ok := e.Values[name]; ok {
+		return v
+	}
+
+	if e.Parent != nil {
+		return e.Parent.Lookup(name)
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package interp
+
+import (
+	"fmt"
+
+	"github.com/danecwalker/hippo/internal/diag"
+	"github.com/danecwalker/hippo/internal/syntax"
+)
+
+// Error is a problem found while interpreting a program.
+type Error = diag.Diagnostic
+
+func NewError(pos *syntax.Position, msg string) *Error {
+	return diag.NewError(diag.CodeRuntime, pos, msg)
+}
+
+func NewUndefinedError(pos *syntax.Position, name string) *Error {
+	msg := "undefined: %s"
+	return NewError(pos, fmt.Sprintf(msg, name))
+}
+
+func NewNotCallableError(pos *syntax.Position, name string) *Error {
+	msg := "cannot call non-function %s"
+	return NewError(pos, fmt.Sprintf(msg, name))
+}
+
+func NewArgumentCountError(pos *syntax.Position, name string, want, got int) *Error {
+	msg := "wrong number of arguments in call to %s: want %d, got %d"
+	return NewError(pos, fmt.Sprintf(msg, name, want, got))
+}
+
+func NewAssignmentCountError(pos *syntax.Position, vars, values int) *Error {
+	msg := "assignment mismatch: %d variables but %d values"
+	return NewError(pos, fmt.Sprintf(msg, vars, values))
+}
+
+func NewOperandError(pos *syntax.Position, op string, v Value) *Error {
+	msg := "invalid operand %s for %s"
+	return NewError(pos, fmt.Sprintf(msg, v, op))
+}
+
+func NewConditionError(pos *syntax.Position, v Value) *Error {
+	msg := "non-boolean condition %s"
+	return NewError(pos, fmt.Sprintf(msg, v))
+}
+
+func NewDivideByZeroError(pos *syntax.Position) *Error {
+	return NewError(pos, "integer divide by zero")
+}
+
+func NewUnexpectedStmtError(pos *syntax.Position) *Error {
+	return NewError(pos, "unexpected statement")
+}
+
+func NewUnexpectedExprError(pos *syntax.Position) *Error {
+	return NewError(pos, "unexpected expression")
+}
+
+func NewIndexOutOfRangeError(pos *syntax.Position, index int64, length int) *Error {
+	msg := "panic: index out of range [%d] with length %d"
+	return NewError(pos, fmt.Sprintf(msg, index, length))
+}
+
+func NewSliceBoundsError(pos *syntax.Position, lo, hi int64, capacity int) *Error {
+	msg := "panic: slice bounds out of range [%d..%d] with capacity %d"
+	return NewError(pos, fmt.Sprintf(msg, lo, hi, capacity))
+}
@@ -0,0 +1,1202 @@
+package interp
+
+import (
+	"io"
+	"os"
+
+	"github.com/danecwalker/hippo/internal/syntax"
+)
+
+type signal int
+
+const (
+	sigNone signal = iota
+	sigReturn
+	sigBreak
+	sigContinue
+)
+
+type Interpreter struct {
+	// Stdout receives the output of print. It defaults to os.Stdout.
+	Stdout io.Writer
+
+	globals *Env
+	env     *Env
+	result  Value
+
+	// branch is the label of the loop a sigBreak or sigContinue is
+	// leaving, or "" for the innermost one. label is the label of the loop
+	// about to run.
+	branch string
+	label  string
+}
+
+func NewInterpreter() *Interpreter {
+	universe := NewEnv(nil)
+	universe.Define("bool", &Type{Name: "bool"})
+	for _, name := range []string{"i8", "i16", "i32", "i64", "u8", "u16", "u32", "u64", "f32", "f64"} {
+		universe.Define(name, &Type{Name: name})
+	}
+	universe.Define("string", &Type{Name: "string"})
+	universe.Define("true", Bool(true))
+	universe.Define("false", Bool(false))
+	universe.Define("len", &Builtin{Name: "len"})
+	universe.Define("cap", &Builtin{Name: "cap"})
+	universe.Define("append", &Builtin{Name: "append"})
+	universe.Define("print", &Builtin{Name: "print"})
+
+	globals := NewEnv(universe)
+	return &Interpreter{
+		Stdout:  os.Stdout,
+		globals: globals,
+		env:     globals,
+	}
+}
+
+// Run evaluates the top level declarations of prog in order and then calls
+// fn main, returning its result. A main without a result yields 0.
+func (in *Interpreter) Run(prog *syntax.Program) (Value, error) {
+	for _, stmt := range prog.Statements {
+		switch stmt := stmt.(type) {
+		case *syntax.FuncStmt:
+			in.globals.Define(stmt.Name.Name, &Func{Stmt: stmt})
+		case *syntax.TypeStmt:
+			in.globals.Define(stmt.Name.Name, &Type{Name: stmt.Name.Name, Struct: stmt})
+		}
+	}
+
+	for _, stmt := range prog.Statements {
+		switch stmt := stmt.(type) {
+		case *syntax.FuncStmt, *syntax.TypeStmt:
+		case *syntax.VarStatement:
+			if err := in.execVar(stmt); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, NewError(stmt.Pos(), "only type, variable and function declarations are allowed at the top level")
+		}
+	}
+
+	main := in.globals.Lookup("main")
+	if main == nil {
+		return nil, NewError(nil, "function main is undeclared")
+	}
+	fn, ok := (*main).(*Func)
+	if !ok {
+		return nil, NewNotCallableError(nil, "main")
+	}
+
+	v, err := in.call(fn, nil, fn.Stmt.Pos())
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		v = NewInt(0, "i32")
+	}
+	return v, nil
+}
+
+func (in *Interpreter) call(fn *Func, args []Value, pos *syntax.Position) (Value, error) {
+	var params []*syntax.Identifier
+	for _, field := range fn.Stmt.Type.Params {
+		params = append(params, field.Names...)
+	}
+
+	if len(params) != len(args) {
+		return nil, NewArgumentCountError(pos, fn.Stmt.Name.Name, len(params), len(args))
+	}
+
+	saved := in.env
+	in.env = NewEnv(in.globals)
+	defer func() { in.env = saved }()
+
+	for i, param := range params {
+		in.env.Define(param.Name, copyValue(args[i]))
+	}
+
+	in.result = nil
+	if _, err := in.execBlock(fn.Stmt.Body); err != nil {
+		return nil, err
+	}
+
+	result := in.result
+	in.result = nil
+	return result, nil
+}
+
+func (in *Interpreter) execStmt(stmt syntax.Statement) (signal, error) {
+	switch stmt := stmt.(type) {
+	case *syntax.VarStatement:
+		return sigNone, in.execVar(stmt)
+	case *syntax.FuncStmt:
+		in.env.Define(stmt.Name.Name, &Func{Stmt: stmt})
+		return sigNone, nil
+	case *syntax.TypeStmt:
+		in.env.Define(stmt.Name.Name, &Type{Name: stmt.Name.Name, Struct: stmt})
+		return sigNone, nil
+	case *syntax.ReturnStmt:
+		return in.execReturn(stmt)
+	case *syntax.BlockStmt:
+		return in.execBlock(stmt)
+	case *syntax.IfStmt:
+		return in.execIf(stmt)
+	case *syntax.ForRangeStmt:
+		return in.execForRange(stmt)
+	case *syntax.WhileStmt:
+		return in.execWhile(stmt)
+	case *syntax.ForLoopStmt:
+		return in.execForLoop(stmt)
+	case *syntax.LabeledStmt:
+		in.label = stmt.Label.Name
+		return in.execStmt(stmt.Stmt)
+	case *syntax.BranchStmt:
+		in.branch = ""
+		if stmt.Label != nil {
+			in.branch = stmt.Label.Name
+		}
+		if stmt.Tok == syntax.TokenBreak {
+			return sigBreak, nil
+		}
+		return sigContinue, nil
+	case *syntax.TupleAssignStmt:
+		return sigNone, in.execTupleAssign(stmt)
+	case *syntax.IncDecStmt:
+		return sigNone, in.execIncDec(stmt)
+	case *syntax.ExpressionStmt:
+		_, err := in.evalExpr(stmt.X)
+		return sigNone, err
+	default:
+		return sigNone, NewUnexpectedStmtError(stmt.Pos())
+	}
+}
+
+func (in *Interpreter) execBlock(block *syntax.BlockStmt) (signal, error) {
+	saved := in.env
+	in.env = NewEnv(in.env)
+	defer func() { in.env = saved }()
+
+	for _, stmt := range block.Stmts {
+		sig, err := in.execStmt(stmt)
+		if err != nil || sig != sigNone {
+			return sig, err
+		}
+	}
+
+	return sigNone, nil
+}
+
+func (in *Interpreter) execVar(stmt *syntax.VarStatement) error {
+	values, err := in.evalList(stmt.Values)
+	if err != nil {
+		return err
+	}
+
+	for i, name := range stmt.Names {
+		var v Value
+		if i < len(values) {
+			v = values[i]
+		} else {
+			if stmt.Type == nil {
+				return NewError(name.Pos(), "missing type or initial value for "+name.Name)
+			}
+			var err error
+			v, err = in.zero(stmt.Type)
+			if err != nil {
+				return err
+			}
+		}
+
+		in.env.Define(name.Name, copyValue(v))
+	}
+
+	return nil
+}
+
+func (in *Interpreter) zero(type_ *syntax.Identifier) (Value, error) {
+	name := type_.Name
+	if type_.Obj != nil {
+		if _, ok := type_.Obj.Decl.(*syntax.ArrayType); ok {
+			// The checker names the type with its length evaluated.
+			name = type_.Obj.Name
+		}
+	}
+	return in.zeroNamed(name, type_)
+}
+
+// zeroNamed returns the zero value of the type called name, which type_
+// spells.
+func (in *Interpreter) zeroNamed(name string, type_ *syntax.Identifier) (Value, error) {
+	switch {
+	case isInteger(name):
+		return NewInt(0, name), nil
+	case isFloat(name):
+		return NewFloat(0, name), nil
+	case name == "bool":
+		return Bool(false), nil
+	case name == "string":
+		return Str(""), nil
+	}
+
+	if n, elem, ok := syntax.SplitArrayTypeName(name); ok {
+		if n < 0 {
+			return &Slice{}, nil
+		}
+		a := &Array{Elems: make([]Value, n)}
+		for i := range a.Elems {
+			ev, err := in.zeroNamed(elem, type_)
+			if err != nil {
+				return nil, err
+			}
+			a.Elems[i] = ev
+		}
+		return a, nil
+	}
+
+	if v := in.env.Lookup(name); v != nil {
+		if t, ok := (*v).(*Type); ok && t.Struct != nil {
+			s := &Struct{Decl: t.Struct}
+			for _, f := range t.Struct.Type.Fields {
+				fv, err := in.zero(f.Type)
+				if err != nil {
+					return nil, err
+				}
+				s.Fields = append(s.Fields, fv)
+			}
+			return s, nil
+		}
+	}
+
+	return nil, NewError(type_.Pos(), "cannot create zero value for type: "+name)
+}
+
+func (in *Interpreter) execReturn(stmt *syntax.ReturnStmt) (signal, error) {
+	values, err := in.evalList(stmt.Results)
+	if err != nil {
+		return sigNone, err
+	}
+
+	switch len(values) {
+	case 0:
+		in.result = nil
+	case 1:
+		in.result = values[0]
+	default:
+		in.result = Tuple(values)
+	}
+
+	return sigReturn, nil
+}
+
+// evalList evaluates exprs left to right. A single call returning several
+// values is expanded into its results.
+func (in *Interpreter) evalList(exprs []syntax.Expression) ([]Value, error) {
+	values := make([]Value, 0, len(exprs))
+	for _, expr := range exprs {
+		v, err := in.evalExpr(expr)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+
+	if len(values) == 1 {
+		if tuple, ok := values[0].(Tuple); ok {
+			return tuple, nil
+		}
+	}
+
+	return values, nil
+}
+
+func (in *Interpreter) evalCond(cond syntax.Expression) (bool, error) {
+	v, err := in.evalExpr(cond)
+	if err != nil {
+		return false, err
+	}
+
+	b, ok := v.(Bool)
+	if !ok {
+		return false, NewConditionError(cond.Pos(), v)
+	}
+
+	return bool(b), nil
+}
+
+func (in *Interpreter) execIf(stmt *syntax.IfStmt) (signal, error) {
+	ok, err := in.evalCond(stmt.Cond)
+	if err != nil {
+		return sigNone, err
+	}
+
+	if ok {
+		return in.execBlock(stmt.Consequence)
+	} else if stmt.Alternative != nil {
+		return in.execStmt(stmt.Alternative)
+	}
+
+	return sigNone, nil
+}
+
+func (in *Interpreter) execForRange(stmt *syntax.ForRangeStmt) (signal, error) {
+	label := in.takeLabel()
+
+	r, ok := stmt.X.(*syntax.RangeExpr)
+	if !ok {
+		return in.execForEach(stmt, label)
+	}
+
+	low, err := in.evalInt(r.Low)
+	if err != nil {
+		return sigNone, err
+	}
+
+	high, err := in.evalInt(r.High)
+	if err != nil {
+		return sigNone, err
+	}
+
+	saved := in.env
+	in.env = NewEnv(in.env)
+	defer func() { in.env = saved }()
+
+	for i := low.Value; i < high.Value; i++ {
+		in.env.Define(stmt.Key.Name, NewInt(i, low.Type))
+
+		sig, err := in.execBlock(stmt.Body)
+		if sig, done := in.loopSignal(sig, label); err != nil || done {
+			return sig, err
+		}
+	}
+
+	return sigNone, nil
+}
+
+// execForEach runs the body of stmt for each element of the array or slice
+// it ranges over, as it was when the loop started.
+func (in *Interpreter) execForEach(stmt *syntax.ForRangeStmt, label string) (signal, error) {
+	x, err := in.evalExpr(stmt.X)
+	if err != nil {
+		return sigNone, err
+	}
+
+	// Ranging over an array ranges over a copy of it.
+	elems, ok := elements(copyValue(x))
+	if !ok {
+		return sigNone, NewError(stmt.X.Pos(), "cannot range over "+x.String())
+	}
+
+	saved := in.env
+	in.env = NewEnv(in.env)
+	defer func() { in.env = saved }()
+
+	for i, e := range elems {
+		if stmt.Value != nil {
+			in.env.Define(stmt.Key.Name, NewInt(int64(i), "i32"))
+			in.env.Define(stmt.Value.Name, copyValue(e))
+		} else {
+			in.env.Define(stmt.Key.Name, copyValue(e))
+		}
+
+		sig, err := in.execBlock(stmt.Body)
+		if sig, done := in.loopSignal(sig, label); err != nil || done {
+			return sig, err
+		}
+	}
+
+	return sigNone, nil
+}
+
+func (in *Interpreter) execWhile(stmt *syntax.WhileStmt) (signal, error) {
+	label := in.takeLabel()
+
+	for {
+		ok, err := in.evalCond(stmt.Cond)
+		if err != nil || !ok {
+			return sigNone, err
+		}
+
+		sig, err := in.execBlock(stmt.Body)
+		if sig, done := in.loopSignal(sig, label); err != nil || done {
+			return sig, err
+		}
+	}
+}
+
+func (in *Interpreter) execForLoop(stmt *syntax.ForLoopStmt) (signal, error) {
+	label := in.takeLabel()
+
+	saved := in.env
+	in.env = NewEnv(in.env)
+	defer func() { in.env = saved }()
+
+	if stmt.Init != nil {
+		if _, err := in.execStmt(stmt.Init); err != nil {
+			return sigNone, err
+		}
+	}
+
+	for {
+		if stmt.Cond != nil {
+			ok, err := in.evalCond(stmt.Cond)
+			if err != nil || !ok {
+				return sigNone, err
+			}
+		}
+
+		sig, err := in.execBlock(stmt.Body)
+		if sig, done := in.loopSignal(sig, label); err != nil || done {
+			return sig, err
+		}
+
+		if stmt.Post != nil {
+			if _, err := in.execStmt(stmt.Post); err != nil {
+				return sigNone, err
+			}
+		}
+	}
+}
+
+// takeLabel returns the label of the loop about to run and clears it, so
+// that loops nested inside it are not mistaken for the labeled one.
+func (in *Interpreter) takeLabel() string {
+	label := in.label
+	in.label = ""
+	return label
+}
+
+// loopSignal handles sig, the signal an iteration of the loop labeled label
+// ended with. It returns the signal for the loop to return and whether the
+// loop must stop.
+func (in *Interpreter) loopSignal(sig signal, label string) (signal, bool) {
+	switch sig {
+	case sigNone:
+		return sigNone, false
+	case sigBreak, sigContinue:
+		if in.branch != "" && in.branch != label {
+			// Leaving an outer loop.
+			return sig, true
+		}
+		in.branch = ""
+		return sigNone, sig == sigBreak
+	default:
+		return sig, true
+	}
+}
+
+func (in *Interpreter) evalExpr(expr syntax.Expression) (Value, error) {
+	switch expr := expr.(type) {
+	case *syntax.Identifier:
+		if expr.Const != nil {
+			return in.evalBasicLit(expr.Const)
+		}
+		v := in.env.Lookup(expr.Name)
+		if v == nil {
+			return nil, NewUndefinedError(expr.Pos(), expr.Name)
+		}
+		return *v, nil
+	case *syntax.BasicLit:
+		return in.evalBasicLit(expr)
+	case *syntax.BinaryExpr:
+		return in.evalBinaryExpr(expr)
+	case *syntax.UnaryExpr:
+		return in.evalUnaryExpr(expr)
+	case *syntax.ParenExpr:
+		return in.evalExpr(expr.X)
+	case *syntax.AssignmentExpr:
+		return in.evalAssignmentExpr(expr)
+	case *syntax.CallExpr:
+		return in.evalCallExpr(expr)
+	case *syntax.SelectorExpr:
+		x, err := in.evalExpr(expr.X)
+		if err != nil {
+			return nil, err
+		}
+		f, err := field(x, expr.Sel)
+		if err != nil {
+			return nil, err
+		}
+		return *f, nil
+	case *syntax.CompositeLit:
+		return in.evalCompositeLit(expr)
+	case *syntax.IndexExpr:
+		x, err := in.evalExpr(expr.X)
+		if err != nil {
+			return nil, err
+		}
+		if r := expr.Range(); r != nil {
+			return in.slice(x, r, expr)
+		}
+		e, err := in.element(x, expr)
+		if err != nil {
+			return nil, err
+		}
+		return *e, nil
+	default:
+		return nil, NewUnexpectedExprError(expr.Pos())
+	}
+}
+
+func (in *Interpreter) evalInt(expr syntax.Expression) (*Int, error) {
+	v, err := in.evalExpr(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	i, ok := v.(*Int)
+	if !ok {
+		return nil, NewError(expr.Pos(), "expected integer, got "+v.String())
+	}
+
+	return i, nil
+}
+
+func (in *Interpreter) evalBasicLit(lit *syntax.BasicLit) (Value, error) {
+	switch {
+	case isInteger(lit.Kind):
+		v, ok := syntax.Int64Value(lit.Value)
+		if !ok {
+			return nil, NewError(lit.Pos(), "invalid integer literal "+lit.Value)
+		}
+		return NewInt(v, lit.Kind), nil
+	case isFloat(lit.Kind):
+		v, ok := syntax.FloatValue(lit.Value)
+		if !ok {
+			return nil, NewError(lit.Pos(), "invalid floating-point literal "+lit.Value)
+		}
+		return NewFloat(v, lit.Kind), nil
+	case lit.Kind == "string":
+		s, ok := syntax.StringValue(lit.Value)
+		if !ok {
+			return nil, NewError(lit.Pos(), "invalid string literal "+lit.Value)
+		}
+		return Str(s), nil
+	default:
+		return nil, NewUnexpectedExprError(lit.Pos())
+	}
+}
+
+func (in *Interpreter) evalBinaryExpr(expr *syntax.BinaryExpr) (Value, error) {
+	switch expr.Op.Type {
+	case syntax.TokenLAnd, syntax.TokenLOr:
+		return in.evalLogical(expr)
+	}
+
+	xv, err := in.evalExpr(expr.X)
+	if err != nil {
+		return nil, err
+	}
+
+	yv, err := in.evalExpr(expr.Y)
+	if err != nil {
+		return nil, err
+	}
+
+	return evalBinary(expr.Op, xv, yv)
+}
+
+// evalBinary applies the binary operator op to xv and yv.
+func evalBinary(op *syntax.Token, xv, yv Value) (Value, error) {
+	if x, ok := xv.(Bool); ok {
+		y, ok := yv.(Bool)
+		if !ok {
+			return nil, NewOperandError(op.Position, op.Literal, yv)
+		}
+		switch op.Type {
+		case syntax.TokenEq:
+			return Bool(x == y), nil
+		case syntax.TokenNotEq:
+			return Bool(x != y), nil
+		default:
+			return nil, NewOperandError(op.Position, op.Literal, x)
+		}
+	}
+
+	if x, ok := xv.(Str); ok {
+		y, ok := yv.(Str)
+		if !ok {
+			return nil, NewOperandError(op.Position, op.Literal, yv)
+		}
+		switch op.Type {
+		case syntax.TokenPlus:
+			return x + y, nil
+		case syntax.TokenEq:
+			return Bool(x == y), nil
+		case syntax.TokenNotEq:
+			return Bool(x != y), nil
+		default:
+			return nil, NewOperandError(op.Position, op.Literal, x)
+		}
+	}
+
+	if x, ok := xv.(*Float); ok {
+		y, ok := yv.(*Float)
+		if !ok {
+			return nil, NewOperandError(op.Position, op.Literal, yv)
+		}
+		return evalFloat(op, x, y)
+	}
+
+	x, ok := xv.(*Int)
+	if !ok {
+		return nil, NewOperandError(op.Position, op.Literal, xv)
+	}
+	y, ok := yv.(*Int)
+	if !ok {
+		return nil, NewOperandError(op.Position, op.Literal, yv)
+	}
+
+	switch op.Type {
+	case syntax.TokenPlus:
+		return NewInt(x.Value+y.Value, x.Type), nil
+	case syntax.TokenMinus:
+		return NewInt(x.Value-y.Value, x.Type), nil
+	case syntax.TokenStar:
+		return NewInt(x.Value*y.Value, x.Type), nil
+	case syntax.TokenSlash:
+		if y.Value == 0 {
+			return nil, NewDivideByZeroError(op.Position)
+		}
+		if isUnsigned(x.Type) {
+			return NewInt(int64(uint64(x.Value)/uint64(y.Value)), x.Type), nil
+		}
+		return NewInt(x.Value/y.Value, x.Type), nil
+	case syntax.TokenPercent:
+		if y.Value == 0 {
+			return nil, NewDivideByZeroError(op.Position)
+		}
+		if isUnsigned(x.Type) {
+			return NewInt(int64(uint64(x.Value)%uint64(y.Value)), x.Type), nil
+		}
+		return NewInt(x.Value%y.Value, x.Type), nil
+	case syntax.TokenAnd:
+		return NewInt(x.Value&y.Value, x.Type), nil
+	case syntax.TokenOr:
+		return NewInt(x.Value|y.Value, x.Type), nil
+	case syntax.TokenXor:
+		return NewInt(x.Value^y.Value, x.Type), nil
+	case syntax.TokenAndNot:
+		return NewInt(x.Value&^y.Value, x.Type), nil
+	case syntax.TokenShl, syntax.TokenShr:
+		return NewInt(shift(op.Type, x, uint64(y.Value)), x.Type), nil
+	}
+
+	if isUnsigned(x.Type) {
+		return compareUnsigned(op, uint64(x.Value), uint64(y.Value))
+	}
+
+	switch op.Type {
+	case syntax.TokenLt:
+		return Bool(x.Value < y.Value), nil
+	case syntax.TokenGt:
+		return Bool(x.Value > y.Value), nil
+	case syntax.TokenLtEq:
+		return Bool(x.Value <= y.Value), nil
+	case syntax.TokenGtEq:
+		return Bool(x.Value >= y.Value), nil
+	case syntax.TokenEq:
+		return Bool(x.Value == y.Value), nil
+	case syntax.TokenNotEq:
+		return Bool(x.Value != y.Value), nil
+	default:
+		return nil, NewOperandError(op.Position, op.Literal, x)
+	}
+}
+
+// shift shifts x by n bits. Counts of 64 or more shift out every bit,
+// leaving 0, or copies of the sign bit for a signed right shift.
+func shift(op syntax.TokenType, x *Int, n uint64) int64 {
+	if n > 63 {
+		if op == syntax.TokenShr && !isUnsigned(x.Type) && x.Value < 0 {
+			return -1
+		}
+		return 0
+	}
+
+	switch {
+	case op == syntax.TokenShl:
+		return x.Value << n
+	case isUnsigned(x.Type):
+		return int64(uint64(x.Value) >> n)
+	default:
+		return x.Value >> n
+	}
+}
+
+// evalFloat applies op to two floats of the same type. Division by zero
+// follows IEEE 754 and yields an infinity or NaN.
+func evalFloat(op *syntax.Token, x, y *Float) (Value, error) {
+	switch op.Type {
+	case syntax.TokenPlus:
+		return NewFloat(x.Value+y.Value, x.Type), nil
+	case syntax.TokenMinus:
+		return NewFloat(x.Value-y.Value, x.Type), nil
+	case syntax.TokenStar:
+		return NewFloat(x.Value*y.Value, x.Type), nil
+	case syntax.TokenSlash:
+		return NewFloat(x.Value/y.Value, x.Type), nil
+	case syntax.TokenLt:
+		return Bool(x.Value < y.Value), nil
+	case syntax.TokenGt:
+		return Bool(x.Value > y.Value), nil
+	case syntax.TokenLtEq:
+		return Bool(x.Value <= y.Value), nil
+	case syntax.TokenGtEq:
+		return Bool(x.Value >= y.Value), nil
+	case syntax.TokenEq:
+		return Bool(x.Value == y.Value), nil
+	case syntax.TokenNotEq:
+		return Bool(x.Value != y.Value), nil
+	default:
+		return nil, NewOperandError(op.Position, op.Literal, x)
+	}
+}
+
+func compareUnsigned(op *syntax.Token, x, y uint64) (Value, error) {
+	switch op.Type {
+	case syntax.TokenLt:
+		return Bool(x < y), nil
+	case syntax.TokenGt:
+		return Bool(x > y), nil
+	case syntax.TokenLtEq:
+		return Bool(x <= y), nil
+	case syntax.TokenGtEq:
+		return Bool(x >= y), nil
+	case syntax.TokenEq:
+		return Bool(x == y), nil
+	case syntax.TokenNotEq:
+		return Bool(x != y), nil
+	default:
+		return nil, NewOperandError(op.Position, op.Literal, NewInt(int64(x), "u64"))
+	}
+}
+
+// evalLogical evaluates the right operand of && and || only when the left
+// operand does not already decide the result.
+func (in *Interpreter) evalLogical(expr *syntax.BinaryExpr) (Value, error) {
+	x, err := in.evalBool(expr.X, expr.Op)
+	if err != nil {
+		return nil, err
+	}
+
+	if x == (expr.Op.Type == syntax.TokenLOr) {
+		return Bool(x), nil
+	}
+
+	y, err := in.evalBool(expr.Y, expr.Op)
+	if err != nil {
+		return nil, err
+	}
+
+	return Bool(y), nil
+}
+
+func (in *Interpreter) evalBool(expr syntax.Expression, op *syntax.Token) (bool, error) {
+	v, err := in.evalExpr(expr)
+	if err != nil {
+		return false, err
+	}
+
+	b, ok := v.(Bool)
+	if !ok {
+		return false, NewOperandError(op.Position, op.Literal, v)
+	}
+
+	return bool(b), nil
+}
+
+func (in *Interpreter) evalUnaryExpr(expr *syntax.UnaryExpr) (Value, error) {
+	switch expr.Op.Type {
+	case syntax.TokenNot:
+		x, err := in.evalBool(expr.X, expr.Op)
+		if err != nil {
+			return nil, err
+		}
+		return Bool(!x), nil
+	case syntax.TokenMinus, syntax.TokenXor:
+		xv, err := in.evalExpr(expr.X)
+		if err != nil {
+			return nil, err
+		}
+		switch x := xv.(type) {
+		case *Int:
+			if expr.Op.Type == syntax.TokenMinus {
+				return NewInt(-x.Value, x.Type), nil
+			}
+			return NewInt(^x.Value, x.Type), nil
+		case *Float:
+			if expr.Op.Type == syntax.TokenMinus {
+				return NewFloat(-x.Value, x.Type), nil
+			}
+		}
+		return nil, NewOperandError(expr.Op.Position, expr.Op.Literal, xv)
+	default:
+		return nil, NewUnexpectedExprError(expr.Pos())
+	}
+}
+
+func (in *Interpreter) evalAssignmentExpr(expr *syntax.AssignmentExpr) (Value, error) {
+	slot, err := in.lookupVar(expr.Lhs)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := in.evalExpr(expr.Rhs)
+	if err != nil {
+		return nil, err
+	}
+
+	if expr.Op.Type != syntax.TokenAssign {
+		op, ok := syntax.CompoundOp(expr.Op.Type)
+		if !ok {
+			return nil, NewUnexpectedExprError(expr.Pos())
+		}
+		v, err = evalBinary(syntax.NewToken(op, expr.Op.Literal, expr.Op.Position), *slot, v)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	store(slot, v)
+	return v, nil
+}
+
+// field returns the storage of the field sel of the struct x.
+func field(x Value, sel *syntax.Identifier) (*Value, error) {
+	if s, ok := x.(*Struct); ok {
+		if f := s.Field(sel.Name); f != nil {
+			return f, nil
+		}
+	}
+	return nil, NewError(sel.Pos(), "undefined field "+sel.Name+" of "+x.String())
+}
+
+// element returns the storage of the element of the array or slice x that
+// expr selects.
+func (in *Interpreter) element(x Value, expr *syntax.IndexExpr) (*Value, error) {
+	elems, ok := elements(x)
+	if !ok {
+		return nil, NewError(expr.X.Pos(), "cannot index "+x.String())
+	}
+
+	i, err := in.evalInt(expr.Index)
+	if err != nil {
+		return nil, err
+	}
+	if uint64(i.Value) >= uint64(len(elems)) {
+		return nil, NewIndexOutOfRangeError(expr.Lbrack, i.Value, len(elems))
+	}
+	return &elems[i.Value], nil
+}
+
+// slice returns the slice of the array or slice x between the bounds r,
+// which may reach past its length up to its capacity.
+func (in *Interpreter) slice(x Value, r *syntax.RangeExpr, expr *syntax.IndexExpr) (Value, error) {
+	elems, ok := elements(x)
+	if !ok {
+		return nil, NewError(expr.X.Pos(), "cannot slice "+x.String())
+	}
+
+	lo, err := in.evalInt(r.Low)
+	if err != nil {
+		return nil, err
+	}
+	hi, err := in.evalInt(r.High)
+	if err != nil {
+		return nil, err
+	}
+	if uint64(lo.Value) > uint64(hi.Value) || uint64(hi.Value) > uint64(cap(elems)) {
+		return nil, NewSliceBoundsError(expr.Lbrack, lo.Value, hi.Value, cap(elems))
+	}
+	return &Slice{Elems: elems[lo.Value:hi.Value]}, nil
+}
+
+// evalCompositeLit builds a struct from its fields' zero values and then
+// sets the fields the literal gives, in the order it gives them. Array and
+// slice literals give their elements in order.
+func (in *Interpreter) evalCompositeLit(lit *syntax.CompositeLit) (Value, error) {
+	v, err := in.zero(lit.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := v.(type) {
+	case *Array:
+		return v, in.evalElements(lit, v.Elems)
+	case *Slice:
+		v.Elems = make([]Value, len(lit.Elts))
+		return v, in.evalElements(lit, v.Elems)
+	}
+
+	s, ok := v.(*Struct)
+	if !ok {
+		return nil, NewError(lit.Type.Pos(), "invalid composite literal type "+lit.Type.Name)
+	}
+
+	for i, elt := range lit.Elts {
+		var slot *Value
+		if kv, ok := elt.(*syntax.KeyValueExpr); ok {
+			if slot, err = field(s, kv.Key); err != nil {
+				return nil, err
+			}
+			elt = kv.Value
+		} else if i < len(s.Fields) {
+			slot = &s.Fields[i]
+		} else {
+			return nil, NewError(elt.Pos(), "too many values in struct literal of type "+lit.Type.Name)
+		}
+
+		ev, err := in.evalExpr(elt)
+		if err != nil {
+			return nil, err
+		}
+		*slot = copyValue(ev)
+	}
+	return s, nil
+}
+
+// evalElements evaluates the elements of an array or slice literal into
+// elems.
+func (in *Interpreter) evalElements(lit *syntax.CompositeLit, elems []Value) error {
+	if len(lit.Elts) > len(elems) {
+		return NewError(lit.Elts[len(elems)].Pos(), "too many values in array literal of type "+lit.Type.Name)
+	}
+
+	for i, elt := range lit.Elts {
+		ev, err := in.evalExpr(elt)
+		if err != nil {
+			return err
+		}
+		elems[i] = copyValue(ev)
+	}
+	return nil
+}
+
+// lookupVar returns the storage of the variable, or field or element of
+// one, expr names.
+func (in *Interpreter) lookupVar(expr syntax.Expression) (*Value, error) {
+	if ie, ok := expr.(*syntax.IndexExpr); ok {
+		x, err := in.evalExpr(ie.X)
+		if err != nil {
+			return nil, err
+		}
+		return in.element(x, ie)
+	}
+
+	if sel, ok := expr.(*syntax.SelectorExpr); ok {
+		x, err := in.lookupVar(sel.X)
+		if err != nil {
+			return nil, err
+		}
+		return field(*x, sel.Sel)
+	}
+
+	ident, ok := expr.(*syntax.Identifier)
+	if !ok {
+		return nil, NewError(expr.Pos(), "cannot assign to expression")
+	}
+
+	slot := in.env.Lookup(ident.Name)
+	if slot == nil {
+		return nil, NewUndefinedError(ident.Pos(), ident.Name)
+	}
+	return slot, nil
+}
+
+// execTupleAssign evaluates every value on the right before assigning any of
+// them.
+func (in *Interpreter) execTupleAssign(stmt *syntax.TupleAssignStmt) error {
+	slots := make([]*Value, len(stmt.Lhs))
+	for i, lhs := range stmt.Lhs {
+		slot, err := in.lookupVar(lhs)
+		if err != nil {
+			return err
+		}
+		slots[i] = slot
+	}
+
+	values, err := in.evalList(stmt.Rhs)
+	if err != nil {
+		return err
+	}
+	if len(values) != len(slots) {
+		return NewAssignmentCountError(stmt.TokPos, len(slots), len(values))
+	}
+
+	// Copy the values first, so that storing an array does not change one
+	// yet to be stored.
+	for i, v := range values {
+		values[i] = copyValue(v)
+	}
+	for i, v := range values {
+		store(slots[i], v)
+	}
+	return nil
+}
+
+func (in *Interpreter) execIncDec(stmt *syntax.IncDecStmt) error {
+	slot, err := in.lookupVar(stmt.X)
+	if err != nil {
+		return err
+	}
+
+	op := syntax.NewToken(syntax.TokenPlus, stmt.Tok.String(), stmt.TokPos)
+	if stmt.Tok == syntax.TokenDec {
+		op.Type = syntax.TokenMinus
+	}
+
+	var one Value
+	switch x := (*slot).(type) {
+	case *Int:
+		one = NewInt(1, x.Type)
+	case *Float:
+		one = NewFloat(1, x.Type)
+	default:
+		return NewOperandError(stmt.TokPos, stmt.Tok.String(), x)
+	}
+
+	v, err := evalBinary(op, *slot, one)
+	if err != nil {
+		return err
+	}
+	*slot = v
+	return nil
+}
+
+func (in *Interpreter) evalCallExpr(expr *syntax.CallExpr) (Value, error) {
+	callee := in.env.Lookup(expr.Func.Name)
+	if callee == nil {
+		return nil, NewUndefinedError(expr.Func.Pos(), expr.Func.Name)
+	}
+
+	switch callee := (*callee).(type) {
+	case *Type:
+		return in.convert(expr, callee)
+	case *Builtin:
+		return in.builtin(expr, callee)
+	}
+
+	fn, ok := (*callee).(*Func)
+	if !ok {
+		return nil, NewNotCallableError(expr.Func.Pos(), expr.Func.Name)
+	}
+
+	args := make([]Value, 0, len(expr.Args))
+	for _, arg := range expr.Args {
+		v, err := in.evalExpr(arg)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+
+	return in.call(fn, args, expr.Pos())
+}
+
+func (in *Interpreter) builtin(expr *syntax.CallExpr, b *Builtin) (Value, error) {
+	args := make([]Value, 0, len(expr.Args))
+	for _, arg := range expr.Args {
+		v, err := in.evalExpr(arg)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+
+	switch b.Name {
+	case "len", "cap":
+		if len(args) != 1 {
+			return nil, NewArgumentCountError(expr.Pos(), b.Name, 1, len(args))
+		}
+		if s, ok := args[0].(Str); ok && b.Name == "len" {
+			return NewInt(int64(len(s)), "i32"), nil
+		}
+		elems, ok := elements(args[0])
+		if !ok {
+			return nil, NewError(expr.Args[0].Pos(), "invalid argument "+args[0].String()+" for "+b.Name)
+		}
+		if b.Name == "cap" {
+			return NewInt(int64(cap(elems)), "i32"), nil
+		}
+		return NewInt(int64(len(elems)), "i32"), nil
+	case "append":
+		if len(args) == 0 {
+			return nil, NewArgumentCountError(expr.Pos(), b.Name, 1, 0)
+		}
+		s, ok := args[0].(*Slice)
+		if !ok {
+			return nil, NewError(expr.Args[0].Pos(), "invalid argument "+args[0].String()+" for append")
+		}
+		return &Slice{Elems: grow(s.Elems, args[1:])}, nil
+	case "print":
+		for _, v := range args {
+			if _, err := io.WriteString(in.Stdout, v.String()); err != nil {
+				return nil, NewError(expr.Pos(), err.Error())
+			}
+		}
+		return Tuple(nil), nil
+	default:
+		return nil, NewNotCallableError(expr.Func.Pos(), b.Name)
+	}
+}
+
+// grow appends values to elems. When they do not fit, the elements move to
+// a new array with at least twice the capacity, as they do in compiled
+// programs.
+func grow(elems []Value, values []Value) []Value {
+	if n := len(elems) + len(values); n > cap(elems) {
+		c := 2 * cap(elems)
+		if c < n {
+			c = n
+		}
+		grown := make([]Value, len(elems), c)
+		for i, e := range elems {
+			grown[i] = copyValue(e)
+		}
+		elems = grown
+	}
+
+	for _, v := range values {
+		elems = append(elems, copyValue(v))
+	}
+	return elems
+}
+
+func (in *Interpreter) convert(expr *syntax.CallExpr, t *Type) (Value, error) {
+	if len(expr.Args) != 1 {
+		return nil, NewArgumentCountError(expr.Pos(), expr.Func.Name, 1, len(expr.Args))
+	}
+
+	v, err := in.evalExpr(expr.Args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := v.(type) {
+	case *Int:
+		switch {
+		case isInteger(t.Name):
+			return NewInt(v.Value, t.Name), nil
+		case isFloat(t.Name) && isUnsigned(v.Type):
+			return NewFloat(float64(uint64(v.Value)), t.Name), nil
+		case isFloat(t.Name):
+			return NewFloat(float64(v.Value), t.Name), nil
+		}
+	case *Float:
+		switch {
+		case isFloat(t.Name):
+			return NewFloat(v.Value, t.Name), nil
+		case isUnsigned(t.Name):
+			return NewInt(int64(uint64(v.Value)), t.Name), nil
+		case isInteger(t.Name):
+			return NewInt(int64(v.Value), t.Name), nil
+		}
+	}
+	if b, ok := v.(Bool); ok && t.Name == "bool" {
+		return b, nil
+	}
+	if s, ok := v.(*Struct); ok && s.Decl == t.Struct {
+		return copyValue(s), nil
+	}
+
+	return nil, NewError(expr.Args[0].Pos(), "cannot convert "+v.String()+" to "+t.Name)
+}
@@ -0,0 +1,261 @@
+package interp
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/danecwalker/hippo/internal/syntax"
+)
+
+type Value interface {
+	String() string
+}
+
+type Int struct {
+	Value int64
+	Type  string
+}
+
+func NewInt(v int64, type_ string) *Int {
+	return &Int{
+		Value: wrap(v, type_),
+		Type:  type_,
+	}
+}
+
+func (v *Int) String() string {
+	if isUnsigned(v.Type) {
+		return strconv.FormatUint(uint64(v.Value), 10)
+	}
+	return strconv.FormatInt(v.Value, 10)
+}
+
+// wrap truncates v to the width of the named integer type. Unsigned values
+// are zero extended; a u64 keeps its bit pattern.
+func wrap(v int64, type_ string) int64 {
+	switch type_ {
+	case "i8":
+		return int64(int8(v))
+	case "i16":
+		return int64(int16(v))
+	case "i32":
+		return int64(int32(v))
+	case "u8":
+		return int64(uint8(v))
+	case "u16":
+		return int64(uint16(v))
+	case "u32":
+		return int64(uint32(v))
+	default:
+		return v
+	}
+}
+
+func isInteger(type_ string) bool {
+	switch type_ {
+	case "i8", "i16", "i32", "i64", "u8", "u16", "u32", "u64":
+		return true
+	default:
+		return false
+	}
+}
+
+func isUnsigned(type_ string) bool {
+	switch type_ {
+	case "u8", "u16", "u32", "u64":
+		return true
+	default:
+		return false
+	}
+}
+
+type Float struct {
+	Value float64
+	Type  string
+}
+
+// NewFloat returns v as a value of the named float type, rounding it to
+// single precision for f32.
+func NewFloat(v float64, type_ string) *Float {
+	if type_ == "f32" {
+		v = float64(float32(v))
+	}
+	return &Float{
+		Value: v,
+		Type:  type_,
+	}
+}
+
+func (v *Float) String() string {
+	bits := 64
+	if v.Type == "f32" {
+		bits = 32
+	}
+	return strconv.FormatFloat(v.Value, 'g', -1, bits)
+}
+
+func isFloat(type_ string) bool {
+	return type_ == "f32" || type_ == "f64"
+}
+
+type Bool bool
+
+func (v Bool) String() string {
+	return strconv.FormatBool(bool(v))
+}
+
+type Str string
+
+func (v Str) String() string {
+	return string(v)
+}
+
+// Tuple holds the results of a call returning more than one value.
+type Tuple []Value
+
+func (v Tuple) String() string {
+	parts := make([]string, len(v))
+	for i, e := range v {
+		parts[i] = e.String()
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+// Struct is a value of a struct type, holding a value for each field in
+// declaration order. Structs are copied whenever they are stored, so no two
+// variables ever share fields.
+type Struct struct {
+	Decl   *syntax.TypeStmt
+	Fields []Value
+}
+
+func (v *Struct) String() string {
+	parts := make([]string, len(v.Fields))
+	for i, f := range v.Fields {
+		parts[i] = f.String()
+	}
+	return v.Decl.Name.Name + "{" + strings.Join(parts, ", ") + "}"
+}
+
+// Field returns the storage of the field called name, or nil.
+func (v *Struct) Field(name string) *Value {
+	if i := v.Decl.Type.Field(name); i >= 0 {
+		return &v.Fields[i]
+	}
+	return nil
+}
+
+// Array is a value of an array type. Like structs, arrays are copied
+// whenever they are stored.
+type Array struct {
+	Elems []Value
+}
+
+func (v *Array) String() string {
+	return elemsString(v.Elems)
+}
+
+// Slice is a value of a slice type. Its elements are shared with the array
+// or slice it was made from, and with those made from it, until append
+// moves them to a larger array.
+type Slice struct {
+	Elems []Value
+}
+
+func (v *Slice) String() string {
+	return elemsString(v.Elems)
+}
+
+func elemsString(elems []Value) string {
+	parts := make([]string, len(elems))
+	for i, e := range elems {
+		parts[i] = e.String()
+	}
+	return "[" + strings.Join(parts, " ") + "]"
+}
+
+// elements returns the elements of the array or slice v.
+func elements(v Value) ([]Value, bool) {
+	switch v := v.(type) {
+	case *Array:
+		return v.Elems, true
+	case *Slice:
+		return v.Elems, true
+	default:
+		return nil, false
+	}
+}
+
+// copyValue returns v, or a copy of it if it is a struct or an array.
+func copyValue(v Value) Value {
+	switch v := v.(type) {
+	case *Struct:
+		return &Struct{
+			Decl:   v.Decl,
+			Fields: copyValues(v.Fields),
+		}
+	case *Array:
+		return &Array{Elems: copyValues(v.Elems)}
+	default:
+		return v
+	}
+}
+
+func copyValues(values []Value) []Value {
+	copied := make([]Value, len(values))
+	for i, v := range values {
+		copied[i] = copyValue(v)
+	}
+	return copied
+}
+
+// store stores v in dst. An array, including one inside a struct, is
+// stored by copying the elements into the array dst already holds, so that
+// slices of that array see them.
+func store(dst *Value, v Value) {
+	switch d := (*dst).(type) {
+	case *Array:
+		if a, ok := v.(*Array); ok && len(a.Elems) == len(d.Elems) {
+			for i, e := range a.Elems {
+				store(&d.Elems[i], e)
+			}
+			return
+		}
+	case *Struct:
+		if s, ok := v.(*Struct); ok && s.Decl == d.Decl {
+			for i, f := range s.Fields {
+				store(&d.Fields[i], f)
+			}
+			return
+		}
+	}
+	*dst = copyValue(v)
+}
+
+type Func struct {
+	Stmt *syntax.FuncStmt
+}
+
+func (v *Func) String() string {
+	return "fn " + v.Stmt.Name.Name
+}
+
+// Builtin is a predeclared function such as len or print.
+type Builtin struct {
+	Name string
+}
+
+func (v *Builtin) String() string {
+	return "builtin " + v.Name
+}
+
+type Type struct {
+	Name string
+
+	// Struct is the declaration of a struct type, nil for a basic type.
+	Struct *syntax.TypeStmt
+}
+
+func (v *Type) String() string {
+	return v.Name
+}
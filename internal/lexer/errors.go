@@ -1,27 +1,17 @@
 package lexer
 
 import (
-	"fmt"
-
+	"github.com/danecwalker/hippo/internal/diag"
 	"github.com/danecwalker/hippo/internal/syntax"
 )
 
-type Error struct {
-	Pos syntax.Position
-	Msg string
-}
+// Error is a problem found while scanning a source file.
+type Error = diag.Diagnostic
 
 func NewError(pos *syntax.Position, msg string) *Error {
-	return &Error{
-		Pos: *pos,
-		Msg: msg,
-	}
+	return diag.NewError(diag.CodeLexical, pos, msg)
 }
 
 func NewEOFError(pos *syntax.Position) *Error {
-	return NewError(pos, "unexpected end of file")
-}
-
-func (e *Error) Error() string {
-	return fmt.Sprintf("%s:%d:%d:\n\t\033[31merror:\033[0m %s", e.Pos.Filename, e.Pos.Line, e.Pos.Column, e.Msg)
+	return NewError(pos, "unexpected end of file").WithCode(diag.CodeUnexpectedEOF)
 }
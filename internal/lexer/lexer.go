@@ -1,8 +1,12 @@
 package lexer
 
 import (
-	"io/ioutil"
+	"fmt"
+	"io"
 	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/danecwalker/hippo/internal/syntax"
 )
@@ -14,50 +18,116 @@ type Lexer struct {
 	Column   int
 	Filename string
 
-	input []byte
+	file   *syntax.SourceFile
+	input  []byte
+	errors []*Error
 }
 
-func NewLexer(filename string) *Lexer {
-	f, err := os.Open(filename)
+// NewLexer reads the file called filename and returns a lexer for it.
+func NewLexer(filename string) (*Lexer, error) {
+	src, err := os.ReadFile(filename)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
+	return NewLexerFromBytes(filename, src), nil
+}
 
-	defer f.Close()
-
-	input, err := ioutil.ReadAll(f)
+// NewLexerFromReader reads all of r and returns a lexer for it. name is used
+// in positions and error messages.
+func NewLexerFromReader(name string, r io.Reader) (*Lexer, error) {
+	src, err := io.ReadAll(r)
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+	return NewLexerFromBytes(name, src), nil
+}
+
+// NewLexerFromBytes returns a lexer for src. name is used in positions and
+// error messages.
+func NewLexerFromBytes(name string, src []byte) *Lexer {
+	return NewLexerFromSource(syntax.NewSourceFile(name, src))
+}
+
+func NewLexerFromSource(file *syntax.SourceFile) *Lexer {
+	offset := 0
+	if strings.HasPrefix(string(file.Src), bomString) {
+		// A byte order mark is allowed as the very first character only.
+		offset = len(bomString)
 	}
 
 	return &Lexer{
-		Offset:   0,
+		Offset:   offset,
 		Line:     1,
 		Column:   1,
-		Filename: filename,
-		input:    input,
+		Filename: file.Name,
+		file:     file,
+		input:    file.Src,
 	}
 }
 
+// File returns the source the lexer reads from.
+func (l *Lexer) File() *syntax.SourceFile {
+	return l.file
+}
+
+const bom = 0xFEFF
+
+var bomString = string(rune(bom))
+
+// Errors returns the malformed tokens found so far.
+func (l *Lexer) Errors() []*Error {
+	return l.errors
+}
+
+func (l *Lexer) error(pos *syntax.Position, msg string) {
+	l.errors = append(l.errors, NewError(pos, msg))
+}
+
 func (l *Lexer) Pos() *syntax.Position {
 	return syntax.NewPosition(l.Offset, l.Line, l.Column, l.Filename)
 }
 
-func (l *Lexer) Next() (byte, error) {
+// Next consumes and returns the character at the current offset. Columns
+// count characters rather than bytes. NUL, a byte order mark after the start
+// of the input and bytes that are not valid UTF-8 are reported as errors; an
+// invalid byte is consumed on its own and returned as utf8.RuneError.
+func (l *Lexer) Next() (rune, error) {
 	if l.Offset >= len(l.input) {
 		return 0, NewEOFError(l.Pos())
 	}
 
-	b := l.input[l.Offset]
-	l.Offset++
+	r, w := rune(l.input[l.Offset]), 1
+	switch {
+	case r == 0:
+		l.error(l.Pos(), "invalid character NUL")
+	case r >= utf8.RuneSelf:
+		r, w = utf8.DecodeRune(l.input[l.Offset:])
+		if r == utf8.RuneError && w == 1 {
+			l.error(l.Pos(), "invalid UTF-8 encoding")
+		} else if r == bom {
+			l.error(l.Pos(), "invalid BOM in the middle of the file")
+		}
+	}
+
+	l.Offset += w
 	l.Column++
 
-	if b == '\n' {
+	if r == '\n' {
 		l.Line++
 		l.Column = 1
 	}
 
-	return b, nil
+	return r, nil
+}
+
+// peekRune returns the character at the current offset without consuming
+// it.
+func (l *Lexer) peekRune() rune {
+	if l.Offset >= len(l.input) {
+		return -1
+	}
+	r, _ := utf8.DecodeRune(l.input[l.Offset:])
+	return r
 }
 
 func (l *Lexer) Peek() (byte, error) {
@@ -82,35 +152,31 @@ func (l *Lexer) NextToken() *syntax.Token {
 
 	switch b {
 	case '-':
-		p := l.Pos()
-		n, err := l.Peek()
-		if err != nil {
-			return syntax.NewToken(syntax.TokenEOF, "", l.Pos())
-		}
-		if n == '>' {
+		if n, _ := l.Peek(); n == '>' {
+			p := l.Pos()
 			l.Next()
 			l.Next()
 			return syntax.NewToken(syntax.TokenArrow, "->", p)
-		} else {
-			l.Next()
-			return syntax.NewToken(syntax.TokenMinus, "-", p)
 		}
+		return l.switch3(syntax.TokenMinus, '=', syntax.TokenMinusAssign, '-', syntax.TokenDec)
 	case '=':
-		p := l.Pos()
-		l.Next()
-		return syntax.NewToken(syntax.TokenAssign, "=", p)
+		return l.switch2(syntax.TokenAssign, '=', syntax.TokenEq)
+	case '!':
+		return l.switch2(syntax.TokenNot, '=', syntax.TokenNotEq)
 	case '+':
-		p := l.Pos()
-		l.Next()
-		return syntax.NewToken(syntax.TokenPlus, "+", p)
+		return l.switch3(syntax.TokenPlus, '=', syntax.TokenPlusAssign, '+', syntax.TokenInc)
 	case '*':
-		p := l.Pos()
-		l.Next()
-		return syntax.NewToken(syntax.TokenStar, "*", p)
+		return l.switch2(syntax.TokenStar, '=', syntax.TokenStarAssign)
+	case '^':
+		return l.switch2(syntax.TokenXor, '=', syntax.TokenXorAssign)
 	case '/':
-		p := l.Pos()
-		l.Next()
-		return syntax.NewToken(syntax.TokenSlash, "/", p)
+		switch n, _ := l.Peek(); n {
+		case '/':
+			return l.scanLineComment()
+		case '*':
+			return l.scanBlockComment()
+		}
+		return l.switch2(syntax.TokenSlash, '=', syntax.TokenSlashAssign)
 	case '<':
 		p := l.Pos()
 		n, err := l.Peek()
@@ -121,14 +187,26 @@ func (l *Lexer) NextToken() *syntax.Token {
 			l.Next()
 			l.Next()
 			return syntax.NewToken(syntax.TokenInfer, "<-", p)
-		} else {
-			l.Next()
-			return syntax.NewToken(syntax.TokenLt, "<", l.Pos())
 		}
+		return l.switch4(syntax.TokenLt, '=', syntax.TokenLtEq, '<', syntax.TokenShl, syntax.TokenShlAssign)
 	case '>':
-		p := l.Pos()
-		l.Next()
-		return syntax.NewToken(syntax.TokenGt, ">", p)
+		return l.switch4(syntax.TokenGt, '=', syntax.TokenGtEq, '>', syntax.TokenShr, syntax.TokenShrAssign)
+	case '&':
+		if n, _ := l.Peek(); n == '&' {
+			p := l.Pos()
+			l.Next()
+			l.Next()
+			return syntax.NewToken(syntax.TokenLAnd, "&&", p)
+		}
+		return l.switch4(syntax.TokenAnd, '=', syntax.TokenAndAssign, '^', syntax.TokenAndNot, syntax.TokenAndNotAssign)
+	case '|':
+		return l.switch3(syntax.TokenOr, '=', syntax.TokenOrAssign, '|', syntax.TokenLOr)
+	case '%':
+		return l.switch2(syntax.TokenPercent, '=', syntax.TokenPercentAssign)
+	case '"':
+		return l.scanString()
+	case '`':
+		return l.scanRawString()
 	case ',':
 		p := l.Pos()
 		l.Next()
@@ -139,6 +217,9 @@ func (l *Lexer) NextToken() *syntax.Token {
 		if err != nil {
 			return syntax.NewToken(syntax.TokenEOF, "", l.Pos())
 		}
+		if isDigit(n) {
+			return l.scanNumber()
+		}
 		if n == '.' {
 			l.Next()
 			l.Next()
@@ -171,15 +252,176 @@ func (l *Lexer) NextToken() *syntax.Token {
 		p := l.Pos()
 		l.Next()
 		return syntax.NewToken(syntax.TokenRBrace, "}", p)
+	case '[':
+		p := l.Pos()
+		l.Next()
+		return syntax.NewToken(syntax.TokenLBrack, "[", p)
+	case ']':
+		p := l.Pos()
+		l.Next()
+		return syntax.NewToken(syntax.TokenRBrack, "]", p)
 	default:
-		if isLetter(b) {
+		if isLetter(l.peekRune()) {
 			return l.scanIdentifier()
 		} else if isDigit(b) {
 			return l.scanNumber()
 		}
+		p := l.Pos()
+		start := l.Offset
+		l.Next()
+		return syntax.NewToken(syntax.TokenIllegal, string(l.input[start:l.Offset]), p)
+	}
+}
+
+// switch2 scans a one byte operator, or the two byte operator tok2 when the
+// following byte is next.
+func (l *Lexer) switch2(tok1 syntax.TokenType, next byte, tok2 syntax.TokenType) *syntax.Token {
+	p := l.Pos()
+	b, _ := l.Next()
+
+	if n, err := l.PeekN(0); err == nil && n == next {
+		l.Next()
+		return syntax.NewToken(tok2, string(b)+string(n), p)
+	}
+
+	return syntax.NewToken(tok1, string(b), p)
+}
+
+// switch3 is like switch2 but also returns tok3 if the byte after the
+// current one is next3.
+func (l *Lexer) switch3(tok1 syntax.TokenType, next2 byte, tok2 syntax.TokenType, next3 byte, tok3 syntax.TokenType) *syntax.Token {
+	if n, err := l.Peek(); err == nil && n == next3 {
+		p := l.Pos()
+		b, _ := l.Next()
+		l.Next()
+		return syntax.NewToken(tok3, string(b)+string(n), p)
+	}
+
+	return l.switch2(tok1, next2, tok2)
+}
+
+// switch4 is like switch2 but also returns tok3 if the byte after the
+// current one is next3, and tok4 if that is followed by '='.
+func (l *Lexer) switch4(tok1 syntax.TokenType, next2 byte, tok2 syntax.TokenType, next3 byte, tok3, tok4 syntax.TokenType) *syntax.Token {
+	if n, err := l.Peek(); err == nil && n == next3 {
+		p := l.Pos()
+		b, _ := l.Next()
+		tok := l.switch2(tok3, '=', tok4)
+		tok.Literal, tok.Position = string(b)+tok.Literal, p
+		return tok
+	}
+
+	return l.switch2(tok1, next2, tok2)
+}
+
+// scanString scans an interpreted string literal. The literal keeps its
+// quotes and escape sequences; syntax.StringValue decodes it.
+func (l *Lexer) scanString() *syntax.Token {
+	pos := l.Pos()
+	start := l.Offset
+	l.Next()
+
+	for {
+		b, err := l.PeekN(0)
+		if err != nil || b == '\n' {
+			l.error(pos, "string literal not terminated")
+			break
+		}
+		l.Next()
+
+		if b == '"' {
+			break
+		}
+
+		if b == '\\' {
+			esc := l.Pos()
+			_, n, msg := syntax.Unescape(string(l.input[l.Offset:l.lineEnd()]))
+			if msg != "" {
+				l.error(syntax.NewPosition(esc.Offset-1, esc.Line, esc.Column-1, esc.Filename), msg)
+			}
+			for i := 0; i < n; i++ {
+				l.Next()
+			}
+		}
+	}
+
+	return syntax.NewToken(syntax.TokenString, string(l.input[start:l.Offset]), pos)
+}
+
+// lineEnd returns the offset of the end of the current line.
+func (l *Lexer) lineEnd() int {
+	end := l.Offset
+	for end < len(l.input) && l.input[end] != '\n' {
+		end++
+	}
+	return end
+}
+
+// scanRawString scans a `raw` string literal, which may span lines.
+func (l *Lexer) scanRawString() *syntax.Token {
+	pos := l.Pos()
+	start := l.Offset
+	l.Next()
+
+	for {
+		b, err := l.Next()
+		if err != nil {
+			l.error(pos, "raw string literal not terminated")
+			break
+		}
+		if b == '`' {
+			break
+		}
+	}
+
+	return syntax.NewToken(syntax.TokenString, string(l.input[start:l.Offset]), pos)
+}
+
+// scanLineComment scans a // comment up to, but not including, the end of
+// the line.
+func (l *Lexer) scanLineComment() *syntax.Token {
+	pos := l.Pos()
+	start := l.Offset
+
+	for {
+		b, err := l.PeekN(0)
+		if err != nil || b == '\n' {
+			break
+		}
 		l.Next()
-		return syntax.NewToken(syntax.TokenIllegal, string(b), l.Pos())
 	}
+
+	return syntax.NewToken(syntax.TokenComment, strings.TrimSuffix(string(l.input[start:l.Offset]), "\r"), pos)
+}
+
+// scanBlockComment scans a /* */ comment. Block comments nest, so every /*
+// inside the comment needs its own */.
+func (l *Lexer) scanBlockComment() *syntax.Token {
+	pos := l.Pos()
+	start := l.Offset
+	l.Next()
+	l.Next()
+
+	depth := 1
+	for depth > 0 {
+		b, err := l.Next()
+		if err != nil {
+			l.error(pos, "comment not terminated")
+			break
+		}
+
+		n, _ := l.PeekN(0)
+		switch {
+		case b == '/' && n == '*':
+			l.Next()
+			depth++
+		case b == '*' && n == '/':
+			l.Next()
+			depth--
+		}
+	}
+
+	return syntax.NewToken(syntax.TokenComment, string(l.input[start:l.Offset]), pos)
 }
 
 func (l *Lexer) skip() {
@@ -201,62 +443,191 @@ func isDigit(b byte) bool {
 	return '0' <= b && b <= '9'
 }
 
+var baseNames = map[int]string{
+	2:  "binary",
+	8:  "octal",
+	10: "decimal",
+	16: "hexadecimal",
+}
+
+// scanNumber scans an integer or floating-point literal. Integers are
+// decimal, or binary, octal or hexadecimal after a 0b, 0o or 0x prefix.
+// Floats have a fraction, an exponent or both; a hexadecimal float needs a p
+// exponent. An underscore may appear after the prefix or between successive
+// digits. Malformed literals are reported as errors but still produce a
+// token so parsing can go on.
 func (l *Lexer) scanNumber() *syntax.Token {
 	pos := l.Pos()
+	start := l.Offset
+	tok := syntax.TokenInt
+
+	base, prefix := 10, byte(0)
+	if b, _ := l.PeekN(0); b == '0' {
+		if n, err := l.Peek(); err == nil {
+			switch n | 0x20 { // lower case
+			case 'b':
+				base, prefix = 2, 'b'
+			case 'o':
+				base, prefix = 8, 'o'
+			case 'x':
+				base, prefix = 16, 'x'
+			}
+		}
+	}
 
-	b, err := l.PeekN(0)
-	if err != nil {
-		return syntax.NewToken(syntax.TokenEOF, "", l.Pos())
+	if prefix != 0 {
+		l.Next()
+		l.Next()
 	}
 
-	literal := string(b)
-	l.Next()
+	invalid := false
+	digits := l.scanDigits(base, &invalid)
+
+	// A '.' followed by another is the range operator, not a radix point.
+	if b, _ := l.PeekN(0); b == '.' {
+		if n, err := l.Peek(); err != nil || n != '.' {
+			tok = syntax.TokenFloat
+			if prefix == 'b' || prefix == 'o' {
+				l.error(l.Pos(), "invalid radix point in "+baseNames[base]+" literal")
+			}
+			l.Next()
+			digits += l.scanDigits(base, &invalid)
+		}
+	}
+
+	if digits == 0 {
+		l.error(pos, baseNames[base]+" literal has no digits")
+	}
+
+	if e, _ := l.PeekN(0); e|0x20 == 'e' || e|0x20 == 'p' {
+		switch {
+		case e|0x20 == 'e' && prefix != 0:
+			l.error(l.Pos(), fmt.Sprintf("%q exponent requires decimal mantissa", e))
+		case e|0x20 == 'p' && prefix != 'x':
+			l.error(l.Pos(), fmt.Sprintf("%q exponent requires hexadecimal mantissa", e))
+		}
+		tok = syntax.TokenFloat
+		l.Next()
+
+		if s, _ := l.PeekN(0); s == '+' || s == '-' {
+			l.Next()
+		}
+		if l.scanDigits(10, &invalid) == 0 {
+			l.error(pos, "exponent has no digits")
+		}
+	} else if prefix == 'x' && tok == syntax.TokenFloat {
+		l.error(pos, "hexadecimal mantissa requires a 'p' exponent")
+	}
+
+	lit := string(l.input[start:l.Offset])
+	if invalidSep(lit) {
+		l.error(pos, "'_' must separate successive digits")
+	}
+
+	return syntax.NewToken(tok, lit, pos)
+}
 
+// scanDigits consumes digits and underscores, stopping at the first byte
+// that is not a hexadecimal digit, or not a decimal digit when base is not
+// 16. The first digit too large for base is reported, unless invalid shows
+// one already was. It returns the number of digits consumed.
+func (l *Lexer) scanDigits(base int, invalid *bool) int {
+	digits := 0
 	for {
 		b, err := l.PeekN(0)
 		if err != nil {
-			break
+			return digits
 		}
 
-		if isDigit(b) {
-			literal += string(b)
-			l.Next()
-		} else {
-			break
+		if b != '_' {
+			d := digitVal(b)
+			if d >= 16 || d >= 10 && base != 16 {
+				return digits
+			}
+			if d >= base && !*invalid {
+				*invalid = true
+				l.error(l.Pos(), fmt.Sprintf("invalid digit %q in %s literal", b, baseNames[base]))
+			}
+			digits++
+		}
+		l.Next()
+	}
+}
+
+// invalidSep reports whether an underscore in the number literal lit does
+// not sit between two digits, or between the base prefix and a digit.
+func invalidSep(lit string) bool {
+	hex := false
+	prev := byte('.') // '0' for a digit, '_' or '.' for anything else
+	i := 0
+	if len(lit) >= 2 && lit[0] == '0' {
+		switch lit[1] | 0x20 {
+		case 'x':
+			hex = true
+			fallthrough
+		case 'b', 'o':
+			prev = '0'
+			i = 2
 		}
 	}
 
-	return syntax.NewToken(syntax.TokenInt, literal, pos)
+	for ; i < len(lit); i++ {
+		b := lit[i]
+		switch {
+		case b == '_':
+			if prev != '0' {
+				return true
+			}
+			prev = '_'
+		case isDigit(b) || hex && digitVal(b) < 16:
+			prev = '0'
+		default:
+			if prev == '_' {
+				return true
+			}
+			prev = '.'
+		}
+	}
+
+	return prev == '_'
+}
+
+func digitVal(b byte) int {
+	switch {
+	case '0' <= b && b <= '9':
+		return int(b - '0')
+	case 'a' <= b|0x20 && b|0x20 <= 'f':
+		return int(b|0x20-'a') + 10
+	default:
+		return 16
+	}
+}
+
+// isLetter reports whether r may start an identifier: an underscore or a
+// Unicode letter.
+func isLetter(r rune) bool {
+	return 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || r == '_' || r >= utf8.RuneSelf && unicode.IsLetter(r)
 }
 
-func isLetter(b byte) bool {
-	return 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z' || b == '_'
+// isIdentDigit reports whether r is a Unicode decimal digit, which may
+// appear in an identifier after its first letter.
+func isIdentDigit(r rune) bool {
+	return '0' <= r && r <= '9' || r >= utf8.RuneSelf && unicode.IsDigit(r)
 }
 
 func (l *Lexer) scanIdentifier() *syntax.Token {
 	pos := l.Pos()
+	start := l.Offset
 
-	b, err := l.PeekN(0)
-	if err != nil {
-		return syntax.NewToken(syntax.TokenEOF, "", l.Pos())
-	}
-
-	literal := string(b)
 	l.Next()
-
 	for {
-		b, err := l.PeekN(0)
-		if err != nil {
-			break
-		}
-
-		if isLetter(b) || isDigit(b) {
-			literal += string(b)
-			l.Next()
-		} else {
+		r := l.peekRune()
+		if !isLetter(r) && !isIdentDigit(r) {
 			break
 		}
+		l.Next()
 	}
 
+	literal := string(l.input[start:l.Offset])
 	return syntax.NewToken(syntax.LookupIdent(literal), literal, pos)
 }
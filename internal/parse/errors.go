@@ -3,45 +3,68 @@ package parse
 import (
 	"fmt"
 
+	"github.com/danecwalker/hippo/internal/diag"
 	"github.com/danecwalker/hippo/internal/syntax"
 )
 
-type Error struct {
-	Pos syntax.Position
-	Msg string
-}
+// Error is a problem found while parsing a program.
+type Error = diag.Diagnostic
 
 func NewError(pos *syntax.Position, msg string) *Error {
-	return &Error{
-		Pos: *pos,
-		Msg: msg,
-	}
-}
-
-func (e *Error) Error() string {
-	return fmt.Sprintf("%s:%d:%d:\n\t\033[31merror:\033[0m %s", e.Pos.Filename, e.Pos.Line, e.Pos.Column, e.Msg)
+	return diag.NewError(diag.CodeSyntax, pos, msg)
 }
 
 func NewEOFError(pos *syntax.Position) *Error {
-	return NewError(pos, "unexpected end of file")
+	return NewError(pos, "unexpected end of file").WithCode(diag.CodeUnexpectedEOF)
 }
 
 func NewPeekError(pos *syntax.Position, expected, actual string) *Error {
 	msg := "expected %s, got %s"
-	return NewError(pos, fmt.Sprintf(msg, expected, actual))
+	return NewError(pos, fmt.Sprintf(msg, expected, actual)).WithCode(diag.CodeExpectedToken)
 }
 
 func NewUnexpectedTokenError(pos *syntax.Position, expected *syntax.Token) *Error {
 	msg := "unexpected token %s"
-	return NewError(pos, fmt.Sprintf(msg, expected))
+	return NewError(pos, fmt.Sprintf(msg, expected)).WithCode(diag.CodeUnexpectedToken).WithEnd(expected.End())
 }
 
-func NewRedeclaredError(pos *syntax.Position, name string) *Error {
-	msg := "redeclared %s"
-	return NewError(pos, fmt.Sprintf(msg, name))
+// NewRedeclaredError reports name declared again at pos, pointing back at
+// prev, the object it already denotes.
+func NewRedeclaredError(pos *syntax.Position, name string, prev *syntax.Object) *Error {
+	msg := "%s redeclared in this block"
+	err := NewError(pos, fmt.Sprintf(msg, name)).WithCode(diag.CodeRedeclared).WithEnd(pos.After(name))
+	if ident := prev.Ident(); ident != nil {
+		err.WithLabel(ident.Pos(), ident.End(), "previously declared here")
+	}
+	return err
 }
 
 func NewInvalidRangeError(pos *syntax.Position) *Error {
 	msg := "invalid range"
-	return NewError(pos, fmt.Sprintf(msg))
+	return NewError(pos, fmt.Sprintf(msg)).WithCode(diag.CodeInvalidRange)
+}
+
+func NewCallNameError(expr syntax.Expression) *Error {
+	return NewError(expr.Pos(), "call requires a function name").WithCode(diag.CodeCallName).WithEnd(expr.End())
+}
+
+func NewMisplacedBranchError(pos *syntax.Position, tok syntax.TokenType) *Error {
+	msg := "%s is not in a loop"
+	return NewError(pos, fmt.Sprintf(msg, tok)).WithCode(diag.CodeMisplacedBranch).WithEnd(pos.After(tok.String()))
+}
+
+func NewUndefinedLabelError(label *syntax.Identifier, tok syntax.TokenType) *Error {
+	msg := "%s label not defined: %s"
+	return NewError(label.Pos(), fmt.Sprintf(msg, tok, label.Name)).WithCode(diag.CodeUndefinedLabel).WithEnd(label.End())
+}
+
+func NewMisplacedLabelError(label *syntax.Identifier) *Error {
+	msg := "label %s must be followed by a for statement"
+	return NewError(label.Pos(), fmt.Sprintf(msg, label.Name)).WithCode(diag.CodeMisplacedLabel).WithEnd(label.End())
+}
+
+func NewRedeclaredLabelError(label *syntax.Identifier, prev *syntax.Identifier) *Error {
+	msg := "label %s already defined"
+	return NewError(label.Pos(), fmt.Sprintf(msg, label.Name)).WithCode(diag.CodeRedeclared).WithEnd(label.End()).
+		WithLabel(prev.Pos(), prev.End(), "previously defined here")
 }
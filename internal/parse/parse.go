@@ -2,8 +2,9 @@ package parse
 
 import (
 	"fmt"
-	"os"
+	"io"
 
+	"github.com/danecwalker/hippo/internal/diag"
 	"github.com/danecwalker/hippo/internal/lexer"
 	"github.com/danecwalker/hippo/internal/syntax"
 )
@@ -19,19 +20,26 @@ type Prec int
 const (
 	_ Prec = iota
 	LOWEST
-	EQUALS      // ==
+	ASSIGN      // =
+	LOGOR       // ||
+	LOGAND      // &&
+	EQUALS      // == or !=
 	LESSGREATER // > or <
-	SUM         // +
-	PRODUCT     // *
+	SUM         // + - | ^
+	PRODUCT     // * / % << >> & &^
 	PREFIX      // -X or !X
 	CALL        // myFunction(X)
 	INDEX       // array[index]
 )
 
 type Parser struct {
+	prev_token *syntax.Token
 	cur_token  *syntax.Token
 	peek_token *syntax.Token
 
+	// pending holds tokens given back by backup, the next one last.
+	pending []*syntax.Token
+
 	lex *lexer.Lexer
 
 	errors []*Error
@@ -44,6 +52,27 @@ type Parser struct {
 	stmtParseFns map[syntax.TokenType]stmtParseFn
 
 	scope *Scope
+
+	// lexErrors counts the lexer errors already copied into errors.
+	lexErrors int
+
+	// recovering is set from a syntax error until the parser has skipped
+	// to the next statement, and silences the errors in between, which
+	// would only be knock-on effects of the first.
+	recovering bool
+
+	// loops holds the labels of the loops enclosing the statement being
+	// parsed, innermost last, with nil for a loop without one. label is
+	// the label of the loop about to be parsed.
+	loops []*syntax.Identifier
+	label *syntax.Identifier
+
+	// noCompositeLit is set while parsing the header of an if or for
+	// statement, where a { after a name opens the body rather than a
+	// composite literal. Parentheses and blocks clear it.
+	noCompositeLit bool
+
+	comments []*syntax.Comment
 }
 
 func (p *Parser) registerPrefix(token_type syntax.TokenType, fn prefixParseFn) {
@@ -58,27 +87,89 @@ func (p *Parser) registerStmt(token_type syntax.TokenType, fn stmtParseFn) {
 	p.stmtParseFns[token_type] = fn
 }
 
-func NewParser(filename string) *Parser {
-	lex := lexer.NewLexer(filename)
+// NewParser reads the file called filename and returns a parser for it.
+func NewParser(filename string) (*Parser, error) {
+	lex, err := lexer.NewLexer(filename)
+	if err != nil {
+		return nil, err
+	}
+	return newParser(lex), nil
+}
+
+// NewParserFromReader reads all of r and returns a parser for it. name is
+// used in positions and error messages.
+func NewParserFromReader(name string, r io.Reader) (*Parser, error) {
+	lex, err := lexer.NewLexerFromReader(name, r)
+	if err != nil {
+		return nil, err
+	}
+	return newParser(lex), nil
+}
+
+// NewParserFromBytes returns a parser for src. name is used in positions and
+// error messages.
+func NewParserFromBytes(name string, src []byte) *Parser {
+	return newParser(lexer.NewLexerFromBytes(name, src))
+}
+
+func NewParserFromSource(file *syntax.SourceFile) *Parser {
+	return newParser(lexer.NewLexerFromSource(file))
+}
+
+func newParser(lex *lexer.Lexer) *Parser {
 	p := &Parser{
 		lex:   lex,
-		scope: NewScope(nil),
+		scope: NewScope(Universe),
 	}
 
 	p.prefixParseFns = make(map[syntax.TokenType]prefixParseFn)
 	p.registerPrefix(syntax.TokenIdent, p.parseIdentifier)
 	p.registerPrefix(syntax.TokenInt, p.parseIntegerLiteral)
+	p.registerPrefix(syntax.TokenFloat, p.parseFloatLiteral)
+	p.registerPrefix(syntax.TokenString, p.parseStringLiteral)
+	p.registerPrefix(syntax.TokenNot, p.parsePrefixExpression)
+	p.registerPrefix(syntax.TokenMinus, p.parsePrefixExpression)
+	p.registerPrefix(syntax.TokenXor, p.parsePrefixExpression)
+	p.registerPrefix(syntax.TokenLParen, p.parseParenExpression)
+	p.registerPrefix(syntax.TokenLBrack, p.parseArrayLiteral)
 
 	p.infixParseFns = make(map[syntax.TokenType]infixParseFn)
 	p.registerInfix(syntax.TokenPlus, p.parseInfixExpression)
 	p.registerInfix(syntax.TokenMinus, p.parseInfixExpression)
 	p.registerInfix(syntax.TokenStar, p.parseInfixExpression)
 	p.registerInfix(syntax.TokenSlash, p.parseInfixExpression)
+	p.registerInfix(syntax.TokenPercent, p.parseInfixExpression)
+	p.registerInfix(syntax.TokenAnd, p.parseInfixExpression)
+	p.registerInfix(syntax.TokenOr, p.parseInfixExpression)
+	p.registerInfix(syntax.TokenXor, p.parseInfixExpression)
+	p.registerInfix(syntax.TokenShl, p.parseInfixExpression)
+	p.registerInfix(syntax.TokenShr, p.parseInfixExpression)
+	p.registerInfix(syntax.TokenAndNot, p.parseInfixExpression)
 	p.registerInfix(syntax.TokenGt, p.parseInfixExpression)
 	p.registerInfix(syntax.TokenLt, p.parseInfixExpression)
+	p.registerInfix(syntax.TokenGtEq, p.parseInfixExpression)
+	p.registerInfix(syntax.TokenLtEq, p.parseInfixExpression)
+	p.registerInfix(syntax.TokenEq, p.parseInfixExpression)
+	p.registerInfix(syntax.TokenNotEq, p.parseInfixExpression)
+	p.registerInfix(syntax.TokenLAnd, p.parseInfixExpression)
+	p.registerInfix(syntax.TokenLOr, p.parseInfixExpression)
 	p.registerInfix(syntax.TokenLParen, p.parseCallExpression)
+	p.registerInfix(syntax.TokenDot, p.parseSelectorExpression)
+	p.registerInfix(syntax.TokenLBrace, p.parseCompositeLiteral)
+	p.registerInfix(syntax.TokenLBrack, p.parseIndexExpression)
 	p.registerInfix(syntax.TokenRange, p.parseRangeExpression)
 	p.registerInfix(syntax.TokenAssign, p.parseAssignExpression)
+	p.registerInfix(syntax.TokenPlusAssign, p.parseAssignExpression)
+	p.registerInfix(syntax.TokenMinusAssign, p.parseAssignExpression)
+	p.registerInfix(syntax.TokenStarAssign, p.parseAssignExpression)
+	p.registerInfix(syntax.TokenSlashAssign, p.parseAssignExpression)
+	p.registerInfix(syntax.TokenPercentAssign, p.parseAssignExpression)
+	p.registerInfix(syntax.TokenAndAssign, p.parseAssignExpression)
+	p.registerInfix(syntax.TokenOrAssign, p.parseAssignExpression)
+	p.registerInfix(syntax.TokenXorAssign, p.parseAssignExpression)
+	p.registerInfix(syntax.TokenShlAssign, p.parseAssignExpression)
+	p.registerInfix(syntax.TokenShrAssign, p.parseAssignExpression)
+	p.registerInfix(syntax.TokenAndNotAssign, p.parseAssignExpression)
 
 	p.stmtParseFns = make(map[syntax.TokenType]stmtParseFn)
 	p.registerStmt(syntax.TokenVar, p.parseVarStatement)
@@ -88,16 +179,46 @@ func NewParser(filename string) *Parser {
 	p.registerStmt(syntax.TokenFor, p.parseForStatement)
 	p.registerStmt(syntax.TokenIdent, p.parseExpressionStatement)
 	p.registerStmt(syntax.TokenIf, p.parseIfStatement)
+	p.registerStmt(syntax.TokenBreak, p.parseBranchStatement)
+	p.registerStmt(syntax.TokenContinue, p.parseBranchStatement)
+	p.registerStmt(syntax.TokenType_, p.parseTypeStatement)
 
 	p.precedences = make(map[syntax.TokenType]Prec)
-	p.precedences[syntax.TokenAssign] = EQUALS
+	p.precedences[syntax.TokenAssign] = ASSIGN
+	p.precedences[syntax.TokenPlusAssign] = ASSIGN
+	p.precedences[syntax.TokenMinusAssign] = ASSIGN
+	p.precedences[syntax.TokenStarAssign] = ASSIGN
+	p.precedences[syntax.TokenSlashAssign] = ASSIGN
+	p.precedences[syntax.TokenPercentAssign] = ASSIGN
+	p.precedences[syntax.TokenAndAssign] = ASSIGN
+	p.precedences[syntax.TokenOrAssign] = ASSIGN
+	p.precedences[syntax.TokenXorAssign] = ASSIGN
+	p.precedences[syntax.TokenShlAssign] = ASSIGN
+	p.precedences[syntax.TokenShrAssign] = ASSIGN
+	p.precedences[syntax.TokenAndNotAssign] = ASSIGN
+	p.precedences[syntax.TokenLOr] = LOGOR
+	p.precedences[syntax.TokenLAnd] = LOGAND
+	p.precedences[syntax.TokenEq] = EQUALS
+	p.precedences[syntax.TokenNotEq] = EQUALS
 	p.precedences[syntax.TokenPlus] = SUM
 	p.precedences[syntax.TokenMinus] = SUM
+	p.precedences[syntax.TokenOr] = SUM
+	p.precedences[syntax.TokenXor] = SUM
 	p.precedences[syntax.TokenSlash] = PRODUCT
 	p.precedences[syntax.TokenStar] = PRODUCT
+	p.precedences[syntax.TokenPercent] = PRODUCT
+	p.precedences[syntax.TokenAnd] = PRODUCT
+	p.precedences[syntax.TokenAndNot] = PRODUCT
+	p.precedences[syntax.TokenShl] = PRODUCT
+	p.precedences[syntax.TokenShr] = PRODUCT
 	p.precedences[syntax.TokenLt] = LESSGREATER
 	p.precedences[syntax.TokenGt] = LESSGREATER
+	p.precedences[syntax.TokenLtEq] = LESSGREATER
+	p.precedences[syntax.TokenGtEq] = LESSGREATER
 	p.precedences[syntax.TokenLParen] = CALL
+	p.precedences[syntax.TokenDot] = CALL
+	p.precedences[syntax.TokenLBrace] = CALL
+	p.precedences[syntax.TokenLBrack] = INDEX
 	p.precedences[syntax.TokenRange] = INDEX
 
 	p.nextToken()
@@ -106,13 +227,61 @@ func NewParser(filename string) *Parser {
 	return p
 }
 
+// File returns the source being parsed.
+func (p *Parser) File() *syntax.SourceFile {
+	return p.lex.File()
+}
+
 func (p *Parser) Errors() []*Error {
 	return p.errors
 }
 
 func (p *Parser) nextToken() {
+	p.prev_token = p.cur_token
 	p.cur_token = p.peek_token
+	if n := len(p.pending); n > 0 {
+		p.peek_token = p.pending[n-1]
+		p.pending = p.pending[:n-1]
+		return
+	}
+
 	p.peek_token = p.lex.NextToken()
+	for p.peek_token.IsComment() {
+		p.comments = append(p.comments, syntax.NewComment(p.peek_token.Position, p.peek_token.Literal))
+		p.peek_token = p.lex.NextToken()
+	}
+
+	errs := p.lex.Errors()
+	p.errors = append(p.errors, errs[p.lexErrors:]...)
+	p.lexErrors = len(errs)
+}
+
+// backup undoes the last nextToken.
+func (p *Parser) backup() {
+	p.pending = append(p.pending, p.peek_token)
+	p.peek_token = p.cur_token
+	p.cur_token = p.prev_token
+	p.prev_token = nil
+}
+
+// error records the syntax error err and starts recovery, unless the parser
+// is already recovering from an earlier one.
+func (p *Parser) error(err *Error) {
+	if p.recovering {
+		return
+	}
+	p.errors = append(p.errors, err)
+	p.recovering = true
+}
+
+// errorExpected reports that tok was found where a token of type want was
+// required.
+func (p *Parser) errorExpected(tok *syntax.Token, want syntax.TokenType) {
+	err := NewPeekError(tok.Position, want.String(), tok.Type.String()).WithEnd(tok.End())
+	if text, ok := delimiters[want]; ok {
+		err.WithFix(fmt.Sprintf("insert %q", text), diag.NewEdit(tok.Position, tok.Position, text))
+	}
+	p.error(err)
 }
 
 func (p *Parser) peekTokenIs(token_type syntax.TokenType) bool {
@@ -123,12 +292,27 @@ func (p *Parser) curTokenIs(token_type syntax.TokenType) bool {
 	return p.cur_token.Type == token_type
 }
 
+// delimiters holds the text of the tokens a missing one of which can be
+// fixed by inserting it.
+var delimiters = map[syntax.TokenType]string{
+	syntax.TokenAssign:    "=",
+	syntax.TokenArrow:     "->",
+	syntax.TokenColon:     ":",
+	syntax.TokenComma:     ",",
+	syntax.TokenSemicolon: ";",
+	syntax.TokenLParen:    "(",
+	syntax.TokenRParen:    ")",
+	syntax.TokenLBrace:    "{",
+	syntax.TokenRBrace:    "}",
+	syntax.TokenRBrack:    "]",
+}
+
 func (p *Parser) expectPeek(token_type syntax.TokenType) bool {
 	if p.peek_token.Type == token_type {
 		p.nextToken()
 		return true
 	} else {
-		NewPeekError(p.cur_token.Position, token_type.String(), p.peek_token.Type.String())
+		p.errorExpected(p.peek_token, token_type)
 		return false
 	}
 }
@@ -143,8 +327,8 @@ func (p *Parser) acceptPeek(token_type syntax.TokenType) bool {
 }
 
 func (p *Parser) DiscardScope() {
-	if p.scope.Parent == nil {
-		p.errors = append(p.errors, NewError(nil, "cannot discard global scope"))
+	if p.scope.Parent == nil || p.scope.Parent == Universe {
+		p.errors = append(p.errors, NewError(p.cur_token.Position, "cannot discard global scope"))
 		return
 	}
 	p.scope = p.scope.Parent
@@ -158,13 +342,80 @@ func (p *Parser) parseStatement() syntax.Statement {
 	if fn := p.stmtParseFns[p.cur_token.Type]; fn != nil {
 		return fn()
 	} else {
-		p.errors = append(p.errors, NewUnexpectedTokenError(p.cur_token.Position, p.cur_token))
+		p.error(NewUnexpectedTokenError(p.cur_token.Position, p.cur_token))
 	}
 
 	return nil
 }
 
+// parseListedStatement parses a statement of the program or of a block. If
+// the statement has a syntax error, it skips to the start of the next one
+// and returns a BadStmt covering the skipped text instead.
+func (p *Parser) parseListedStatement() syntax.Statement {
+	start := p.cur_token
+	scope := p.scope
+
+	stmt := p.parseStatement()
+	if !p.recovering {
+		return stmt
+	}
+
+	p.sync(start)
+	p.scope = scope
+	return syntax.NewBadStmt(start.Position, p.cur_token.End())
+}
+
+// sync ends recovery from a syntax error in the statement starting at
+// start. It leaves the parser on the last token before the next statement
+// keyword or closing brace that is not inside braces skipped on the way.
+func (p *Parser) sync(start *syntax.Token) {
+	p.recovering = false
+
+	// The error may have been found at a token that begins the next
+	// statement or ends the block; leave it for the caller to see.
+	if p.cur_token != start && p.prev_token != nil && syncsTo(p.cur_token.Type) {
+		p.backup()
+		return
+	}
+
+	depth := 0
+	if p.curTokenIs(syntax.TokenLBrace) {
+		depth++
+	}
+	for !p.peekTokenIs(syntax.TokenEOF) {
+		switch p.peek_token.Type {
+		case syntax.TokenLBrace:
+			depth++
+		case syntax.TokenRBrace:
+			if depth == 0 {
+				return
+			}
+			depth--
+		default:
+			if depth == 0 && syncsTo(p.peek_token.Type) {
+				return
+			}
+		}
+		p.nextToken()
+	}
+}
+
+// syncsTo reports whether recovery stops before a token of type tt.
+func syncsTo(tt syntax.TokenType) bool {
+	switch tt {
+	case syntax.TokenFunc, syntax.TokenVar, syntax.TokenConst, syntax.TokenReturn,
+		syntax.TokenFor, syntax.TokenIf, syntax.TokenBreak, syntax.TokenContinue,
+		syntax.TokenType_, syntax.TokenRBrace:
+		return true
+	default:
+		return false
+	}
+}
+
 func (p *Parser) peekPrecedence() Prec {
+	if p.noCompositeLit && p.peekTokenIs(syntax.TokenLBrace) {
+		return LOWEST
+	}
 	if precedence, ok := p.precedences[p.peek_token.Type]; ok {
 		return precedence
 	}
@@ -189,36 +440,30 @@ func (p *Parser) parseExpression(precedence Prec) syntax.Expression {
 				p.nextToken()
 				left_exp = fn(left_exp)
 			} else {
-				p.errors = append(p.errors, NewUnexpectedTokenError(p.cur_token.Position, p.cur_token))
+				p.error(NewUnexpectedTokenError(p.peek_token.Position, p.peek_token))
 				return left_exp
 			}
 		}
 
 		return left_exp
 	} else {
-		p.errors = append(p.errors, NewUnexpectedTokenError(p.cur_token.Position, p.cur_token))
+		p.error(NewUnexpectedTokenError(p.cur_token.Position, p.cur_token))
 	}
 
-	return nil
+	return syntax.NewBadExpr(p.cur_token.Position, p.cur_token.End())
 }
 
 func (p *Parser) ParseProgram() *syntax.Program {
 	program := syntax.NewProgram()
 
 	for p.cur_token.Type != syntax.TokenEOF {
-		stmt := p.parseStatement()
+		stmt := p.parseListedStatement()
 		if stmt != nil {
 			program.AddStatement(stmt)
 		}
 		p.nextToken()
 	}
-
-	if len(p.errors) > 0 {
-		for _, err := range p.errors {
-			fmt.Fprintln(os.Stderr, err)
-		}
-		os.Exit(1)
-	}
+	program.Comments = p.comments
 
 	return program
 }
@@ -234,8 +479,9 @@ func (p *Parser) parseVarStatement() syntax.Statement {
 	names := p.parseIdentifierList()
 
 	var type_ *syntax.Identifier
-	if p.acceptPeek(syntax.TokenIdent) {
-		type_ = p.parseIdentifier().(*syntax.Identifier)
+	if p.peekTokenIs(syntax.TokenIdent) || p.peekTokenIs(syntax.TokenLBrack) {
+		p.nextToken()
+		type_ = p.parseType()
 	}
 
 	var values []syntax.Expression
@@ -244,11 +490,11 @@ func (p *Parser) parseVarStatement() syntax.Statement {
 		values = p.parseExpressionList()
 	}
 
-	vs := syntax.NewVarStatement(position, names, type_, values)
+	vs := syntax.NewVarStatement(position, kind, names, type_, values)
 
 	for _, name := range vs.Names {
-		if p.scope.Lookup(name.Name) != nil {
-			p.errors = append(p.errors, NewRedeclaredError(name.Pos(), name.Name))
+		if prev := p.scope.LookupLocal(name.Name); prev != nil {
+			p.errors = append(p.errors, NewRedeclaredError(name.Pos(), name.Name, prev))
 		} else {
 			k := syntax.ObjKindVar
 			if kind == "const" {
@@ -275,7 +521,10 @@ func (p *Parser) parseIfStatement() syntax.Statement {
 	position := p.cur_token.Position
 	p.nextToken()
 
+	saved := p.noCompositeLit
+	p.noCompositeLit = true
 	condition := p.parseExpression(LOWEST)
+	p.noCompositeLit = saved
 	if !p.expectPeek(syntax.TokenLBrace) {
 		return nil
 	}
@@ -300,14 +549,20 @@ func (p *Parser) parseIfStatement() syntax.Statement {
 func (p *Parser) parseFunctionStatement() syntax.Statement {
 	position := p.cur_token.Position
 	p.NewScope()
+
+	// Loops around a nested function cannot be left from inside it.
+	loops := p.loops
+	p.loops = nil
+	defer func() { p.loops = loops }()
 	if !p.expectPeek(syntax.TokenIdent) {
 		return nil
 	}
 
 	name, ok := p.parseIdentifier().(*syntax.Identifier)
 	if !ok {
-		fmt.Fprintln(os.Stderr, NewError(p.cur_token.Position, "function requires a name"))
-		os.Exit(1)
+		p.errors = append(p.errors, NewError(p.cur_token.Position, "function requires a name"))
+		p.DiscardScope()
+		return nil
 	}
 
 	var params_ []*syntax.NField
@@ -319,18 +574,18 @@ func (p *Parser) parseFunctionStatement() syntax.Statement {
 	var return_type []*syntax.Identifier
 	if p.acceptPeek(syntax.TokenArrow) {
 		p.nextToken()
-		return_type = p.parseIdentifierList()
+		return_type = p.parseTypeList()
 	}
 
 	fnType := syntax.NewFuncType(params_, return_type)
 
 	for _, param := range params_ {
 		for _, name := range param.Names {
-			if p.scope.Lookup(name.Name) != nil {
-				p.errors = append(p.errors, NewRedeclaredError(name.Pos(), name.Name))
+			if prev := p.scope.LookupLocal(name.Name); prev != nil {
+				p.errors = append(p.errors, NewRedeclaredError(name.Pos(), name.Name, prev))
 			} else {
 				k := syntax.ObjKindVar
-				s := syntax.NewVarStatement(name.Pos(), []*syntax.Identifier{name}, param.Type, nil)
+				s := syntax.NewVarStatement(name.Pos(), "var", []*syntax.Identifier{name}, param.Type, nil)
 				obj := syntax.NewObject(k, name.Name, s)
 				name.Obj = obj
 				p.scope.Insert(obj)
@@ -347,8 +602,8 @@ func (p *Parser) parseFunctionStatement() syntax.Statement {
 	p.DiscardScope()
 
 	fn := syntax.NewFuncStmt(position, name, fnType, body)
-	if p.scope.Lookup(name.Name) != nil {
-		p.errors = append(p.errors, NewRedeclaredError(name.Pos(), name.Name))
+	if prev := p.scope.LookupLocal(name.Name); prev != nil {
+		p.errors = append(p.errors, NewRedeclaredError(name.Pos(), name.Name, prev))
 	} else {
 		k := syntax.ObjKindFunc
 		obj := syntax.NewObject(k, name.Name, fn)
@@ -363,11 +618,11 @@ func (p *Parser) parseNFields() []*syntax.NField {
 
 	names := p.parseIdentifierList()
 
-	if !p.expectPeek(syntax.TokenIdent) {
+	if !p.expectType() {
 		return nil
 	}
 
-	type_ := p.parseIdentifier().(*syntax.Identifier)
+	type_ := p.parseType()
 
 	fields = append(fields, syntax.NewNField(names, type_))
 
@@ -376,11 +631,11 @@ func (p *Parser) parseNFields() []*syntax.NField {
 
 		names := p.parseIdentifierList()
 
-		if !p.expectPeek(syntax.TokenIdent) {
+		if !p.expectType() {
 			return nil
 		}
 
-		type_ := p.parseIdentifier().(*syntax.Identifier)
+		type_ := p.parseType()
 
 		fields = append(fields, syntax.NewNField(names, type_))
 	}
@@ -407,26 +662,141 @@ func (p *Parser) parseIdentifierList() []*syntax.Identifier {
 	identifiers = append(identifiers, p.parseIdentifier().(*syntax.Identifier))
 
 	for p.acceptPeek(syntax.TokenComma) {
-		p.nextToken()
+		if !p.expectPeek(syntax.TokenIdent) {
+			break
+		}
 		identifiers = append(identifiers, p.parseIdentifier().(*syntax.Identifier))
 	}
 
 	return identifiers
 }
 
+// expectType advances to the type that must follow the current token,
+// reporting an error if the next token cannot start one.
+func (p *Parser) expectType() bool {
+	if p.acceptPeek(syntax.TokenLBrack) {
+		return true
+	}
+	return p.expectPeek(syntax.TokenIdent)
+}
+
+// parseType parses the type starting at the current token: a type name,
+// `[N]T` or `[]T`. An array or slice type is returned as an identifier
+// spelling it as written, whose object describes it with an ArrayType. It
+// returns nil after a syntax error.
+func (p *Parser) parseType() *syntax.Identifier {
+	if !p.curTokenIs(syntax.TokenLBrack) {
+		return p.parseIdentifier().(*syntax.Identifier)
+	}
+	lbrack := p.cur_token.Position
+
+	var len_ syntax.Expression
+	if !p.acceptPeek(syntax.TokenRBrack) {
+		p.nextToken()
+		len_ = p.parseExpression(LOWEST)
+		if !p.expectPeek(syntax.TokenRBrack) {
+			return nil
+		}
+	}
+
+	if !p.expectType() {
+		return nil
+	}
+	elem := p.parseType()
+	if elem == nil {
+		return nil
+	}
+
+	name := string(p.File().Src[lbrack.Offset:elem.End().Offset])
+	obj := syntax.NewObject(syntax.ObjKindType, name, syntax.NewArrayType(lbrack, len_, elem))
+	obj.Type = name
+
+	ident := syntax.NewIdentifier(lbrack, name)
+	ident.Obj = obj
+	return ident
+}
+
+// parseTypeList parses a comma separated list of types starting at the
+// current token.
+func (p *Parser) parseTypeList() []*syntax.Identifier {
+	types := []*syntax.Identifier{p.parseType()}
+
+	for p.acceptPeek(syntax.TokenComma) {
+		if !p.expectType() {
+			break
+		}
+		types = append(types, p.parseType())
+	}
+
+	return types
+}
+
+// parseFields parses the fields of a struct up to its closing brace. Each
+// group of names is followed by their type and optionally a comma or
+// semicolon.
 func (p *Parser) parseFields() []*syntax.Field {
 	var fields []*syntax.Field = make([]*syntax.Field, 0)
 
-	name := p.parseIdentifier().(*syntax.Identifier)
+	for !p.peekTokenIs(syntax.TokenRBrace) {
+		if !p.expectPeek(syntax.TokenIdent) {
+			return nil
+		}
+		names := p.parseIdentifierList()
+
+		if !p.expectType() {
+			return nil
+		}
+		type_ := p.parseType()
+
+		for _, name := range names {
+			fields = append(fields, syntax.NewField(name, type_))
+		}
+
+		if !p.acceptPeek(syntax.TokenComma) {
+			p.acceptPeek(syntax.TokenSemicolon)
+		}
+	}
+
+	return fields
+}
+
+// parseTypeStatement parses `type Name struct { ... }`.
+func (p *Parser) parseTypeStatement() syntax.Statement {
+	position := p.cur_token.Position
 
 	if !p.expectPeek(syntax.TokenIdent) {
 		return nil
 	}
+	name := syntax.NewIdentifier(p.cur_token.Position, p.cur_token.Literal)
 
-	type_ := p.parseIdentifier().(*syntax.Identifier)
+	if prev := p.scope.LookupLocal(name.Name); prev != nil {
+		p.errors = append(p.errors, NewRedeclaredError(name.Pos(), name.Name, prev))
+	} else {
+		obj := syntax.NewObject(syntax.ObjKindType, name.Name, nil)
+		obj.Type = name.Name
+		name.Obj = obj
+		p.scope.Insert(obj)
+	}
 
-	fields = append(fields, syntax.NewField(name, type_))
-	return fields
+	if !p.expectPeek(syntax.TokenStruct) {
+		return nil
+	}
+	structPos := p.cur_token.Position
+
+	if !p.expectPeek(syntax.TokenLBrace) {
+		return nil
+	}
+
+	fields := p.parseFields()
+	if fields == nil || !p.expectPeek(syntax.TokenRBrace) {
+		return nil
+	}
+
+	ts := syntax.NewTypeStmt(position, name, syntax.NewStructType(structPos, fields, p.cur_token.Position))
+	if name.Obj != nil {
+		name.Obj.Decl = ts
+	}
+	return ts
 }
 
 func (p *Parser) parseBlockStatement() *syntax.BlockStmt {
@@ -434,15 +804,26 @@ func (p *Parser) parseBlockStatement() *syntax.BlockStmt {
 
 	block := syntax.NewBlockStmt(position)
 
+	saved := p.noCompositeLit
+	p.noCompositeLit = false
+	p.NewScope()
+	defer func() {
+		p.noCompositeLit = saved
+		p.DiscardScope()
+	}()
+
 	p.nextToken()
 
 	for !p.curTokenIs(syntax.TokenRBrace) && !p.curTokenIs(syntax.TokenEOF) {
-		stmt := p.parseStatement()
+		stmt := p.parseListedStatement()
 		if stmt != nil {
 			block.AddStmt(stmt)
 		}
 		p.nextToken()
 	}
+	if p.curTokenIs(syntax.TokenEOF) {
+		p.errorExpected(p.cur_token, syntax.TokenRBrace)
+	}
 
 	block.Rbrace = p.cur_token.Position
 
@@ -452,30 +833,85 @@ func (p *Parser) parseBlockStatement() *syntax.BlockStmt {
 func (p *Parser) parseReturnStatement() syntax.Statement {
 	position := p.cur_token.Position
 
-	p.nextToken()
-	results := p.parseExpression(LOWEST)
+	var results []syntax.Expression
+	if !p.peekStatementEnd() {
+		p.nextToken()
+		results = p.parseExpressionList()
+	}
 
 	return syntax.NewReturnStmt(position, results)
 }
 
+// peekStatementEnd reports whether the next token cannot continue the
+// current statement, which lets a bare ret be followed by another statement.
+func (p *Parser) peekStatementEnd() bool {
+	switch p.peek_token.Type {
+	case syntax.TokenRBrace, syntax.TokenEOF, syntax.TokenSemicolon,
+		syntax.TokenVar, syntax.TokenConst, syntax.TokenFunc, syntax.TokenReturn,
+		syntax.TokenFor, syntax.TokenIf, syntax.TokenBreak, syntax.TokenContinue,
+		syntax.TokenType_:
+		return true
+	default:
+		return false
+	}
+}
+
 func (p *Parser) parseForStatement() syntax.Statement {
 	position := p.cur_token.Position
-	if p.peekTokenIs(syntax.TokenIdent) {
-		return p.parseForRangeStatement(position)
-	} else if p.peekTokenIs(syntax.TokenLBrace) {
+
+	p.loops = append(p.loops, p.label)
+	p.label = nil
+	saved := p.noCompositeLit
+	p.noCompositeLit = true
+	defer func() {
+		p.loops = p.loops[:len(p.loops)-1]
+		p.noCompositeLit = saved
+	}()
+
+	if p.peekTokenIs(syntax.TokenLBrace) {
 		return p.parseWhileStatement(position, syntax.NewIdentifier(position, "true"))
-	} else {
-		return p.parseForLoopStatement(position)
 	}
-}
 
-func (p *Parser) parseForRangeStatement(position *syntax.Position) syntax.Statement {
-	p.nextToken()
+	// A header with a ; after its first clause is that of a three-clause
+	// loop, whose clauses may each be empty.
 	p.NewScope()
-	expr_ := p.parseExpression(LOWEST)
-	name, ok := expr_.(*syntax.Identifier)
-	if !ok {
-		return p.parseWhileStatement(position, expr_)
+	defer p.DiscardScope()
+
+	var init syntax.Statement
+	switch {
+	case p.peekTokenIs(syntax.TokenSemicolon):
+	case p.peekTokenIs(syntax.TokenVar) || p.peekTokenIs(syntax.TokenConst):
+		p.nextToken()
+		init = p.parseVarStatement()
+	default:
+		p.nextToken()
+		expr := p.parseExpression(LOWEST)
+		if name, ok := expr.(*syntax.Identifier); ok && (p.peekTokenIs(syntax.TokenInfer) || p.peekTokenIs(syntax.TokenComma)) {
+			return p.parseForRangeStatement(position, name)
+		}
+		if !p.peekTokenIs(syntax.TokenSemicolon) {
+			return p.parseWhileStatement(position, expr)
+		}
+		init = p.parseSimpleStatement(expr)
+	}
+
+	return p.parseForLoopStatement(position, init)
+}
+
+// parseForRangeStatement parses the rest of a range header once the name of
+// its first variable has been parsed, in the scope the statement opened.
+// Two names may also start the tuple assignment of a three-clause header.
+func (p *Parser) parseForRangeStatement(position *syntax.Position, name *syntax.Identifier) syntax.Statement {
+	var value *syntax.Identifier
+	if p.acceptPeek(syntax.TokenComma) {
+		if !p.expectPeek(syntax.TokenIdent) {
+			return nil
+		}
+		value = p.parseIdentifier().(*syntax.Identifier)
+
+		if p.peekTokenIs(syntax.TokenComma) || p.peekTokenIs(syntax.TokenAssign) {
+			return p.parseForLoopStatement(position, p.parseTupleAssignStatement([]syntax.Expression{name, value}))
+		}
 	}
 
 	if !p.expectPeek(syntax.TokenInfer) {
@@ -486,18 +922,37 @@ func (p *Parser) parseForRangeStatement(position *syntax.Position) syntax.Statem
 
 	expr := p.parseExpression(LOWEST)
 
+	// The variables of a range over an array or slice are declared by the
+	// statement itself, which is only built once the body is parsed.
+	var vars []*syntax.Object
 	switch expr := expr.(type) {
 	case *syntax.RangeExpr:
 		obj := syntax.NewObject(syntax.ObjKindVar, name.Name, syntax.NewVarStatement(
 			name.Pos(),
+			"var",
 			[]*syntax.Identifier{name},
-			syntax.NewIdentifier(position, "i32"),
-			[]syntax.Expression{syntax.NewBasicLit(position, "i32", expr.Low.(*syntax.BasicLit).Value)},
+			nil,
+			[]syntax.Expression{expr.Low},
 		))
 		name.Obj = obj
 		p.scope.Insert(obj)
+		if value != nil {
+			p.errors = append(p.errors, NewInvalidRangeError(value.Pos()))
+		}
 	default:
-		p.errors = append(p.errors, NewInvalidRangeError(expr.Pos()))
+		for _, ident := range []*syntax.Identifier{name, value} {
+			if ident == nil {
+				continue
+			}
+			if prev := p.scope.Objects[ident.Name]; prev != nil {
+				p.errors = append(p.errors, NewRedeclaredError(ident.Pos(), ident.Name, prev))
+				continue
+			}
+			obj := syntax.NewObject(syntax.ObjKindVar, ident.Name, nil)
+			ident.Obj = obj
+			p.scope.Insert(obj)
+			vars = append(vars, obj)
+		}
 	}
 
 	if !p.expectPeek(syntax.TokenLBrace) {
@@ -506,13 +961,12 @@ func (p *Parser) parseForRangeStatement(position *syntax.Position) syntax.Statem
 
 	body := p.parseBlockStatement()
 
-	if !p.expectPeek(syntax.TokenRBrace) {
-		return nil
+	stmt := syntax.NewForRangeStmt(position, name, value, expr, body)
+	for _, obj := range vars {
+		obj.Decl = stmt
 	}
 
-	p.DiscardScope()
-
-	return syntax.NewForRangeStmt(position, name, expr, body)
+	return stmt
 }
 
 func (p *Parser) parseWhileStatement(position *syntax.Position, expr syntax.Expression) syntax.Statement {
@@ -522,31 +976,31 @@ func (p *Parser) parseWhileStatement(position *syntax.Position, expr syntax.Expr
 
 	body := p.parseBlockStatement()
 
-	if !p.expectPeek(syntax.TokenRBrace) {
-		return nil
-	}
-
 	return syntax.NewWhileStmt(expr, body)
 }
 
-func (p *Parser) parseForLoopStatement(position *syntax.Position) syntax.Statement {
-	p.nextToken()
-
-	init := p.parseStatement()
-
+// parseForLoopStatement parses the rest of a three-clause header once its
+// init clause, nil if it is empty, has been parsed.
+func (p *Parser) parseForLoopStatement(position *syntax.Position, init syntax.Statement) syntax.Statement {
 	if !p.expectPeek(syntax.TokenSemicolon) {
 		return nil
 	}
 
-	p.nextToken()
-	cond := p.parseExpression(LOWEST)
+	var cond syntax.Expression
+	if !p.peekTokenIs(syntax.TokenSemicolon) {
+		p.nextToken()
+		cond = p.parseExpression(LOWEST)
+	}
 
 	if !p.expectPeek(syntax.TokenSemicolon) {
 		return nil
 	}
 
-	p.nextToken()
-	inc := p.parseStatement()
+	var post syntax.Statement
+	if !p.peekTokenIs(syntax.TokenLBrace) {
+		p.nextToken()
+		post = p.parseSimpleStatement(p.parseExpression(LOWEST))
+	}
 
 	if !p.expectPeek(syntax.TokenLBrace) {
 		return nil
@@ -554,11 +1008,7 @@ func (p *Parser) parseForLoopStatement(position *syntax.Position) syntax.Stateme
 
 	body := p.parseBlockStatement()
 
-	if !p.expectPeek(syntax.TokenRBrace) {
-		return nil
-	}
-
-	return syntax.NewForLoopStmt(init, cond, inc, body)
+	return syntax.NewForLoopStmt(position, init, cond, post, body)
 }
 
 func (p *Parser) parseIdentifier() syntax.Expression {
@@ -572,7 +1022,38 @@ func (p *Parser) parseIdentifier() syntax.Expression {
 func (p *Parser) parseIntegerLiteral() syntax.Expression {
 	position := p.cur_token.Position
 	literal := p.cur_token.Literal
-	return syntax.NewBasicLit(position, "i32", literal)
+	return syntax.NewBasicLit(position, "int", literal)
+}
+
+func (p *Parser) parseFloatLiteral() syntax.Expression {
+	return syntax.NewBasicLit(p.cur_token.Position, "float", p.cur_token.Literal)
+}
+
+func (p *Parser) parseStringLiteral() syntax.Expression {
+	return syntax.NewBasicLit(p.cur_token.Position, "string", p.cur_token.Literal)
+}
+
+func (p *Parser) parsePrefixExpression() syntax.Expression {
+	operator := p.cur_token
+	p.nextToken()
+	x := p.parseExpression(PREFIX)
+	return syntax.NewUnaryExpr(operator, x)
+}
+
+func (p *Parser) parseParenExpression() syntax.Expression {
+	lparen := p.cur_token.Position
+	p.nextToken()
+
+	saved := p.noCompositeLit
+	p.noCompositeLit = false
+	x := p.parseExpression(LOWEST)
+	p.noCompositeLit = saved
+
+	if !p.expectPeek(syntax.TokenRParen) {
+		return syntax.NewBadExpr(lparen, p.cur_token.End())
+	}
+
+	return syntax.NewParenExpr(lparen, x, p.cur_token.Position)
 }
 
 func (p *Parser) parseInfixExpression(expr syntax.Expression) syntax.Expression {
@@ -588,28 +1069,210 @@ func (p *Parser) parseCallExpression(expr syntax.Expression) syntax.Expression {
 
 	name, ok := expr.(*syntax.Identifier)
 	if !ok {
-		fmt.Fprintln(os.Stderr, NewError(p.cur_token.Position, "call requires a name"))
-		os.Exit(1)
+		p.error(NewCallNameError(expr))
 	}
 
-	p.nextToken()
-	args := p.parseExpression(LOWEST)
+	var args []syntax.Expression
+	if !p.acceptPeek(syntax.TokenRParen) {
+		saved := p.noCompositeLit
+		p.noCompositeLit = false
+		p.nextToken()
+		args = p.parseExpressionList()
+		p.noCompositeLit = saved
 
-	if !p.expectPeek(syntax.TokenRParen) {
-		return nil
+		if !p.expectPeek(syntax.TokenRParen) {
+			return syntax.NewBadExpr(expr.Pos(), p.cur_token.End())
+		}
+	}
+
+	if !ok {
+		return expr
 	}
 
 	return syntax.NewCallExpr(name, position, args, p.cur_token.Position)
 }
 
+func (p *Parser) parseSelectorExpression(x syntax.Expression) syntax.Expression {
+	if !p.expectPeek(syntax.TokenIdent) {
+		return syntax.NewBadExpr(x.Pos(), p.cur_token.End())
+	}
+
+	return syntax.NewSelectorExpr(x, syntax.NewIdentifier(p.cur_token.Position, p.cur_token.Literal))
+}
+
+// parseIndexExpression parses `x[i]`, or the slice expression `x[lo..hi]`.
+func (p *Parser) parseIndexExpression(x syntax.Expression) syntax.Expression {
+	lbrack := p.cur_token.Position
+	p.nextToken()
+
+	saved := p.noCompositeLit
+	p.noCompositeLit = false
+	index := p.parseExpression(LOWEST)
+	p.noCompositeLit = saved
+
+	if !p.expectPeek(syntax.TokenRBrack) {
+		return syntax.NewBadExpr(x.Pos(), p.cur_token.End())
+	}
+
+	return syntax.NewIndexExpr(x, lbrack, index, p.cur_token.Position)
+}
+
+// parseArrayLiteral parses an array or slice literal such as `[3]i32{1, 2,
+// 3}`. Its type starts with a bracket, so unlike `T{...}` it is never
+// ambiguous with a block.
+func (p *Parser) parseArrayLiteral() syntax.Expression {
+	lbrack := p.cur_token.Position
+
+	type_ := p.parseType()
+	if type_ == nil || !p.expectPeek(syntax.TokenLBrace) {
+		return syntax.NewBadExpr(lbrack, p.cur_token.End())
+	}
+
+	return p.parseCompositeLiteral(type_)
+}
+
+// parseCompositeLiteral parses `T{...}` once the type name T has been
+// parsed. Elements are separated by commas, with an optional trailing one.
+func (p *Parser) parseCompositeLiteral(expr syntax.Expression) syntax.Expression {
+	lbrace := p.cur_token.Position
+
+	type_, ok := expr.(*syntax.Identifier)
+	if !ok {
+		p.error(NewError(expr.Pos(), "composite literal requires a type name"))
+		return syntax.NewBadExpr(expr.Pos(), p.cur_token.End())
+	}
+
+	saved := p.noCompositeLit
+	p.noCompositeLit = false
+	defer func() { p.noCompositeLit = saved }()
+
+	elts := make([]syntax.Expression, 0)
+	for !p.peekTokenIs(syntax.TokenRBrace) {
+		p.nextToken()
+		elts = append(elts, p.parseElement())
+		if !p.acceptPeek(syntax.TokenComma) {
+			break
+		}
+	}
+
+	if !p.expectPeek(syntax.TokenRBrace) {
+		return syntax.NewBadExpr(expr.Pos(), p.cur_token.End())
+	}
+
+	return syntax.NewCompositeLit(type_, lbrace, elts, p.cur_token.Position)
+}
+
+// parseElement parses an element of a composite literal, which is either a
+// value or `field: value`.
+func (p *Parser) parseElement() syntax.Expression {
+	if !p.curTokenIs(syntax.TokenIdent) || !p.peekTokenIs(syntax.TokenColon) {
+		return p.parseExpression(LOWEST)
+	}
+
+	key := syntax.NewIdentifier(p.cur_token.Position, p.cur_token.Literal)
+	p.nextToken()
+	colon := p.cur_token.Position
+	p.nextToken()
+
+	return syntax.NewKeyValueExpr(key, colon, p.parseExpression(LOWEST))
+}
+
 func (p *Parser) parseExpressionStatement() syntax.Statement {
-	expr := p.parseExpression(LOWEST)
+	if p.peekTokenIs(syntax.TokenColon) {
+		return p.parseLabeledStatement()
+	}
+
+	return p.parseSimpleStatement(p.parseExpression(LOWEST))
+}
+
+// parseSimpleStatement parses the rest of an expression statement, ++, --
+// or tuple assignment once its first expression has been parsed.
+func (p *Parser) parseSimpleStatement(expr syntax.Expression) syntax.Statement {
+	switch p.peek_token.Type {
+	case syntax.TokenComma:
+		if _, ok := expr.(*syntax.AssignmentExpr); !ok {
+			return p.parseTupleAssignStatement([]syntax.Expression{expr})
+		}
+	case syntax.TokenInc, syntax.TokenDec:
+		p.nextToken()
+		return syntax.NewIncDecStmt(expr, p.cur_token.Position, p.cur_token.Type)
+	}
+
 	return syntax.NewExpressionStmt(expr)
 }
 
+// parseTupleAssignStatement parses `a, b = x, y` once the first operands on
+// the left, lhs, have been parsed.
+func (p *Parser) parseTupleAssignStatement(lhs []syntax.Expression) syntax.Statement {
+	for p.acceptPeek(syntax.TokenComma) {
+		p.nextToken()
+		lhs = append(lhs, p.parseExpression(ASSIGN))
+	}
+
+	if !p.expectPeek(syntax.TokenAssign) {
+		return nil
+	}
+	position := p.cur_token.Position
+	p.nextToken()
+
+	return syntax.NewTupleAssignStmt(lhs, position, p.parseExpressionList())
+}
+
+// parseLabeledStatement parses `label: for ...`. Labels may only name loops.
+func (p *Parser) parseLabeledStatement() syntax.Statement {
+	label := syntax.NewIdentifier(p.cur_token.Position, p.cur_token.Literal)
+	p.nextToken()
+	colon := p.cur_token.Position
+
+	if !p.peekTokenIs(syntax.TokenFor) {
+		p.error(NewMisplacedLabelError(label))
+		return nil
+	}
+	p.nextToken()
+
+	for _, l := range p.loops {
+		if l != nil && l.Name == label.Name {
+			p.errors = append(p.errors, NewRedeclaredLabelError(label, l))
+		}
+	}
+
+	p.label = label
+	return syntax.NewLabeledStmt(label, colon, p.parseForStatement())
+}
+
+// parseBranchStatement parses break or continue, with the label of the loop
+// it leaves if one follows on the same line.
+func (p *Parser) parseBranchStatement() syntax.Statement {
+	tok := p.cur_token
+
+	var label *syntax.Identifier
+	if p.peekTokenIs(syntax.TokenIdent) && p.peek_token.Position.Line == tok.Position.Line {
+		p.nextToken()
+		label = syntax.NewIdentifier(p.cur_token.Position, p.cur_token.Literal)
+	}
+
+	switch {
+	case len(p.loops) == 0:
+		p.errors = append(p.errors, NewMisplacedBranchError(tok.Position, tok.Type))
+	case label != nil && !p.loopLabeled(label.Name):
+		p.errors = append(p.errors, NewUndefinedLabelError(label, tok.Type))
+	}
+
+	return syntax.NewBranchStmt(tok.Position, tok.Type, label)
+}
+
+// loopLabeled reports whether one of the enclosing loops is labeled name.
+func (p *Parser) loopLabeled(name string) bool {
+	for _, l := range p.loops {
+		if l != nil && l.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 func (p *Parser) parseRangeExpression(expr syntax.Expression) syntax.Expression {
 	p.nextToken()
 	right := p.parseExpression(LOWEST)
-	// TODO: actually check the type
-	return syntax.NewRangeExpr(expr, right, "i32")
+	return syntax.NewRangeExpr(expr, right)
 }
@@ -0,0 +1,134 @@
+package parse
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/danecwalker/hippo/internal/diag"
+	"github.com/danecwalker/hippo/internal/syntax"
+)
+
+// parseBody parses body as the body of a function and returns its
+// statements, failing the test on any error.
+func parseBody(t *testing.T, body string) []syntax.Statement {
+	t.Helper()
+
+	p := NewParserFromBytes("test.x", []byte("fn main {\n"+body+"\n}\n"))
+	prog := p.ParseProgram()
+	for _, err := range p.Errors() {
+		t.Errorf("%s: %v", body, err)
+	}
+	if t.Failed() {
+		t.FailNow()
+	}
+	return prog.Statements[0].(*syntax.FuncStmt).Body.Stmts
+}
+
+func TestForLoopClauses(t *testing.T) {
+	tests := []struct {
+		src              string
+		init, cond, post bool
+	}{
+		{"var i i32\nfor i = 0; i < 4; i++ {}", true, true, true},
+		{"var i i32\nfor ; i < 4; i++ {}", false, true, true},
+		{"for var i = 0; ; i++ {}", true, false, true},
+		{"var i i32\nfor i = 0; i < 4; {}", true, true, false},
+		{"for ; ; {}", false, false, false},
+		{"var a, b i32\nfor a, b = 0, 1; a < b; a, b = b, a {}", true, true, true},
+	}
+
+	for _, test := range tests {
+		stmts := parseBody(t, test.src)
+		loop, ok := stmts[len(stmts)-1].(*syntax.ForLoopStmt)
+		if !ok {
+			t.Errorf("%q: got %T, want *syntax.ForLoopStmt", test.src, stmts[len(stmts)-1])
+			continue
+		}
+		if got := loop.Init != nil; got != test.init {
+			t.Errorf("%q: has init = %v, want %v", test.src, got, test.init)
+		}
+		if got := loop.Cond != nil; got != test.cond {
+			t.Errorf("%q: has cond = %v, want %v", test.src, got, test.cond)
+		}
+		if got := loop.Post != nil; got != test.post {
+			t.Errorf("%q: has post = %v, want %v", test.src, got, test.post)
+		}
+	}
+}
+
+func TestForHeaders(t *testing.T) {
+	tests := []struct {
+		src  string
+		want syntax.Statement
+	}{
+		{"for {}", &syntax.WhileStmt{}},
+		{"var i i32\nfor i < 4 {}", &syntax.WhileStmt{}},
+		{"var done bool\nfor done {}", &syntax.WhileStmt{}},
+		{"for i <- 0..4 {}", &syntax.ForRangeStmt{}},
+		{"var s []i32\nfor i, x <- s {}", &syntax.ForRangeStmt{}},
+	}
+
+	for _, test := range tests {
+		stmts := parseBody(t, test.src)
+		got := stmts[len(stmts)-1]
+		if fmt.Sprintf("%T", got) != fmt.Sprintf("%T", test.want) {
+			t.Errorf("%q: got %T, want %T", test.src, got, test.want)
+		}
+	}
+}
+
+func TestShadowPredeclared(t *testing.T) {
+	p := NewParserFromBytes("test.x", []byte(`
+var len = 1
+
+fn print : x i32 {
+}
+
+type string struct {}
+
+fn main {
+  var cap = 2
+  if true {
+    var x = 1
+  } else {
+    var x = 2
+  }
+}
+`))
+	p.ParseProgram()
+	for _, err := range p.Errors() {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestRedeclared(t *testing.T) {
+	tests := []string{
+		"var x = 1\nvar x = 2\n",
+		"fn x {\n}\nfn x {\n}\n",
+		"type x struct {}\nvar x = 1\n",
+		"fn f : x, x i32 {\n}\n",
+		"fn main {\n  var x = 1\n  var x = 2\n}\n",
+	}
+
+	for _, src := range tests {
+		p := NewParserFromBytes("test.x", []byte(src))
+		p.ParseProgram()
+		errs := p.Errors()
+		if len(errs) != 1 {
+			t.Errorf("%q: got %d errors, want 1", src, len(errs))
+			continue
+		}
+		if errs[0].Code != diag.CodeRedeclared || errs[0].Msg != "x redeclared in this block" {
+			t.Errorf("%q: got %s %q, want %s %q", src, errs[0].Code, errs[0].Msg, diag.CodeRedeclared, "x redeclared in this block")
+		}
+	}
+}
+
+func TestCallName(t *testing.T) {
+	p := NewParserFromBytes("test.x", []byte("fn main -> i32 {\n  var x = 1\n  ret (x)(2)\n}\n"))
+	p.ParseProgram()
+	errs := p.Errors()
+	if len(errs) != 1 || errs[0].Code != diag.CodeCallName {
+		t.Fatalf("got %v, want one %s error", errs, diag.CodeCallName)
+	}
+}
@@ -18,6 +18,12 @@ func (s *Scope) Insert(obj *syntax.Object) {
 	s.Objects[obj.Name] = obj
 }
 
+// LookupLocal returns the object declared as name in s itself, ignoring the
+// scopes enclosing it, or nil.
+func (s *Scope) LookupLocal(name string) *syntax.Object {
+	return s.Objects[name]
+}
+
 func (s *Scope) Lookup(name string) *syntax.Object {
 	if obj, ok := s.Objects[name]; ok {
 		return obj
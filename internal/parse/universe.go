@@ -1 +1,44 @@
 package parse
+
+import "github.com/danecwalker/hippo/internal/syntax"
+
+// Universe holds the predeclared objects. It is the parent of every
+// parser's global scope.
+var Universe = NewScope(nil)
+
+func init() {
+	defineType("bool")
+	for _, name := range []string{"i8", "i16", "i32", "i64", "u8", "u16", "u32", "u64", "f32", "f64"} {
+		defineType(name)
+	}
+	defineType("string")
+
+	defineConst("true", "bool")
+	defineConst("false", "bool")
+
+	defineBuiltin("len")
+	defineBuiltin("cap")
+	defineBuiltin("append")
+	defineBuiltin("print")
+}
+
+func defineType(name string) {
+	obj := syntax.NewObject(syntax.ObjKindType, name, nil)
+	obj.Type = name
+	Universe.Insert(obj)
+}
+
+func defineConst(name string, type_ string) {
+	obj := syntax.NewObject(syntax.ObjKindConst, name, nil)
+	obj.Type = type_
+	Universe.Insert(obj)
+}
+
+func defineBuiltin(name string) {
+	Universe.Insert(syntax.NewObject(syntax.ObjKindBuiltin, name, nil))
+}
+
+// IsPredeclared reports whether obj is one of the objects in Universe.
+func IsPredeclared(obj *syntax.Object) bool {
+	return obj != nil && Universe.Objects[obj.Name] == obj
+}
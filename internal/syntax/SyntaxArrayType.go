@@ -0,0 +1,81 @@
+package syntax
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// ArrayType is the type `[Len]Elem` of an array, or `[]Elem` of a slice
+// when Len is nil. Types are named by identifiers throughout the tree, so
+// the parser returns an array or slice type as an Identifier spelling it,
+// whose object has the ArrayType as its Decl.
+type ArrayType struct {
+	Lbrack *Position
+	Len    Expression
+	Elem   *Identifier
+}
+
+func NewArrayType(lbrack *Position, len_ Expression, elem *Identifier) *ArrayType {
+	return &ArrayType{
+		Lbrack: lbrack,
+		Len:    len_,
+		Elem:   elem,
+	}
+}
+
+func (at *ArrayType) Pos() *Position {
+	return at.Lbrack
+}
+
+func (at *ArrayType) End() *Position {
+	return at.Elem.End()
+}
+
+func (at *ArrayType) PrettyPrint(w *bytes.Buffer, indent int) {
+	addIndent(w, indent)
+	w.WriteString("ArrayType:\n")
+	addIndent(w, indent+1)
+	w.WriteString("Len:\n")
+	if at.Len != nil {
+		at.Len.PrettyPrint(w, indent+2)
+	} else {
+		addIndent(w, indent+2)
+		w.WriteString("nil\n")
+	}
+	addIndent(w, indent+1)
+	w.WriteString("Elem:\n")
+	at.Elem.PrettyPrint(w, indent+2)
+}
+
+// ArrayTypeName returns the name the type checker gives the array type
+// [n]elem, or the slice type []elem when n is negative.
+func ArrayTypeName(n int64, elem string) string {
+	if n < 0 {
+		return "[]" + elem
+	}
+	return "[" + strconv.FormatInt(n, 10) + "]" + elem
+}
+
+// SplitArrayTypeName splits a name made by ArrayTypeName into the length,
+// -1 for a slice, and the name of the element type. It reports false if
+// name is not the name of an array or slice type.
+func SplitArrayTypeName(name string) (int64, string, bool) {
+	if !strings.HasPrefix(name, "[") {
+		return 0, "", false
+	}
+
+	i := strings.IndexByte(name, ']')
+	if i < 0 || i == len(name)-1 {
+		return 0, "", false
+	}
+	if i == 1 {
+		return -1, name[2:], true
+	}
+
+	n, err := strconv.ParseInt(name[1:i], 10, 64)
+	if err != nil || n < 0 {
+		return 0, "", false
+	}
+	return n, name[i+1:], true
+}
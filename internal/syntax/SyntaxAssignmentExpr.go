@@ -24,6 +24,10 @@ func (e *AssignmentExpr) Pos() *Position {
 	return e.Lhs.Pos()
 }
 
+func (e *AssignmentExpr) End() *Position {
+	return e.Rhs.End()
+}
+
 func (e *AssignmentExpr) PrettyPrint(w *bytes.Buffer, indent int) {
 	addIndent(w, indent)
 	w.WriteString("AssignmentExpr:\n")
@@ -0,0 +1,34 @@
+package syntax
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// BadExpr is a placeholder for an expression that could not be parsed. It
+// spans the text the parser skipped.
+type BadExpr struct {
+	From *Position
+	To   *Position
+}
+
+func NewBadExpr(from *Position, to *Position) *BadExpr {
+	return &BadExpr{
+		From: from,
+		To:   to,
+	}
+}
+
+func (b *BadExpr) expressionNode() {}
+func (b *BadExpr) Pos() *Position {
+	return b.From
+}
+
+func (b *BadExpr) End() *Position {
+	return b.To
+}
+
+func (b *BadExpr) PrettyPrint(w *bytes.Buffer, indent int) {
+	addIndent(w, indent)
+	w.WriteString(fmt.Sprintf("BadExpr: (%s)\n", b.From))
+}
@@ -0,0 +1,34 @@
+package syntax
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// BadStmt is a placeholder for a statement that could not be parsed. It
+// spans the text the parser skipped to recover.
+type BadStmt struct {
+	From *Position
+	To   *Position
+}
+
+func NewBadStmt(from *Position, to *Position) *BadStmt {
+	return &BadStmt{
+		From: from,
+		To:   to,
+	}
+}
+
+func (b *BadStmt) statementNode() {}
+func (b *BadStmt) Pos() *Position {
+	return b.From
+}
+
+func (b *BadStmt) End() *Position {
+	return b.To
+}
+
+func (b *BadStmt) PrettyPrint(w *bytes.Buffer, indent int) {
+	addIndent(w, indent)
+	w.WriteString(fmt.Sprintf("BadStmt: (%s)\n", b.From))
+}
@@ -23,6 +23,10 @@ func (bl *BasicLit) expressionNode() {}
 func (bl *BasicLit) Pos() *Position {
 	return bl.ValuePos
 }
+
+func (bl *BasicLit) End() *Position {
+	return bl.ValuePos.After(bl.Value)
+}
 func (bl *BasicLit) PrettyPrint(w *bytes.Buffer, indent int) {
 	addIndent(w, indent)
 	w.WriteString(fmt.Sprintf("BasicLit: (%s)\n", bl.ValuePos))
@@ -23,6 +23,10 @@ func (b *BinaryExpr) Pos() *Position {
 	return b.X.Pos()
 }
 
+func (b *BinaryExpr) End() *Position {
+	return b.Y.End()
+}
+
 func (b *BinaryExpr) PrettyPrint(w *bytes.Buffer, indent int) {
 	addIndent(w, indent)
 	w.WriteString("BinaryExpr:\n")
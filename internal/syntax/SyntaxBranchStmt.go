@@ -0,0 +1,35 @@
+package syntax
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// BranchStmt is a break or continue statement. Label is nil when it refers
+// to the innermost enclosing loop.
+type BranchStmt struct {
+	TokPos *Position
+	Tok    TokenType // TokenBreak or TokenContinue
+	Label  *Identifier
+}
+
+func NewBranchStmt(tokPos *Position, tok TokenType, label *Identifier) *BranchStmt {
+	return &BranchStmt{
+		TokPos: tokPos,
+		Tok:    tok,
+		Label:  label,
+	}
+}
+
+func (bs *BranchStmt) statementNode() {}
+func (bs *BranchStmt) Pos() *Position {
+	return bs.TokPos
+}
+
+func (bs *BranchStmt) PrettyPrint(w *bytes.Buffer, indent int) {
+	addIndent(w, indent)
+	w.WriteString(fmt.Sprintf("BranchStmt (%s): %s\n", bs.TokPos, bs.Tok))
+	if bs.Label != nil {
+		bs.Label.PrettyPrint(w, indent+1)
+	}
+}
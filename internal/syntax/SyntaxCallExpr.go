@@ -5,11 +5,11 @@ import "bytes"
 type CallExpr struct {
 	Func   *Identifier
 	Lparen *Position
-	Args   Expression
+	Args   []Expression
 	Rparen *Position
 }
 
-func NewCallExpr(func_ *Identifier, lparen *Position, args Expression, rparen *Position) *CallExpr {
+func NewCallExpr(func_ *Identifier, lparen *Position, args []Expression, rparen *Position) *CallExpr {
 	return &CallExpr{
 		Func:   func_,
 		Lparen: lparen,
@@ -23,6 +23,10 @@ func (c *CallExpr) Pos() *Position {
 	return c.Func.Pos()
 }
 
+func (c *CallExpr) End() *Position {
+	return c.Rparen.After(")")
+}
+
 func (c *CallExpr) PrettyPrint(w *bytes.Buffer, indent int) {
 	addIndent(w, indent)
 	w.WriteString("CallExpr:\n")
@@ -31,5 +35,7 @@ func (c *CallExpr) PrettyPrint(w *bytes.Buffer, indent int) {
 	c.Func.PrettyPrint(w, indent+2)
 	addIndent(w, indent+1)
 	w.WriteString("Args:\n")
-	c.Args.PrettyPrint(w, indent+2)
+	for _, arg := range c.Args {
+		arg.PrettyPrint(w, indent+2)
+	}
 }
@@ -0,0 +1,31 @@
+package syntax
+
+import "strings"
+
+// Comment is a // line comment or a /* */ block comment. Comments are not
+// part of the syntax tree; the parser collects them in Program.Comments so
+// tools can reattach them to the surrounding code.
+type Comment struct {
+	Slash *Position
+	Text  string // including the comment markers
+}
+
+func NewComment(slash *Position, text string) *Comment {
+	return &Comment{
+		Slash: slash,
+		Text:  text,
+	}
+}
+
+func (c *Comment) Pos() *Position {
+	return c.Slash
+}
+
+// End returns the offset just past the last byte of the comment.
+func (c *Comment) End() int {
+	return c.Slash.Offset + len(c.Text)
+}
+
+func (c *Comment) IsBlock() bool {
+	return strings.HasPrefix(c.Text, "/*")
+}
@@ -0,0 +1,44 @@
+package syntax
+
+import "bytes"
+
+// CompositeLit is a struct value written out field by field, either by name,
+// as in `Point{x: 1, y: 2}`, or by position, as in `Point{1, 2}`. Named
+// elements are KeyValueExprs.
+type CompositeLit struct {
+	Type   *Identifier
+	Lbrace *Position
+	Elts   []Expression
+	Rbrace *Position
+}
+
+func NewCompositeLit(type_ *Identifier, lbrace *Position, elts []Expression, rbrace *Position) *CompositeLit {
+	return &CompositeLit{
+		Type:   type_,
+		Lbrace: lbrace,
+		Elts:   elts,
+		Rbrace: rbrace,
+	}
+}
+
+func (c *CompositeLit) expressionNode() {}
+func (c *CompositeLit) Pos() *Position {
+	return c.Type.Pos()
+}
+
+func (c *CompositeLit) End() *Position {
+	return c.Rbrace.After("}")
+}
+
+func (c *CompositeLit) PrettyPrint(w *bytes.Buffer, indent int) {
+	addIndent(w, indent)
+	w.WriteString("CompositeLit:\n")
+	addIndent(w, indent+1)
+	w.WriteString("Type:\n")
+	c.Type.PrettyPrint(w, indent+2)
+	addIndent(w, indent+1)
+	w.WriteString("Elts:\n")
+	for _, elt := range c.Elts {
+		elt.PrettyPrint(w, indent+2)
+	}
+}
@@ -2,20 +2,23 @@ package syntax
 
 import "bytes"
 
+// ForLoopStmt is a three-clause for statement. Init, Cond and Post are nil
+// when their clause is empty.
 type ForLoopStmt struct {
-	Init Statement
-	Cond Expression
-	Post Statement
-	Body *BlockStmt
+	ForPos *Position
+	Init   Statement
+	Cond   Expression
+	Post   Statement
+	Body   *BlockStmt
 }
 
-func NewForLoopStmt(init Statement, cond Expression, post Statement, body *BlockStmt) *ForLoopStmt {
-	return &ForLoopStmt{Init: init, Cond: cond, Post: post, Body: body}
+func NewForLoopStmt(forPos *Position, init Statement, cond Expression, post Statement, body *BlockStmt) *ForLoopStmt {
+	return &ForLoopStmt{ForPos: forPos, Init: init, Cond: cond, Post: post, Body: body}
 }
 
 func (f *ForLoopStmt) statementNode() {}
 func (f *ForLoopStmt) Pos() *Position {
-	return f.Init.Pos()
+	return f.ForPos
 }
 
 func (fs *ForLoopStmt) PrettyPrint(w *bytes.Buffer, indent int) {
@@ -23,14 +26,24 @@ func (fs *ForLoopStmt) PrettyPrint(w *bytes.Buffer, indent int) {
 	w.WriteString("ForLoopStmt:\n")
 	addIndent(w, indent+1)
 	w.WriteString("Init:\n")
-	fs.Init.PrettyPrint(w, indent+2)
+	prettyOptional(w, indent+2, fs.Init)
 	addIndent(w, indent+1)
 	w.WriteString("Cond:\n")
-	fs.Cond.PrettyPrint(w, indent+2)
+	prettyOptional(w, indent+2, fs.Cond)
 	addIndent(w, indent+1)
 	w.WriteString("Post:\n")
-	fs.Post.PrettyPrint(w, indent+2)
+	prettyOptional(w, indent+2, fs.Post)
 	addIndent(w, indent+1)
 	w.WriteString("Body:\n")
 	fs.Body.PrettyPrint(w, indent+2)
 }
+
+// prettyOptional prints node, or nil if it is absent.
+func prettyOptional(w *bytes.Buffer, indent int, node Node) {
+	if node == nil {
+		addIndent(w, indent)
+		w.WriteString("nil\n")
+		return
+	}
+	node.PrettyPrint(w, indent)
+}
@@ -4,17 +4,23 @@ import (
 	"bytes"
 )
 
+// ForRangeStmt is `for k <- lo..hi`, binding k to each integer in the
+// range, or `for v <- x` or `for k, v <- x` over the array or slice x,
+// binding v to each element and k to its index. Value is nil unless both
+// are named.
 type ForRangeStmt struct {
 	ForPos *Position
 	Key    *Identifier
+	Value  *Identifier
 	X      Expression
 	Body   *BlockStmt
 }
 
-func NewForRangeStmt(forPos *Position, key *Identifier, x Expression, body *BlockStmt) *ForRangeStmt {
+func NewForRangeStmt(forPos *Position, key *Identifier, value *Identifier, x Expression, body *BlockStmt) *ForRangeStmt {
 	return &ForRangeStmt{
 		ForPos: forPos,
 		Key:    key,
+		Value:  value,
 		X:      x,
 		Body:   body,
 	}
@@ -36,6 +42,11 @@ func (fs *ForRangeStmt) PrettyPrint(w *bytes.Buffer, indent int) {
 	addIndent(w, indent+1)
 	w.WriteString("Key:\n")
 	fs.Key.PrettyPrint(w, indent+2)
+	if fs.Value != nil {
+		addIndent(w, indent+1)
+		w.WriteString("Value:\n")
+		fs.Value.PrettyPrint(w, indent+2)
+	}
 	addIndent(w, indent+1)
 	w.WriteString("X:\n")
 	fs.X.PrettyPrint(w, indent+2)
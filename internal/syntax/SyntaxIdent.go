@@ -9,6 +9,10 @@ type Identifier struct {
 	NamePos *Position
 	Name    string
 	Obj     *Object
+
+	// Const is the value of a named integer constant in the type this use
+	// of it was given, when the checker converted it.
+	Const *BasicLit
 }
 
 func NewIdentifier(namePos *Position, name string) *Identifier {
@@ -23,6 +27,10 @@ func (i *Identifier) Pos() *Position {
 	return i.NamePos
 }
 
+func (i *Identifier) End() *Position {
+	return i.NamePos.After(i.Name)
+}
+
 func (i *Identifier) PrettyPrint(w *bytes.Buffer, indent int) {
 	addIndent(w, indent)
 	w.WriteString(fmt.Sprintf("Identifier: (%s)\n", i.NamePos))
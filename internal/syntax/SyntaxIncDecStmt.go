@@ -0,0 +1,32 @@
+package syntax
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// IncDecStmt is an x++ or x-- statement.
+type IncDecStmt struct {
+	X      Expression
+	TokPos *Position
+	Tok    TokenType // TokenInc or TokenDec
+}
+
+func NewIncDecStmt(x Expression, tokPos *Position, tok TokenType) *IncDecStmt {
+	return &IncDecStmt{
+		X:      x,
+		TokPos: tokPos,
+		Tok:    tok,
+	}
+}
+
+func (s *IncDecStmt) statementNode() {}
+func (s *IncDecStmt) Pos() *Position {
+	return s.X.Pos()
+}
+
+func (s *IncDecStmt) PrettyPrint(w *bytes.Buffer, indent int) {
+	addIndent(w, indent)
+	w.WriteString(fmt.Sprintf("IncDecStmt (%s): %s\n", s.TokPos, s.Tok))
+	s.X.PrettyPrint(w, indent+1)
+}
@@ -0,0 +1,48 @@
+package syntax
+
+import "bytes"
+
+// IndexExpr is an element of an array or slice, as in `a[i]`, or a slice of
+// one when Index is a RangeExpr, as in `a[lo..hi]`.
+type IndexExpr struct {
+	X      Expression
+	Lbrack *Position
+	Index  Expression
+	Rbrack *Position
+}
+
+func NewIndexExpr(x Expression, lbrack *Position, index Expression, rbrack *Position) *IndexExpr {
+	return &IndexExpr{
+		X:      x,
+		Lbrack: lbrack,
+		Index:  index,
+		Rbrack: rbrack,
+	}
+}
+
+func (ie *IndexExpr) expressionNode() {}
+func (ie *IndexExpr) Pos() *Position {
+	return ie.X.Pos()
+}
+
+func (ie *IndexExpr) End() *Position {
+	return ie.Rbrack.After("]")
+}
+
+// Range returns the bounds of a slice expression, or nil if ie selects a
+// single element.
+func (ie *IndexExpr) Range() *RangeExpr {
+	r, _ := ie.Index.(*RangeExpr)
+	return r
+}
+
+func (ie *IndexExpr) PrettyPrint(w *bytes.Buffer, indent int) {
+	addIndent(w, indent)
+	w.WriteString("IndexExpr:\n")
+	addIndent(w, indent+1)
+	w.WriteString("X:\n")
+	ie.X.PrettyPrint(w, indent+2)
+	addIndent(w, indent+1)
+	w.WriteString("Index:\n")
+	ie.Index.PrettyPrint(w, indent+2)
+}
@@ -0,0 +1,38 @@
+package syntax
+
+import "bytes"
+
+// KeyValueExpr is an element `key: value` of a composite literal.
+type KeyValueExpr struct {
+	Key   *Identifier
+	Colon *Position
+	Value Expression
+}
+
+func NewKeyValueExpr(key *Identifier, colon *Position, value Expression) *KeyValueExpr {
+	return &KeyValueExpr{
+		Key:   key,
+		Colon: colon,
+		Value: value,
+	}
+}
+
+func (kv *KeyValueExpr) expressionNode() {}
+func (kv *KeyValueExpr) Pos() *Position {
+	return kv.Key.Pos()
+}
+
+func (kv *KeyValueExpr) End() *Position {
+	return kv.Value.End()
+}
+
+func (kv *KeyValueExpr) PrettyPrint(w *bytes.Buffer, indent int) {
+	addIndent(w, indent)
+	w.WriteString("KeyValueExpr:\n")
+	addIndent(w, indent+1)
+	w.WriteString("Key:\n")
+	kv.Key.PrettyPrint(w, indent+2)
+	addIndent(w, indent+1)
+	w.WriteString("Value:\n")
+	kv.Value.PrettyPrint(w, indent+2)
+}
@@ -0,0 +1,33 @@
+package syntax
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// LabeledStmt is a loop named by a label, which break and continue
+// statements inside it may refer to.
+type LabeledStmt struct {
+	Label *Identifier
+	Colon *Position
+	Stmt  Statement
+}
+
+func NewLabeledStmt(label *Identifier, colon *Position, stmt Statement) *LabeledStmt {
+	return &LabeledStmt{
+		Label: label,
+		Colon: colon,
+		Stmt:  stmt,
+	}
+}
+
+func (ls *LabeledStmt) statementNode() {}
+func (ls *LabeledStmt) Pos() *Position {
+	return ls.Label.Pos()
+}
+
+func (ls *LabeledStmt) PrettyPrint(w *bytes.Buffer, indent int) {
+	addIndent(w, indent)
+	w.WriteString(fmt.Sprintf("LabeledStmt (%s): %s\n", ls.Label.Pos(), ls.Label.Name))
+	ls.Stmt.PrettyPrint(w, indent+1)
+}
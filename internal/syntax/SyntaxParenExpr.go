@@ -0,0 +1,34 @@
+package syntax
+
+import "bytes"
+
+type ParenExpr struct {
+	Lparen *Position
+	X      Expression
+	Rparen *Position
+}
+
+func NewParenExpr(lparen *Position, x Expression, rparen *Position) *ParenExpr {
+	return &ParenExpr{
+		Lparen: lparen,
+		X:      x,
+		Rparen: rparen,
+	}
+}
+
+func (p *ParenExpr) expressionNode() {}
+func (p *ParenExpr) Pos() *Position {
+	return p.Lparen
+}
+
+func (p *ParenExpr) End() *Position {
+	return p.Rparen.After(")")
+}
+
+func (p *ParenExpr) PrettyPrint(w *bytes.Buffer, indent int) {
+	addIndent(w, indent)
+	w.WriteString("ParenExpr:\n")
+	addIndent(w, indent+1)
+	w.WriteString("X:\n")
+	p.X.PrettyPrint(w, indent+2)
+}
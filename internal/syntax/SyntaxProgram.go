@@ -3,10 +3,14 @@ package syntax
 import (
 	"bytes"
 	"fmt"
+	"io"
+	"os"
+	"strconv"
 )
 
 type Program struct {
 	Statements []Statement
+	Comments   []*Comment // in source order
 }
 
 func NewProgram() *Program {
@@ -20,13 +24,25 @@ func (p *Program) AddStatement(stmt Statement) {
 }
 
 func (p *Program) PrettyPrint() {
+	p.Fprint(os.Stdout)
+}
+
+func (p *Program) Fprint(out io.Writer) {
 	var w bytes.Buffer
 	w.WriteString("File:\n")
 	for _, stmt := range p.Statements {
 		stmt.PrettyPrint(&w, 1)
 	}
+	if len(p.Comments) > 0 {
+		w.WriteString("Comments:\n")
+		for _, c := range p.Comments {
+			addIndent(&w, 1)
+			w.WriteString(fmt.Sprintf("%s (%s)\n", strconv.Quote(c.Text), c.Pos()))
+		}
+	}
+	w.WriteString("\n")
 
-	fmt.Println(w.String())
+	out.Write(w.Bytes())
 }
 
 func addIndent(w *bytes.Buffer, indent int) {
@@ -5,14 +5,16 @@ import "bytes"
 type RangeExpr struct {
 	Low  Expression
 	High Expression
+
+	// Kind is the name of the integer type of the bounds. The type checker
+	// sets it for a range a for statement iterates over.
 	Kind string
 }
 
-func NewRangeExpr(low Expression, high Expression, type_ string) *RangeExpr {
+func NewRangeExpr(low Expression, high Expression) *RangeExpr {
 	return &RangeExpr{
 		Low:  low,
 		High: high,
-		Kind: type_,
 	}
 }
 
@@ -21,6 +23,10 @@ func (re *RangeExpr) Pos() *Position {
 	return re.Low.Pos()
 }
 
+func (re *RangeExpr) End() *Position {
+	return re.High.End()
+}
+
 func (re *RangeExpr) PrettyPrint(w *bytes.Buffer, indent int) {
 	addIndent(w, indent)
 	w.WriteString("RangeExpr:\n")
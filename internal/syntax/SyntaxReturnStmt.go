@@ -7,13 +7,13 @@ import (
 
 type ReturnStmt struct {
 	ReturnPos *Position
-	Result    Expression
+	Results   []Expression
 }
 
-func NewReturnStmt(returnPos *Position, result Expression) *ReturnStmt {
+func NewReturnStmt(returnPos *Position, results []Expression) *ReturnStmt {
 	return &ReturnStmt{
 		ReturnPos: returnPos,
-		Result:    result,
+		Results:   results,
 	}
 }
 
@@ -25,5 +25,7 @@ func (rs *ReturnStmt) Pos() *Position {
 func (rs *ReturnStmt) PrettyPrint(w *bytes.Buffer, indent int) {
 	addIndent(w, indent)
 	w.WriteString(fmt.Sprintf("ReturnStmt (%s):\n", rs.ReturnPos))
-	rs.Result.PrettyPrint(w, indent+1)
+	for _, result := range rs.Results {
+		result.PrettyPrint(w, indent+1)
+	}
 }
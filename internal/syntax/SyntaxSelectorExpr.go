@@ -0,0 +1,36 @@
+package syntax
+
+import "bytes"
+
+// SelectorExpr selects the field Sel of the struct value X, as in `p.x`.
+type SelectorExpr struct {
+	X   Expression
+	Sel *Identifier
+}
+
+func NewSelectorExpr(x Expression, sel *Identifier) *SelectorExpr {
+	return &SelectorExpr{
+		X:   x,
+		Sel: sel,
+	}
+}
+
+func (s *SelectorExpr) expressionNode() {}
+func (s *SelectorExpr) Pos() *Position {
+	return s.X.Pos()
+}
+
+func (s *SelectorExpr) End() *Position {
+	return s.Sel.End()
+}
+
+func (s *SelectorExpr) PrettyPrint(w *bytes.Buffer, indent int) {
+	addIndent(w, indent)
+	w.WriteString("SelectorExpr:\n")
+	addIndent(w, indent+1)
+	w.WriteString("X:\n")
+	s.X.PrettyPrint(w, indent+2)
+	addIndent(w, indent+1)
+	w.WriteString("Sel:\n")
+	s.Sel.PrettyPrint(w, indent+2)
+}
@@ -0,0 +1,47 @@
+package syntax
+
+import "bytes"
+
+// StructType is the body of a struct declaration. Names declared together,
+// as in `x, y i32`, are separate fields sharing the same type identifier.
+type StructType struct {
+	Struct *Position
+	Fields []*Field
+	Rbrace *Position
+}
+
+func NewStructType(struct_ *Position, fields []*Field, rbrace *Position) *StructType {
+	return &StructType{
+		Struct: struct_,
+		Fields: fields,
+		Rbrace: rbrace,
+	}
+}
+
+func (st *StructType) Pos() *Position {
+	return st.Struct
+}
+
+func (st *StructType) End() *Position {
+	return st.Rbrace.After("}")
+}
+
+// Field returns the index of the field called name, or -1.
+func (st *StructType) Field(name string) int {
+	for i, f := range st.Fields {
+		if f.Name.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (st *StructType) PrettyPrint(w *bytes.Buffer, indent int) {
+	addIndent(w, indent)
+	w.WriteString("StructType:\n")
+	addIndent(w, indent+1)
+	w.WriteString("Fields:\n")
+	for _, field := range st.Fields {
+		field.PrettyPrint(w, indent+2)
+	}
+}
@@ -0,0 +1,39 @@
+package syntax
+
+import "bytes"
+
+// TupleAssignStmt assigns several values at once, as in a, b = b, a. Every
+// operand on both sides is evaluated before any variable is assigned.
+type TupleAssignStmt struct {
+	Lhs    []Expression
+	TokPos *Position
+	Rhs    []Expression
+}
+
+func NewTupleAssignStmt(lhs []Expression, tokPos *Position, rhs []Expression) *TupleAssignStmt {
+	return &TupleAssignStmt{
+		Lhs:    lhs,
+		TokPos: tokPos,
+		Rhs:    rhs,
+	}
+}
+
+func (s *TupleAssignStmt) statementNode() {}
+func (s *TupleAssignStmt) Pos() *Position {
+	return s.Lhs[0].Pos()
+}
+
+func (s *TupleAssignStmt) PrettyPrint(w *bytes.Buffer, indent int) {
+	addIndent(w, indent)
+	w.WriteString("TupleAssignStmt:\n")
+	addIndent(w, indent+1)
+	w.WriteString("Lhs:\n")
+	for _, x := range s.Lhs {
+		x.PrettyPrint(w, indent+2)
+	}
+	addIndent(w, indent+1)
+	w.WriteString("Rhs:\n")
+	for _, x := range s.Rhs {
+		x.PrettyPrint(w, indent+2)
+	}
+}
@@ -0,0 +1,37 @@
+package syntax
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// TypeStmt declares a named type, as in `type Point struct { x, y i32 }`.
+type TypeStmt struct {
+	TypePos *Position
+	Name    *Identifier
+	Type    *StructType
+}
+
+func NewTypeStmt(typePos *Position, name *Identifier, type_ *StructType) *TypeStmt {
+	return &TypeStmt{
+		TypePos: typePos,
+		Name:    name,
+		Type:    type_,
+	}
+}
+
+func (ts *TypeStmt) statementNode() {}
+func (ts *TypeStmt) Pos() *Position {
+	return ts.TypePos
+}
+
+func (ts *TypeStmt) PrettyPrint(w *bytes.Buffer, indent int) {
+	addIndent(w, indent)
+	w.WriteString(fmt.Sprintf("TypeStmt (%s):\n", ts.TypePos))
+	addIndent(w, indent+1)
+	w.WriteString("Name:\n")
+	ts.Name.PrettyPrint(w, indent+2)
+	addIndent(w, indent+1)
+	w.WriteString("Type:\n")
+	ts.Type.PrettyPrint(w, indent+2)
+}
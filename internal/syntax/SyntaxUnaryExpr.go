@@ -0,0 +1,38 @@
+package syntax
+
+import (
+	"bytes"
+)
+
+type UnaryExpr struct {
+	Op *Token
+	X  Expression
+}
+
+func NewUnaryExpr(op *Token, x Expression) *UnaryExpr {
+	return &UnaryExpr{
+		Op: op,
+		X:  x,
+	}
+}
+
+func (u *UnaryExpr) expressionNode() {}
+func (u *UnaryExpr) Pos() *Position {
+	return u.Op.Position
+}
+
+func (u *UnaryExpr) End() *Position {
+	return u.X.End()
+}
+
+func (u *UnaryExpr) PrettyPrint(w *bytes.Buffer, indent int) {
+	addIndent(w, indent)
+	w.WriteString("UnaryExpr:\n")
+	addIndent(w, indent+1)
+	w.WriteString("Op: ")
+	w.WriteString(u.Op.Literal)
+	w.WriteString("\n")
+	addIndent(w, indent+1)
+	w.WriteString("X:\n")
+	u.X.PrettyPrint(w, indent+2)
+}
@@ -7,14 +7,16 @@ import (
 
 type VarStatement struct {
 	Position *Position
+	Kind     string // var or const
 	Names    []*Identifier
 	Type     *Identifier
 	Values   []Expression
 }
 
-func NewVarStatement(position *Position, names []*Identifier, type_ *Identifier, values []Expression) *VarStatement {
+func NewVarStatement(position *Position, kind string, names []*Identifier, type_ *Identifier, values []Expression) *VarStatement {
 	return &VarStatement{
 		Position: position,
+		Kind:     kind,
 		Names:    names,
 		Type:     type_,
 		Values:   values,
@@ -0,0 +1,162 @@
+package syntax
+
+import (
+	"errors"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// IntValue returns the value of the integer literal lit. The prefixes 0x,
+// 0o and 0b select base 16, 8 and 2; any other literal is decimal, even with
+// leading zeros. Underscores are ignored.
+func IntValue(lit string) (*big.Int, bool) {
+	lit = strings.ReplaceAll(lit, "_", "")
+
+	base := 10
+	if len(lit) > 2 && lit[0] == '0' {
+		switch lit[1] {
+		case 'x', 'X':
+			base = 16
+		case 'o', 'O':
+			base = 8
+		case 'b', 'B':
+			base = 2
+		}
+		if base != 10 {
+			lit = lit[2:]
+		}
+	}
+
+	return new(big.Int).SetString(lit, base)
+}
+
+// Int64Value returns the integer literal lit as 64 bits, treating values
+// beyond the range of int64 as the bit pattern of a uint64.
+func Int64Value(lit string) (int64, bool) {
+	v, ok := IntValue(lit)
+	switch {
+	case !ok:
+		return 0, false
+	case v.IsInt64():
+		return v.Int64(), true
+	case v.IsUint64():
+		return int64(v.Uint64()), true
+	default:
+		return 0, false
+	}
+}
+
+// FloatValue returns the value of the numeric literal lit as a float64.
+// Integer literals are accepted too, since integer constants may be given a
+// floating-point type. A value too large for a float64 is returned as an
+// infinity.
+func FloatValue(lit string) (float64, bool) {
+	lit = strings.ReplaceAll(lit, "_", "")
+
+	v, err := strconv.ParseFloat(lit, 64)
+	if err == nil || errors.Is(err, strconv.ErrRange) {
+		return v, true
+	}
+
+	i, ok := IntValue(lit)
+	if !ok {
+		return 0, false
+	}
+	v, _ = new(big.Float).SetInt(i).Float64()
+	return v, true
+}
+
+// Unescape decodes the escape sequence at the start of s, which follows a
+// backslash in an interpreted string literal. It returns the decoded bytes
+// and the length of the sequence, or a message describing why it is
+// invalid.
+func Unescape(s string) (value string, n int, msg string) {
+	if s == "" {
+		return "", 0, "escape sequence not terminated"
+	}
+
+	switch s[0] {
+	case 'n':
+		return "\n", 1, ""
+	case 't':
+		return "\t", 1, ""
+	case 'r':
+		return "\r", 1, ""
+	case '"':
+		return "\"", 1, ""
+	case '\\':
+		return "\\", 1, ""
+	case 'u':
+		return unescapeUnicode(s)
+	default:
+		return "", 1, "unknown escape sequence"
+	}
+}
+
+// unescapeUnicode decodes \u{XXXX}: one to six hex digits naming a Unicode
+// scalar value.
+func unescapeUnicode(s string) (string, int, string) {
+	if len(s) < 2 || s[1] != '{' {
+		return "", 1, "missing { in \\u{...} escape"
+	}
+
+	// Stop at a closing quote so an unclosed escape does not swallow it.
+	end := strings.IndexAny(s, "}\"")
+	if end < 0 || s[end] != '}' {
+		if end < 0 {
+			end = len(s)
+		}
+		return "", end, "missing } in \\u{...} escape"
+	}
+
+	digits := s[2:end]
+	if digits == "" || len(digits) > 6 {
+		return "", end + 1, "\\u{...} escape must have one to six hex digits"
+	}
+
+	v, ok := new(big.Int).SetString(digits, 16)
+	if !ok {
+		return "", end + 1, "invalid hex digit in \\u{...} escape"
+	}
+
+	r := rune(v.Int64())
+	if r > utf8.MaxRune || 0xD800 <= r && r < 0xE000 {
+		return "", end + 1, "escape sequence is invalid Unicode code point"
+	}
+
+	return string(r), end + 1, ""
+}
+
+// StringValue returns the value of the string literal lit, including its
+// quotes. Raw strings are taken as written, less carriage returns.
+func StringValue(lit string) (string, bool) {
+	if len(lit) < 2 {
+		return "", false
+	}
+
+	if lit[0] == '`' {
+		return strings.ReplaceAll(lit[1:len(lit)-1], "\r", ""), true
+	}
+
+	var w strings.Builder
+	s := lit[1 : len(lit)-1]
+	for s != "" {
+		i := strings.IndexByte(s, '\\')
+		if i < 0 {
+			w.WriteString(s)
+			break
+		}
+
+		w.WriteString(s[:i])
+		v, n, msg := Unescape(s[i+1:])
+		if msg != "" {
+			return "", false
+		}
+		w.WriteString(v)
+		s = s[i+1+n:]
+	}
+
+	return w.String(), true
+}
@@ -11,6 +11,7 @@ const (
 	ObjKindType
 	ObjKindConst
 	ObjKindField
+	ObjKindBuiltin
 )
 
 func (ok ObjectKind) String() string {
@@ -25,6 +26,8 @@ func (ok ObjectKind) String() string {
 		return "const"
 	case ObjKindField:
 		return "field"
+	case ObjKindBuiltin:
+		return "builtin"
 	default:
 		return "invalid"
 	}
@@ -52,7 +55,7 @@ func (o *Object) String() string {
 	switch decl := o.Decl.(type) {
 	case *VarStatement:
 		for i, name := range decl.Names {
-			if name.Name == n {
+			if name.Name == n && i < len(decl.Values) {
 				v = decl.Values[i]
 				return fmt.Sprintf("%s %s = %s", k, n, v)
 			}
@@ -62,3 +65,35 @@ func (o *Object) String() string {
 		return n
 	}
 }
+
+// Ident returns the identifier that declared o, or nil if o is predeclared
+// or its declaration does not name it.
+func (o *Object) Ident() *Identifier {
+	switch decl := o.Decl.(type) {
+	case *VarStatement:
+		for _, name := range decl.Names {
+			if name.Name == o.Name {
+				return name
+			}
+		}
+	case *FuncStmt:
+		if decl.Name.Name == o.Name {
+			return decl.Name
+		}
+	case *ForRangeStmt:
+		if decl.Key.Name == o.Name {
+			return decl.Key
+		}
+		if decl.Value != nil && decl.Value.Name == o.Name {
+			return decl.Value
+		}
+	case *TypeStmt:
+		if o.Kind != ObjKindField && decl.Name.Name == o.Name {
+			return decl.Name
+		}
+		if i := decl.Type.Field(o.Name); o.Kind == ObjKindField && i >= 0 {
+			return decl.Type.Fields[i].Name
+		}
+	}
+	return nil
+}
@@ -2,6 +2,9 @@ package syntax
 
 import "fmt"
 
+// Position is a location in a source file. Offset counts bytes from the
+// start of the file; Line and Column start at 1 and Column counts
+// characters, so a multi-byte UTF-8 sequence advances it by one.
 type Position struct {
 	Offset   int
 	Line     int
@@ -21,3 +24,19 @@ func NewPosition(offset int, line int, column int, filename string) *Position {
 func (p *Position) String() string {
 	return fmt.Sprintf("%s:%d:%d", p.Filename, p.Line, p.Column)
 }
+
+// After returns the position just past text, which starts at p in the
+// source.
+func (p *Position) After(text string) *Position {
+	end := *p
+	end.Offset += len(text)
+	for _, r := range text {
+		if r == '\n' {
+			end.Line++
+			end.Column = 1
+		} else {
+			end.Column++
+		}
+	}
+	return &end
+}
@@ -0,0 +1,86 @@
+package syntax
+
+import (
+	"bytes"
+	"sort"
+	"unicode/utf8"
+)
+
+// SourceFile is the text of a source file together with the offset at which
+// each of its lines starts, so that offsets can be mapped back to positions
+// and lines of text without rescanning the file.
+type SourceFile struct {
+	Name string
+	Src  []byte
+
+	lines []int // offset of the first byte of each line
+}
+
+func NewSourceFile(name string, src []byte) *SourceFile {
+	lines := []int{0}
+	for i, b := range src {
+		if b == '\n' {
+			lines = append(lines, i+1)
+		}
+	}
+
+	return &SourceFile{
+		Name:  name,
+		Src:   src,
+		lines: lines,
+	}
+}
+
+// bom is the UTF-8 encoding of a byte order mark, which may start a file
+// and is not counted as a column.
+var bom = []byte{0xEF, 0xBB, 0xBF}
+
+// LineCount returns the number of lines in f. A file ending in a newline
+// has an empty last line.
+func (f *SourceFile) LineCount() int {
+	return len(f.lines)
+}
+
+// LineStart returns the offset of the first byte of line, counting from 1,
+// or -1 if f has no such line.
+func (f *SourceFile) LineStart(line int) int {
+	if line < 1 || line > len(f.lines) {
+		return -1
+	}
+	return f.lines[line-1]
+}
+
+// Line returns the text of line, counting from 1, without its line ending.
+// It returns "" if f has no such line.
+func (f *SourceFile) Line(line int) string {
+	start := f.LineStart(line)
+	if start < 0 {
+		return ""
+	}
+
+	end := len(f.Src)
+	if line < len(f.lines) {
+		end = f.lines[line] - 1
+	}
+
+	return string(bytes.TrimSuffix(f.Src[start:end], []byte{'\r'}))
+}
+
+// Position returns the position of the byte at offset, which is clamped to
+// the bounds of the file. Columns count characters like the lexer does.
+func (f *SourceFile) Position(offset int) *Position {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > len(f.Src) {
+		offset = len(f.Src)
+	}
+
+	i := sort.Search(len(f.lines), func(i int) bool { return f.lines[i] > offset }) - 1
+	start := f.lines[i]
+	if i == 0 && bytes.HasPrefix(f.Src, bom) && offset >= len(bom) {
+		start = len(bom)
+	}
+
+	return NewPosition(offset, i+1, utf8.RuneCount(f.Src[start:offset])+1, f.Name)
+}
@@ -12,7 +12,10 @@ type Statement interface {
 	statementNode()
 }
 
+// Expression is a node that produces a value. End is the position just past
+// the expression in the source.
 type Expression interface {
 	Node
+	End() *Position
 	expressionNode()
 }
@@ -10,19 +10,50 @@ const (
 	TokenComment                  // Comment
 
 	// Identifiers and basic type literals
-	TokenIdent // main
-	TokenInt   // 12345
+	TokenIdent  // main
+	TokenInt    // 12345
+	TokenFloat  // 123.45
+	TokenString // "abc"
 
 	// Operators and delimiters
-	TokenAssign // =
-	TokenStar   // *
-	TokenSlash  // /
-	TokenPlus   // +
-	TokenMinus  // -
-	TokenGt     // >
-	TokenLt     // <
-	TokenArrow  // ->
-	TokenInfer  // <-
+	TokenAssign  // =
+	TokenStar    // *
+	TokenSlash   // /
+	TokenPercent // %
+	TokenPlus    // +
+	TokenMinus   // -
+	TokenAnd     // &
+	TokenOr      // |
+	TokenXor     // ^
+	TokenShl     // <<
+	TokenShr     // >>
+	TokenAndNot  // &^
+	TokenGt      // >
+	TokenLt      // <
+	TokenArrow   // ->
+	TokenInfer   // <-
+
+	TokenPlusAssign    // +=
+	TokenMinusAssign   // -=
+	TokenStarAssign    // *=
+	TokenSlashAssign   // /=
+	TokenPercentAssign // %=
+	TokenAndAssign     // &=
+	TokenOrAssign      // |=
+	TokenXorAssign     // ^=
+	TokenShlAssign     // <<=
+	TokenShrAssign     // >>=
+	TokenAndNotAssign  // &^=
+	TokenInc           // ++
+	TokenDec           // --
+
+	TokenEq    // ==
+	TokenNotEq // !=
+	TokenLtEq  // <=
+	TokenGtEq  // >=
+	TokenLAnd  // &&
+	TokenLOr   // ||
+	TokenNot   // !
 
 	TokenSemicolon // ;
 	TokenColon     // :
@@ -33,15 +64,21 @@ const (
 	TokenRParen    // )
 	TokenLBrace    // {
 	TokenRBrace    // }
+	TokenLBrack    // [
+	TokenRBrack    // ]
 
 	// Keywords
-	TokenFunc   // fn
-	TokenVar    // var
-	TokenConst  // const
-	TokenReturn // ret
-	TokenFor    // for
-	TokenIf     // if
-	TokenElse   // else
+	TokenFunc     // fn
+	TokenVar      // var
+	TokenConst    // const
+	TokenReturn   // ret
+	TokenFor      // for
+	TokenIf       // if
+	TokenElse     // else
+	TokenBreak    // break
+	TokenContinue // continue
+	TokenType_    // type
+	TokenStruct   // struct
 )
 
 var tokenNames = map[TokenType]string{
@@ -49,18 +86,49 @@ var tokenNames = map[TokenType]string{
 	TokenEOF:     "EOF",
 	TokenComment: "COMMENT",
 
-	TokenIdent: "IDENT",
-	TokenInt:   "INT",
-
-	TokenAssign: "=",
-	TokenStar:   "*",
-	TokenSlash:  "/",
-	TokenPlus:   "+",
-	TokenMinus:  "-",
-	TokenGt:     ">",
-	TokenLt:     "<",
-	TokenArrow:  "->",
-	TokenInfer:  "<-",
+	TokenIdent:  "IDENT",
+	TokenInt:    "INT",
+	TokenFloat:  "FLOAT",
+	TokenString: "STRING",
+
+	TokenAssign:  "=",
+	TokenStar:    "*",
+	TokenSlash:   "/",
+	TokenPercent: "%",
+	TokenPlus:    "+",
+	TokenMinus:   "-",
+	TokenAnd:     "&",
+	TokenOr:      "|",
+	TokenXor:     "^",
+	TokenShl:     "<<",
+	TokenShr:     ">>",
+	TokenAndNot:  "&^",
+	TokenGt:      ">",
+	TokenLt:      "<",
+	TokenArrow:   "->",
+	TokenInfer:   "<-",
+
+	TokenPlusAssign:    "+=",
+	TokenMinusAssign:   "-=",
+	TokenStarAssign:    "*=",
+	TokenSlashAssign:   "/=",
+	TokenPercentAssign: "%=",
+	TokenAndAssign:     "&=",
+	TokenOrAssign:      "|=",
+	TokenXorAssign:     "^=",
+	TokenShlAssign:     "<<=",
+	TokenShrAssign:     ">>=",
+	TokenAndNotAssign:  "&^=",
+	TokenInc:           "++",
+	TokenDec:           "--",
+
+	TokenEq:    "==",
+	TokenNotEq: "!=",
+	TokenLtEq:  "<=",
+	TokenGtEq:  ">=",
+	TokenLAnd:  "&&",
+	TokenLOr:   "||",
+	TokenNot:   "!",
 
 	TokenSemicolon: ";",
 	TokenColon:     "COLON",
@@ -71,6 +139,8 @@ var tokenNames = map[TokenType]string{
 	TokenRParen:    ")",
 	TokenLBrace:    "{",
 	TokenRBrace:    "}",
+	TokenLBrack:    "[",
+	TokenRBrack:    "]",
 
 	TokenFunc:   "fn",
 	TokenVar:    "var",
@@ -79,6 +149,11 @@ var tokenNames = map[TokenType]string{
 	TokenFor:    "for",
 	TokenIf:     "if",
 	TokenElse:   "else",
+
+	TokenBreak:    "break",
+	TokenContinue: "continue",
+	TokenType_:    "type",
+	TokenStruct:   "struct",
 }
 
 func (tt TokenType) String() string {
@@ -89,14 +164,41 @@ func (tt TokenType) String() string {
 	return "UNKNOWN"
 }
 
+// compoundOps maps each compound assignment operator to the binary operator
+// it applies.
+var compoundOps = map[TokenType]TokenType{
+	TokenPlusAssign:    TokenPlus,
+	TokenMinusAssign:   TokenMinus,
+	TokenStarAssign:    TokenStar,
+	TokenSlashAssign:   TokenSlash,
+	TokenPercentAssign: TokenPercent,
+	TokenAndAssign:     TokenAnd,
+	TokenOrAssign:      TokenOr,
+	TokenXorAssign:     TokenXor,
+	TokenShlAssign:     TokenShl,
+	TokenShrAssign:     TokenShr,
+	TokenAndNotAssign:  TokenAndNot,
+}
+
+// CompoundOp returns the binary operator applied by the compound assignment
+// operator tt, such as + for +=. It reports false if tt is not one.
+func CompoundOp(tt TokenType) (TokenType, bool) {
+	op, ok := compoundOps[tt]
+	return op, ok
+}
+
 var keywords = map[string]TokenType{
-	"fn":    TokenFunc,
-	"var":   TokenVar,
-	"const": TokenConst,
-	"ret":   TokenReturn,
-	"for":   TokenFor,
-	"if":    TokenIf,
-	"else":  TokenElse,
+	"fn":       TokenFunc,
+	"var":      TokenVar,
+	"const":    TokenConst,
+	"ret":      TokenReturn,
+	"for":      TokenFor,
+	"if":       TokenIf,
+	"else":     TokenElse,
+	"break":    TokenBreak,
+	"continue": TokenContinue,
+	"type":     TokenType_,
+	"struct":   TokenStruct,
 }
 
 type Token struct {
@@ -136,3 +238,8 @@ func LookupIdent(lit string) TokenType {
 
 	return TokenIdent
 }
+
+// End returns the position just past the token.
+func (t *Token) End() *Position {
+	return t.Position.After(t.Literal)
+}
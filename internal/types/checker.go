@@ -0,0 +1,1399 @@
+package types
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/danecwalker/hippo/internal/diag"
+	"github.com/danecwalker/hippo/internal/parse"
+	"github.com/danecwalker/hippo/internal/syntax"
+)
+
+type Checker struct {
+	// Types records the type of every expression that was checked.
+	Types map[syntax.Expression]Type
+
+	objects   map[*syntax.Object]Type
+	consts    map[syntax.Expression]*big.Int
+	constVals map[*syntax.Object]*big.Int // values of integer constants
+	scope     *parse.Scope
+	sig       *Signature
+
+	errors []*Error
+}
+
+func NewChecker() *Checker {
+	c := &Checker{
+		Types:     make(map[syntax.Expression]Type),
+		objects:   make(map[*syntax.Object]Type),
+		consts:    make(map[syntax.Expression]*big.Int),
+		constVals: make(map[*syntax.Object]*big.Int),
+		scope:     parse.NewScope(parse.Universe),
+	}
+
+	for _, obj := range parse.Universe.Objects {
+		if t := LookupBasic(obj.Type); t != nil {
+			c.objects[obj] = t
+		}
+	}
+
+	return c
+}
+
+func (c *Checker) Errors() []*Error {
+	return c.errors
+}
+
+func (c *Checker) error(err *Error) {
+	c.errors = append(c.errors, err)
+}
+
+// TypeOf returns the type recorded for obj, or nil if it has none.
+func (c *Checker) TypeOf(obj *syntax.Object) Type {
+	return c.objects[obj]
+}
+
+// Check resolves the type of every declaration and expression in prog.
+// Types and then functions are declared before any global so that they may
+// be used regardless of declaration order.
+func (c *Checker) Check(prog *syntax.Program) {
+	var types []*syntax.TypeStmt
+	for _, stmt := range prog.Statements {
+		if ts, ok := stmt.(*syntax.TypeStmt); ok {
+			types = append(types, ts)
+		}
+	}
+	c.typeStmts(types)
+
+	for _, stmt := range prog.Statements {
+		if fn, ok := stmt.(*syntax.FuncStmt); ok {
+			c.declareFunc(fn)
+		}
+	}
+
+	for _, stmt := range prog.Statements {
+		switch stmt := stmt.(type) {
+		case *syntax.FuncStmt, *syntax.TypeStmt:
+		case *syntax.VarStatement:
+			c.varStmt(stmt)
+		default:
+			c.error(NewError(stmt.Pos(), "only type, variable and function declarations are allowed at the top level"))
+		}
+	}
+
+	for _, stmt := range prog.Statements {
+		if fn, ok := stmt.(*syntax.FuncStmt); ok {
+			c.funcBody(fn)
+		}
+	}
+}
+
+func (c *Checker) openScope() {
+	c.scope = parse.NewScope(c.scope)
+}
+
+func (c *Checker) closeScope() {
+	c.scope = c.scope.Parent
+}
+
+// declare binds ident in the current scope, reusing the object the parser
+// attached to it when there is one.
+func (c *Checker) declare(ident *syntax.Identifier, kind syntax.ObjectKind, decl syntax.Node, t Type) *syntax.Object {
+	obj := ident.Obj
+	if obj == nil || obj.Name != ident.Name {
+		obj = syntax.NewObject(kind, ident.Name, decl)
+		ident.Obj = obj
+	}
+
+	if prev, ok := c.scope.Objects[ident.Name]; ok {
+		c.error(NewRedeclaredError(ident.Pos(), ident.Name, prev))
+	}
+
+	c.scope.Insert(obj)
+	c.objects[obj] = t
+	if !IsInvalid(t) {
+		obj.Type = t.String()
+	}
+	return obj
+}
+
+func (c *Checker) resolveType(ident *syntax.Identifier) Type {
+	if ident.Obj != nil {
+		if at, ok := ident.Obj.Decl.(*syntax.ArrayType); ok {
+			return c.arrayType(ident.Obj, at)
+		}
+	}
+
+	obj := c.scope.Lookup(ident.Name)
+	if obj == nil {
+		c.error(c.undefined(ident, true))
+		return Typ[Invalid]
+	}
+
+	if obj.Kind != syntax.ObjKindType {
+		c.error(NewNotATypeError(ident.Pos(), ident.Name))
+		return Typ[Invalid]
+	}
+
+	ident.Obj = obj
+	return c.objects[obj]
+}
+
+// arrayType resolves the array or slice type at, whose object the parser
+// made. The object is renamed after the type, so that later stages see
+// [3]i32 however the length was written.
+func (c *Checker) arrayType(obj *syntax.Object, at *syntax.ArrayType) Type {
+	if t, ok := c.objects[obj]; ok {
+		return t
+	}
+
+	t := Type(Typ[Invalid])
+	elem := c.resolveType(at.Elem)
+	if at.Len == nil {
+		t = NewSlice(elem)
+	} else if n := c.arrayLength(at.Len); n >= 0 {
+		t = NewArray(n, elem)
+	}
+
+	if IsInvalid(elem) {
+		t = Typ[Invalid]
+	}
+	c.objects[obj] = t
+	if !IsInvalid(t) {
+		obj.Name = t.String()
+		obj.Type = obj.Name
+	}
+	return t
+}
+
+// maxLen is the largest length of an array, which len reports as an i32.
+const maxLen = 1<<31 - 1
+
+// arrayLength returns the length of an array type, which must be a
+// non-negative integer constant, or -1 after reporting an error.
+func (c *Checker) arrayLength(expr syntax.Expression) int64 {
+	t := c.value(expr)
+	if IsInvalid(t) {
+		return -1
+	}
+
+	v, ok := c.constant(expr)
+	if !ok || !IsInteger(t) {
+		c.error(NewArrayLengthError(expr.Pos(), "array length "+exprString(expr)+" must be an integer constant").WithEnd(expr.End()))
+		return -1
+	}
+	if IsUntyped(t) {
+		c.convertUntyped(expr, Typ[I64])
+	}
+
+	if v.Sign() < 0 || v.Cmp(big.NewInt(maxLen)) > 0 {
+		c.error(NewArrayLengthError(expr.Pos(), "invalid array length "+v.String()).WithEnd(expr.End()))
+		return -1
+	}
+	return v.Int64()
+}
+
+// typeStmts declares the struct types of stmts and then resolves their
+// fields, so that the fields may refer to any of them.
+func (c *Checker) typeStmts(stmts []*syntax.TypeStmt) {
+	for _, ts := range stmts {
+		c.declare(ts.Name, syntax.ObjKindType, ts, NewStruct(ts.Name.Name))
+	}
+
+	for _, ts := range stmts {
+		s := c.objects[ts.Name.Obj].(*Struct)
+		for i, f := range ts.Type.Fields {
+			if first := ts.Type.Field(f.Name.Name); first != i {
+				prev := ts.Type.Fields[first].Name
+				c.error(NewDuplicateFieldError(f.Name.Pos(), f.Name.Name).WithLabel(prev.Pos(), prev.End(), "previously declared here"))
+				continue
+			}
+
+			t := c.resolveType(f.Type)
+			obj := syntax.NewObject(syntax.ObjKindField, f.Name.Name, ts)
+			if !IsInvalid(t) {
+				obj.Type = t.String()
+			}
+			f.Name.Obj = obj
+			c.objects[obj] = t
+			s.Fields = append(s.Fields, &Field{Name: f.Name.Name, Type: t})
+		}
+	}
+
+	for _, ts := range stmts {
+		s := c.objects[ts.Name.Obj].(*Struct)
+		if contains(s, s, map[*Struct]bool{}) {
+			c.error(NewRecursiveTypeError(ts.Name.Pos(), ts.Name.Name))
+		}
+	}
+}
+
+// contains reports whether a value of struct type t holds a value of type
+// s in one of its fields, however deeply nested. A struct that contains
+// itself would be infinitely large.
+func contains(t *Struct, s *Struct, seen map[*Struct]bool) bool {
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+
+	for _, f := range t.Fields {
+		elem := f.Type
+		for {
+			a, ok := elem.(*Array)
+			if !ok {
+				break
+			}
+			elem = a.Elem
+		}
+
+		ft, ok := elem.(*Struct)
+		if ok && (ft == s || contains(ft, s, seen)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Checker) signature(ft *syntax.FuncType) *Signature {
+	var params []Type
+	for _, field := range ft.Params {
+		t := c.resolveType(field.Type)
+		for range field.Names {
+			params = append(params, t)
+		}
+	}
+
+	var results []Type
+	for _, result := range ft.Results {
+		results = append(results, c.resolveType(result))
+	}
+
+	return NewSignature(params, results)
+}
+
+func (c *Checker) declareFunc(fn *syntax.FuncStmt) {
+	sig := c.signature(fn.Type)
+	c.declare(fn.Name, syntax.ObjKindFunc, fn, sig)
+
+	if fn.Name.Name == "main" && c.scope.Parent == parse.Universe {
+		if len(sig.Params) > 0 {
+			c.error(NewError(fn.Name.Pos(), "func main must have no arguments"))
+		}
+		if len(sig.Results) > 1 || len(sig.Results) == 1 && !IsInvalid(sig.Results[0]) && !IsInteger(sig.Results[0]) {
+			c.error(NewError(fn.Name.Pos(), "func main must return nothing or a single integer exit status"))
+		}
+	}
+}
+
+func (c *Checker) funcBody(fn *syntax.FuncStmt) {
+	sig, ok := c.objects[fn.Name.Obj].(*Signature)
+	if !ok {
+		return
+	}
+
+	saved := c.sig
+	c.sig = sig
+	c.openScope()
+	defer func() {
+		c.closeScope()
+		c.sig = saved
+	}()
+
+	i := 0
+	for _, field := range fn.Type.Params {
+		for _, name := range field.Names {
+			c.declare(name, syntax.ObjKindVar, declOf(name, fn), sig.Params[i])
+			i++
+		}
+	}
+
+	for _, stmt := range fn.Body.Stmts {
+		c.stmt(stmt)
+	}
+
+	if len(sig.Results) > 0 && !isTerminating(fn.Body) {
+		c.error(NewMissingReturnError(fn.Body.End()))
+	}
+}
+
+func (c *Checker) stmt(stmt syntax.Statement) {
+	switch stmt := stmt.(type) {
+	case *syntax.VarStatement:
+		c.varStmt(stmt)
+	case *syntax.FuncStmt:
+		c.declareFunc(stmt)
+		c.funcBody(stmt)
+	case *syntax.TypeStmt:
+		c.typeStmts([]*syntax.TypeStmt{stmt})
+	case *syntax.ReturnStmt:
+		c.returnStmt(stmt)
+	case *syntax.BlockStmt:
+		c.block(stmt)
+	case *syntax.IfStmt:
+		c.cond(stmt.Cond, "if")
+		c.block(stmt.Consequence)
+		if stmt.Alternative != nil {
+			c.stmt(stmt.Alternative)
+		}
+	case *syntax.ForRangeStmt:
+		c.forRangeStmt(stmt)
+	case *syntax.WhileStmt:
+		c.cond(stmt.Cond, "for")
+		c.block(stmt.Body)
+	case *syntax.ForLoopStmt:
+		c.openScope()
+		if stmt.Init != nil {
+			c.stmt(stmt.Init)
+		}
+		if stmt.Cond != nil {
+			c.cond(stmt.Cond, "for")
+		}
+		if stmt.Post != nil {
+			c.stmt(stmt.Post)
+		}
+		c.block(stmt.Body)
+		c.closeScope()
+	case *syntax.LabeledStmt:
+		c.stmt(stmt.Stmt)
+	case *syntax.BranchStmt:
+		// The parser has checked that the statement is inside a loop
+		// with its label.
+	case *syntax.TupleAssignStmt:
+		c.tupleAssign(stmt)
+	case *syntax.IncDecStmt:
+		if t := c.addressable(stmt.X); !IsInvalid(t) && !IsNumeric(t) {
+			c.error(NewOperatorError(stmt.TokPos, stmt.Tok.String(), t).WithEnd(stmt.TokPos.After(stmt.Tok.String())))
+		}
+	case *syntax.ExpressionStmt:
+		if t := c.expr(stmt.X); IsUntyped(t) {
+			c.convertUntyped(stmt.X, Default(t))
+		}
+	default:
+		c.error(NewError(stmt.Pos(), "unexpected statement"))
+	}
+}
+
+func (c *Checker) block(block *syntax.BlockStmt) {
+	c.openScope()
+	for _, stmt := range block.Stmts {
+		c.stmt(stmt)
+	}
+	c.closeScope()
+}
+
+func (c *Checker) cond(expr syntax.Expression, stmt string) {
+	t := c.value(expr)
+	if !IsInvalid(t) && !IsBoolean(t) {
+		c.error(NewConditionError(expr.Pos(), t, stmt).WithEnd(expr.End()))
+	}
+}
+
+func (c *Checker) varStmt(stmt *syntax.VarStatement) {
+	var declared Type
+	if stmt.Type != nil {
+		declared = c.resolveType(stmt.Type)
+	}
+
+	kind := syntax.ObjKindVar
+	if stmt.Kind == "const" {
+		kind = syntax.ObjKindConst
+		if len(stmt.Values) == 0 {
+			c.error(NewError(stmt.Pos(), "missing init expr for const declaration"))
+		}
+	}
+
+	var exprs []syntax.Expression
+	var values []Type
+	if len(stmt.Values) > 0 {
+		exprs, values = c.values(stmt.Values)
+		if len(values) != len(stmt.Names) {
+			c.error(NewAssignmentCountError(stmt.Pos(), len(stmt.Names), len(values)))
+		}
+	}
+
+	types := make([]Type, len(stmt.Names))
+	for i := range stmt.Names {
+		switch {
+		case i < len(values):
+			t := values[i]
+			if declared != nil {
+				c.assignable(exprs[i], t, declared, "variable declaration")
+				t = declared
+			} else if kind == syntax.ObjKindConst && isBasic(t, UntypedInt) {
+				// The constant stays untyped and is converted where it
+				// is used; its storage holds it in the first type wide
+				// enough.
+				c.convertUntyped(exprs[i], c.storage(exprs[i]))
+			} else if IsUntyped(t) {
+				t = Default(t)
+				c.convertUntyped(exprs[i], t)
+			}
+			types[i] = t
+		case declared != nil:
+			types[i] = declared
+		default:
+			types[i] = Typ[Invalid]
+			if len(values) == 0 {
+				c.error(NewError(stmt.Names[i].Pos(), "missing type or init expr for "+stmt.Names[i].Name))
+			}
+		}
+	}
+
+	for i, name := range stmt.Names {
+		if _, ok := types[i].(*Basic); !ok && kind == syntax.ObjKindConst {
+			c.error(NewError(name.Pos(), "invalid constant type "+types[i].String()).WithEnd(name.End()))
+			types[i] = Typ[Invalid]
+		}
+		obj := c.declare(name, kind, stmt, types[i])
+		if kind == syntax.ObjKindConst && i < len(values) && len(exprs) == len(stmt.Names) {
+			if v, ok := c.constant(exprs[i]); ok && IsInteger(types[i]) && Representable(v, types[i]) {
+				c.constVals[obj] = v
+			}
+			if IsUntyped(types[i]) {
+				obj.Type = c.Types[exprs[i]].String()
+			}
+		}
+	}
+}
+
+// storage returns the type in which the untyped integer constant expr is
+// stored: its default type, or the first wider one that holds its value.
+func (c *Checker) storage(expr syntax.Expression) Type {
+	v, ok := c.constant(expr)
+	if !ok {
+		return Default(c.Types[expr])
+	}
+	for _, t := range []Type{Default(c.Types[expr]), Typ[I64], Typ[U64]} {
+		if Representable(v, t) {
+			return t
+		}
+	}
+	return Default(c.Types[expr])
+}
+
+func (c *Checker) returnStmt(stmt *syntax.ReturnStmt) {
+	if c.sig == nil {
+		c.error(NewError(stmt.Pos(), "return outside function"))
+		return
+	}
+
+	exprs, results := c.values(stmt.Results)
+
+	if len(results) != len(c.sig.Results) {
+		c.error(NewReturnCountError(stmt.Pos(), len(c.sig.Results), len(results)))
+		return
+	}
+
+	for i, t := range results {
+		c.assignable(exprs[i], t, c.sig.Results[i], "return statement")
+	}
+}
+
+// values checks a list of expressions that supply values to a list of
+// targets. A single call returning several values is expanded into one
+// entry per result, all attributed to the call expression.
+func (c *Checker) values(list []syntax.Expression) ([]syntax.Expression, []Type) {
+	if len(list) == 1 {
+		if tuple, ok := c.expr(list[0]).(*Tuple); ok && len(tuple.Types) > 1 {
+			exprs := make([]syntax.Expression, len(tuple.Types))
+			for i := range exprs {
+				exprs[i] = list[0]
+			}
+			return exprs, tuple.Types
+		}
+		return list, []Type{c.single(list[0], c.Types[list[0]])}
+	}
+
+	types := make([]Type, len(list))
+	for i, expr := range list {
+		types[i] = c.value(expr)
+	}
+	return list, types
+}
+
+func (c *Checker) forRangeStmt(stmt *syntax.ForRangeStmt) {
+	c.openScope()
+	defer c.closeScope()
+
+	key, value := Type(Typ[Invalid]), Type(Typ[Invalid])
+	if _, ok := stmt.X.(*syntax.RangeExpr); ok {
+		key = c.expr(stmt.X)
+	} else {
+		switch t := c.value(stmt.X).(type) {
+		case *Array:
+			key, value = t.Elem, t.Elem
+		case *Slice:
+			key, value = t.Elem, t.Elem
+		default:
+			if !IsInvalid(t) {
+				c.error(NewCannotRangeError(stmt.X.Pos(), stmt.X, t).WithEnd(stmt.X.End()))
+			}
+		}
+		// With two variables the first is the index of the element.
+		if stmt.Value != nil && !IsInvalid(key) {
+			key = Typ[I32]
+		}
+	}
+
+	c.declare(stmt.Key, syntax.ObjKindVar, declOf(stmt.Key, stmt), key)
+	if stmt.Value != nil {
+		c.declare(stmt.Value, syntax.ObjKindVar, declOf(stmt.Value, stmt), value)
+	}
+
+	for _, s := range stmt.Body.Stmts {
+		c.stmt(s)
+	}
+}
+
+// declOf returns the declaration the parser recorded for ident, falling
+// back to the enclosing statement.
+func declOf(ident *syntax.Identifier, stmt syntax.Node) syntax.Node {
+	if ident.Obj != nil && ident.Obj.Decl != nil {
+		return ident.Obj.Decl
+	}
+	return stmt
+}
+
+// assignable reports an error unless a value of type t may be stored in a
+// location of type target.
+func (c *Checker) assignable(expr syntax.Expression, t Type, target Type, context string) bool {
+	if IsInvalid(t) || IsInvalid(target) || Identical(t, target) {
+		return true
+	}
+
+	if IsUntyped(t) && untypedFits(t, target) {
+		c.convertUntyped(expr, target)
+		return true
+	}
+
+	err := NewAssignmentError(expr.Pos(), t, target, context).WithEnd(expr.End())
+	if IsNumeric(t) && !IsUntyped(t) && IsNumeric(target) {
+		err.WithFix(fmt.Sprintf("convert to %s", target),
+			diag.NewEdit(expr.Pos(), expr.Pos(), target.String()+"("),
+			diag.NewEdit(expr.End(), expr.End(), ")"))
+	}
+	c.error(err)
+	return false
+}
+
+// value checks expr and requires it to produce exactly one value.
+func (c *Checker) value(expr syntax.Expression) Type {
+	return c.single(expr, c.expr(expr))
+}
+
+func (c *Checker) single(expr syntax.Expression, t Type) Type {
+	if tuple, ok := t.(*Tuple); ok {
+		if len(tuple.Types) == 0 {
+			c.error(NewNoValueError(expr.Pos(), exprString(expr)).WithEnd(expr.End()))
+		} else {
+			c.error(NewError(expr.Pos(), "multiple-value "+exprString(expr)+" in single-value context").WithEnd(expr.End()))
+		}
+		return Typ[Invalid]
+	}
+	return t
+}
+
+func (c *Checker) expr(expr syntax.Expression) Type {
+	t := c.exprInternal(expr)
+	c.Types[expr] = t
+	return t
+}
+
+func (c *Checker) exprInternal(expr syntax.Expression) Type {
+	switch expr := expr.(type) {
+	case *syntax.Identifier:
+		return c.ident(expr)
+	case *syntax.BasicLit:
+		return c.basicLit(expr)
+	case *syntax.BinaryExpr:
+		return c.binary(expr)
+	case *syntax.UnaryExpr:
+		return c.unary(expr)
+	case *syntax.ParenExpr:
+		return c.paren(expr)
+	case *syntax.AssignmentExpr:
+		return c.assignment(expr)
+	case *syntax.CallExpr:
+		return c.call(expr)
+	case *syntax.SelectorExpr:
+		return c.selector(expr)
+	case *syntax.CompositeLit:
+		return c.compositeLit(expr)
+	case *syntax.RangeExpr:
+		return c.rangeExpr(expr)
+	case *syntax.IndexExpr:
+		return c.index(expr)
+	default:
+		c.error(NewError(expr.Pos(), "unexpected expression"))
+		return Typ[Invalid]
+	}
+}
+
+func (c *Checker) ident(ident *syntax.Identifier) Type {
+	obj := c.scope.Lookup(ident.Name)
+	if obj == nil {
+		c.error(c.undefined(ident, false))
+		return Typ[Invalid]
+	}
+
+	ident.Obj = obj
+	switch obj.Kind {
+	case syntax.ObjKindType:
+		c.error(NewNotAnExpressionError(ident.Pos(), ident.Name))
+		return Typ[Invalid]
+	case syntax.ObjKindBuiltin:
+		c.error(NewBuiltinValueError(ident.Pos(), ident.Name))
+		return Typ[Invalid]
+	}
+
+	if v, ok := c.constVals[obj]; ok {
+		c.consts[ident] = v
+	}
+	if t, ok := c.objects[obj]; ok {
+		return t
+	}
+	return Typ[Invalid]
+}
+
+func (c *Checker) binary(expr *syntax.BinaryExpr) Type {
+	x := c.value(expr.X)
+	y := c.value(expr.Y)
+	if IsInvalid(x) || IsInvalid(y) {
+		return Typ[Invalid]
+	}
+
+	if expr.Op.Type == syntax.TokenShl || expr.Op.Type == syntax.TokenShr {
+		return c.shift(expr, x, y)
+	}
+
+	op := expr.Op.Literal
+	switch {
+	case IsUntyped(x) && IsUntyped(y) && !Identical(x, y):
+		c.promoteUntyped(expr)
+		x, y = c.Types[expr.X], c.Types[expr.Y]
+	case IsUntyped(x) && !IsUntyped(y):
+		c.convertUntyped(expr.X, y)
+		x = c.Types[expr.X]
+	case IsUntyped(y) && !IsUntyped(x):
+		c.convertUntyped(expr.Y, x)
+		y = c.Types[expr.Y]
+	}
+
+	if !Identical(x, y) {
+		c.error(NewMismatchedTypesError(expr.Op.Position, op, x, y).WithEnd(expr.Op.End()))
+		return Typ[Invalid]
+	}
+
+	switch expr.Op.Type {
+	case syntax.TokenPlus, syntax.TokenMinus, syntax.TokenStar, syntax.TokenSlash,
+		syntax.TokenPercent, syntax.TokenAnd, syntax.TokenOr, syntax.TokenXor, syntax.TokenAndNot:
+		if !arithmetic(expr.Op.Type, x) {
+			c.error(NewOperatorError(expr.Op.Position, op, x).WithEnd(expr.Op.End()))
+			return Typ[Invalid]
+		}
+		if IsConcatenable(x) {
+			return x
+		}
+		if c.divisionByZero(expr.Op, expr.Y) {
+			return Typ[Invalid]
+		}
+		if v, ok := c.fold(expr); ok {
+			if !Representable(v, x) {
+				c.error(NewOverflowError(expr.Pos(), v, x).WithEnd(expr.End()))
+			}
+			c.consts[expr] = v
+		}
+		return x
+	case syntax.TokenLt, syntax.TokenGt, syntax.TokenLtEq, syntax.TokenGtEq:
+		c.defaultOperands(expr)
+		if !IsOrdered(x) {
+			c.error(NewOperatorError(expr.Op.Position, op, x).WithEnd(expr.Op.End()))
+			return Typ[Invalid]
+		}
+		return Typ[Bool]
+	case syntax.TokenEq, syntax.TokenNotEq:
+		c.defaultOperands(expr)
+		if !IsComparable(x) {
+			c.error(NewOperatorError(expr.Op.Position, op, x).WithEnd(expr.Op.End()))
+			return Typ[Invalid]
+		}
+		return Typ[Bool]
+	case syntax.TokenLAnd, syntax.TokenLOr:
+		if !IsBoolean(x) {
+			c.error(NewOperatorError(expr.Op.Position, op, x).WithEnd(expr.Op.End()))
+			return Typ[Invalid]
+		}
+		return x
+	default:
+		c.error(NewOperatorError(expr.Op.Position, op, x).WithEnd(expr.Op.End()))
+		return Typ[Invalid]
+	}
+}
+
+// defaultOperands gives untyped operands of a comparison their default type,
+// since its boolean result carries no type for them.
+func (c *Checker) defaultOperands(expr *syntax.BinaryExpr) {
+	c.convertUntyped(expr.X, Default(c.Types[expr.X]))
+	c.convertUntyped(expr.Y, Default(c.Types[expr.Y]))
+}
+
+func (c *Checker) unary(expr *syntax.UnaryExpr) Type {
+	x := c.value(expr.X)
+	if IsInvalid(x) {
+		return x
+	}
+
+	switch expr.Op.Type {
+	case syntax.TokenNot:
+		if !IsBoolean(x) {
+			c.error(NewOperatorError(expr.Op.Position, expr.Op.Literal, x).WithEnd(expr.Op.End()))
+			return Typ[Invalid]
+		}
+		return x
+	case syntax.TokenMinus, syntax.TokenXor:
+		if expr.Op.Type == syntax.TokenMinus && !IsNumeric(x) || expr.Op.Type == syntax.TokenXor && !IsInteger(x) {
+			c.error(NewOperatorError(expr.Op.Position, expr.Op.Literal, x).WithEnd(expr.Op.End()))
+			return Typ[Invalid]
+		}
+		if v, ok := c.foldUnary(expr, x); ok {
+			if !Representable(v, x) {
+				c.error(NewOverflowError(expr.Pos(), v, x).WithEnd(expr.End()))
+			}
+			c.consts[expr] = v
+		}
+		return x
+	default:
+		c.error(NewOperatorError(expr.Op.Position, expr.Op.Literal, x).WithEnd(expr.Op.End()))
+		return Typ[Invalid]
+	}
+}
+
+func (c *Checker) paren(expr *syntax.ParenExpr) Type {
+	t := c.value(expr.X)
+	if v, ok := c.constant(expr.X); ok {
+		c.consts[expr] = v
+	}
+	return t
+}
+
+func (c *Checker) selector(expr *syntax.SelectorExpr) Type {
+	x := c.value(expr.X)
+	if IsInvalid(x) {
+		return x
+	}
+
+	var f *Field
+	if s, ok := x.(*Struct); ok {
+		f = s.Lookup(expr.Sel.Name)
+	}
+	if f == nil {
+		c.error(NewMissingFieldError(expr.Sel.Pos(), expr.X, x, expr.Sel.Name))
+		return Typ[Invalid]
+	}
+	return f.Type
+}
+
+// index checks x[i], which selects an element of an array or slice, and
+// the slice expression x[lo..hi], which makes a slice of the elements from
+// lo up to but not including hi.
+func (c *Checker) index(expr *syntax.IndexExpr) Type {
+	x := c.value(expr.X)
+
+	length := int64(-1)
+	if a, ok := x.(*Array); ok {
+		length = a.Len
+	}
+
+	if r := expr.Range(); r != nil {
+		lo := c.indexValue(r.Low, length, true)
+		hi := c.indexValue(r.High, length, true)
+		if lo >= 0 && hi >= 0 && lo > hi {
+			c.error(NewIndexError(r.Low.Pos(), fmt.Sprintf("invalid slice indices: %d > %d", lo, hi)).WithEnd(r.High.End()))
+		}
+		c.Types[r] = Typ[Invalid]
+	} else {
+		c.indexValue(expr.Index, length, false)
+	}
+
+	switch x := x.(type) {
+	case *Array:
+		if expr.Range() != nil {
+			return NewSlice(x.Elem)
+		}
+		return x.Elem
+	case *Slice:
+		if expr.Range() != nil {
+			return x
+		}
+		return x.Elem
+	}
+
+	if !IsInvalid(x) {
+		c.error(NewCannotIndexError(expr.X.Pos(), expr.X, x).WithEnd(expr.X.End()))
+	}
+	return Typ[Invalid]
+}
+
+// indexValue checks an index or slice bound, which must be an integer, and
+// returns its value if it is constant, or -1. A constant must not be
+// negative, nor beyond length if that is known. A bound may equal the
+// length, but an index must be less.
+func (c *Checker) indexValue(expr syntax.Expression, length int64, bound bool) int64 {
+	t := c.value(expr)
+	if IsInvalid(t) {
+		return -1
+	}
+	if !IsInteger(t) {
+		c.error(NewIndexError(expr.Pos(), "invalid argument: index "+exprString(expr)+" (value of type "+t.String()+") must be integer").WithEnd(expr.End()))
+		return -1
+	}
+	if IsUntyped(t) {
+		c.convertUntyped(expr, Default(t))
+	}
+
+	v, ok := c.constant(expr)
+	if !ok {
+		return -1
+	}
+	if v.Sign() < 0 {
+		c.error(NewIndexError(expr.Pos(), "invalid argument: index "+v.String()+" must not be negative").WithEnd(expr.End()))
+		return -1
+	}
+
+	max := big.NewInt(length)
+	if bound {
+		max.Add(max, big.NewInt(1))
+	}
+	if length >= 0 && v.Cmp(max) >= 0 {
+		c.error(NewIndexError(expr.Pos(), fmt.Sprintf("invalid argument: index %s out of bounds [0..%d]", v, max)).WithEnd(expr.End()))
+		return -1
+	}
+	if !v.IsInt64() {
+		return -1
+	}
+	return v.Int64()
+}
+
+// compositeLit checks T{...}. The elements either all name the field they
+// set, leaving the others zero, or give every field in order.
+func (c *Checker) compositeLit(lit *syntax.CompositeLit) Type {
+	t := c.resolveType(lit.Type)
+	switch t := t.(type) {
+	case *Array:
+		c.arrayLit(lit, t.Elem, t.Len)
+		return t
+	case *Slice:
+		c.arrayLit(lit, t.Elem, -1)
+		return t
+	}
+
+	s, ok := t.(*Struct)
+	if !ok {
+		if !IsInvalid(t) {
+			c.error(NewCompositeLitError(lit.Type.Pos(), "invalid composite literal type "+t.String()).WithEnd(lit.Type.End()))
+		}
+		for _, elt := range lit.Elts {
+			if kv, ok := elt.(*syntax.KeyValueExpr); ok {
+				elt = kv.Value
+			}
+			c.expr(elt)
+		}
+		return Typ[Invalid]
+	}
+
+	keyed := false
+	if len(lit.Elts) > 0 {
+		_, keyed = lit.Elts[0].(*syntax.KeyValueExpr)
+	}
+
+	seen := map[string]bool{}
+	for i, elt := range lit.Elts {
+		kv, ok := elt.(*syntax.KeyValueExpr)
+		switch {
+		case ok != keyed:
+			c.error(NewCompositeLitError(elt.Pos(), "mixture of field: value and value elements in struct literal").WithEnd(elt.End()))
+			if ok {
+				elt = kv.Value
+			}
+			c.expr(elt)
+		case keyed:
+			f := s.Lookup(kv.Key.Name)
+			switch {
+			case f == nil:
+				c.error(NewUnknownFieldError(kv.Key.Pos(), kv.Key.Name, s))
+			case seen[f.Name]:
+				c.error(NewDuplicateFieldError(kv.Key.Pos(), f.Name))
+			}
+			vt := c.value(kv.Value)
+			if f != nil {
+				seen[f.Name] = true
+				c.assignable(kv.Value, vt, f.Type, "struct literal")
+			}
+		case i >= len(s.Fields):
+			c.error(NewCompositeLitError(elt.Pos(), "too many values in struct literal of type "+s.Name).WithEnd(elt.End()))
+			c.expr(elt)
+		default:
+			c.assignable(elt, c.value(elt), s.Fields[i].Type, "struct literal")
+		}
+	}
+
+	if !keyed && len(lit.Elts) > 0 && len(lit.Elts) < len(s.Fields) {
+		c.error(NewCompositeLitError(lit.Rbrace, "too few values in struct literal of type "+s.Name))
+	}
+	return s
+}
+
+// arrayLit checks the elements of an array or slice literal, which give the
+// elements in order from the first. An array literal may leave the elements
+// after the last it gives zero, but must not give more than length.
+func (c *Checker) arrayLit(lit *syntax.CompositeLit, elem Type, length int64) {
+	for i, elt := range lit.Elts {
+		if kv, ok := elt.(*syntax.KeyValueExpr); ok {
+			c.error(NewCompositeLitError(kv.Key.Pos(), "field: value element in array or slice literal").WithEnd(kv.Key.End()))
+			c.expr(kv.Value)
+			continue
+		}
+
+		t := c.value(elt)
+		if length >= 0 && int64(i) >= length {
+			c.error(NewCompositeLitError(elt.Pos(), "too many values in array literal of type "+lit.Type.Obj.Name).WithEnd(elt.End()))
+			continue
+		}
+		c.assignable(elt, t, elem, "array or slice literal")
+	}
+}
+
+func (c *Checker) assignment(expr *syntax.AssignmentExpr) Type {
+	target := c.addressable(expr.Lhs)
+	t := c.value(expr.Rhs)
+
+	if expr.Op.Type == syntax.TokenAssign {
+		c.assignable(expr.Rhs, t, target, "assignment")
+		return target
+	}
+
+	op, ok := syntax.CompoundOp(expr.Op.Type)
+	if !ok || !IsInvalid(target) && !arithmetic(op, target) {
+		c.error(NewOperatorError(expr.Op.Position, expr.Op.Literal, target).WithEnd(expr.Op.End()))
+		return Typ[Invalid]
+	}
+
+	if op == syntax.TokenShl || op == syntax.TokenShr {
+		c.shiftCount(expr.Rhs, t)
+		return target
+	}
+
+	if c.assignable(expr.Rhs, t, target, "assignment") {
+		c.divisionByZero(expr.Op, expr.Rhs)
+	}
+	return target
+}
+
+// tupleAssign checks an assignment of several values, which may come from a
+// single call with several results.
+func (c *Checker) tupleAssign(stmt *syntax.TupleAssignStmt) {
+	targets := make([]Type, len(stmt.Lhs))
+	for i, lhs := range stmt.Lhs {
+		targets[i] = c.addressable(lhs)
+	}
+
+	exprs, values := c.values(stmt.Rhs)
+	if len(values) != len(targets) {
+		c.error(NewAssignmentCountError(stmt.TokPos, len(targets), len(values)))
+		return
+	}
+
+	for i, t := range values {
+		c.assignable(exprs[i], t, targets[i], "assignment")
+	}
+}
+
+// arithmetic reports whether the arithmetic operator op is defined on
+// operands of type t. The remainder and bitwise operators take integers only.
+func arithmetic(op syntax.TokenType, t Type) bool {
+	switch op {
+	case syntax.TokenPlus:
+		return IsNumeric(t) || IsConcatenable(t)
+	case syntax.TokenMinus, syntax.TokenStar, syntax.TokenSlash:
+		return IsNumeric(t)
+	case syntax.TokenPercent, syntax.TokenAnd, syntax.TokenOr, syntax.TokenXor, syntax.TokenAndNot,
+		syntax.TokenShl, syntax.TokenShr:
+		return IsInteger(t)
+	default:
+		return false
+	}
+}
+
+// shift checks x << y or x >> y. The result has the type of x; an untyped
+// constant x shifted by a variable count takes its default type.
+func (c *Checker) shift(expr *syntax.BinaryExpr, x, y Type) Type {
+	if !c.shiftCount(expr.Y, y) {
+		return Typ[Invalid]
+	}
+	if !IsInteger(x) {
+		c.error(NewOperatorError(expr.Op.Position, expr.Op.Literal, x).WithEnd(expr.Op.End()))
+		return Typ[Invalid]
+	}
+	if !IsUntyped(x) {
+		return x
+	}
+
+	if v, ok := c.fold(expr); ok {
+		c.consts[expr] = v
+		return x
+	}
+	c.convertUntyped(expr.X, Default(x))
+	return c.Types[expr.X]
+}
+
+// shiftCount checks that the shift count expr of type t is an unsigned
+// integer or a non-negative integer constant, which it gives the type u64.
+func (c *Checker) shiftCount(expr syntax.Expression, t Type) bool {
+	if IsInvalid(t) {
+		return false
+	}
+
+	if IsUntyped(t) {
+		v, ok := c.constant(expr)
+		if !ok {
+			c.error(NewShiftCountError(expr.Pos(), t).WithEnd(expr.End()))
+			return false
+		}
+		if v.Sign() < 0 {
+			c.error(NewNegativeShiftCountError(expr.Pos(), v).WithEnd(expr.End()))
+			return false
+		}
+		c.convertUntyped(expr, Typ[U64])
+		return true
+	}
+
+	if !IsUnsigned(t) {
+		c.error(NewShiftCountError(expr.Pos(), t).WithEnd(expr.End()))
+		return false
+	}
+	return true
+}
+
+// divisionByZero reports an error and returns true if op divides by the
+// constant y and y is zero.
+func (c *Checker) divisionByZero(op *syntax.Token, y syntax.Expression) bool {
+	switch op.Type {
+	case syntax.TokenSlash, syntax.TokenSlashAssign, syntax.TokenPercent, syntax.TokenPercentAssign:
+	default:
+		return false
+	}
+	if v, ok := c.constant(y); !ok || v.Sign() != 0 {
+		return false
+	}
+	c.error(NewDivisionByZeroError(op.Position).WithEnd(op.End()))
+	return true
+}
+
+// addressable checks that expr denotes a variable, or a field or array
+// element of one, or an element of a slice, and returns its type.
+func (c *Checker) addressable(expr syntax.Expression) Type {
+	t := c.expr(expr)
+	if IsInvalid(t) {
+		return t
+	}
+
+	root := expr
+loop:
+	for {
+		switch x := root.(type) {
+		case *syntax.SelectorExpr:
+			root = x.X
+		case *syntax.IndexExpr:
+			if x.Range() != nil {
+				break loop
+			}
+			if _, ok := c.Types[x.X].(*Slice); ok {
+				return t
+			}
+			root = x.X
+		default:
+			break loop
+		}
+	}
+
+	ident, ok := root.(*syntax.Identifier)
+	if !ok {
+		c.error(NewCannotAssignError(expr.Pos(), exprString(expr)).WithEnd(expr.End()))
+		return Typ[Invalid]
+	}
+
+	if ident.Obj != nil && ident.Obj.Kind != syntax.ObjKindVar {
+		c.error(NewCannotAssignError(expr.Pos(), ident.Name+" (neither a variable nor a parameter)").WithEnd(expr.End()))
+		return Typ[Invalid]
+	}
+
+	return t
+}
+
+func (c *Checker) call(expr *syntax.CallExpr) Type {
+	if obj := c.scope.Lookup(expr.Func.Name); obj != nil {
+		switch obj.Kind {
+		case syntax.ObjKindType:
+			expr.Func.Obj = obj
+			return c.conversion(expr, c.objects[obj])
+		case syntax.ObjKindBuiltin:
+			expr.Func.Obj = obj
+			return c.builtin(expr)
+		}
+	}
+
+	t := c.expr(expr.Func)
+	if IsInvalid(t) {
+		return Typ[Invalid]
+	}
+
+	sig, ok := t.(*Signature)
+	if !ok {
+		c.error(NewNotCallableError(expr.Func.Pos(), expr.Func.Name))
+		return Typ[Invalid]
+	}
+
+	args := expr.Args
+	if len(args) != len(sig.Params) {
+		c.error(NewArgumentCountError(expr.Rparen, expr.Func.Name, len(sig.Params), len(args)))
+	}
+
+	for i, arg := range args {
+		at := c.value(arg)
+		if i < len(sig.Params) {
+			c.assignable(arg, at, sig.Params[i], "argument to "+expr.Func.Name)
+		}
+	}
+
+	if len(sig.Results) == 1 {
+		return sig.Results[0]
+	}
+	return NewTuple(sig.Results)
+}
+
+// conversion checks T(x). Numbers convert to any numeric type: integers
+// wrap to the width of T and floats are truncated towards zero, but integer
+// constants must fit.
+func (c *Checker) conversion(expr *syntax.CallExpr, t Type) Type {
+	if len(expr.Args) != 1 {
+		c.error(NewArgumentCountError(expr.Rparen, "conversion to "+expr.Func.Name, 1, len(expr.Args)))
+		for _, arg := range expr.Args {
+			c.expr(arg)
+		}
+		return t
+	}
+
+	arg := expr.Args[0]
+	x := c.value(arg)
+	if IsInvalid(x) || IsInvalid(t) {
+		return t
+	}
+
+	switch {
+	case IsUntyped(x) && untypedFits(x, t):
+		c.convertUntyped(arg, t)
+	case IsUntyped(x) && IsNumeric(t):
+		// A floating-point constant converted to an integer type is
+		// truncated at run time, like any other float.
+		c.convertUntyped(arg, Default(x))
+	case Identical(x, t), IsNumeric(x) && IsNumeric(t):
+	default:
+		c.error(NewConversionError(arg.Pos(), x, t).WithEnd(arg.End()))
+	}
+
+	return t
+}
+
+// builtin checks a call to a predeclared function. len returns the length
+// of a string in bytes, or of an array or slice in elements, and cap the
+// capacity of an array or slice, both as an i32. append(s, x...) returns the
+// slice s with the values x added to the end. print writes each of its
+// string or integer arguments to standard output and returns nothing.
+func (c *Checker) builtin(expr *syntax.CallExpr) Type {
+	name := expr.Func.Name
+	switch name {
+	case "len", "cap":
+		if len(expr.Args) != 1 {
+			c.error(NewArgumentCountError(expr.Rparen, name, 1, len(expr.Args)))
+			for _, arg := range expr.Args {
+				c.expr(arg)
+			}
+			return Typ[I32]
+		}
+		arg := expr.Args[0]
+		t := c.value(arg)
+		switch t.(type) {
+		case *Array, *Slice:
+		default:
+			if !IsInvalid(t) && (name == "cap" || !IsString(t)) {
+				c.error(NewArgumentError(arg.Pos(), arg, t, name).WithEnd(arg.End()))
+			}
+		}
+		return Typ[I32]
+	case "append":
+		if len(expr.Args) == 0 {
+			c.error(NewArgumentCountError(expr.Rparen, name, 1, 0))
+			return Typ[Invalid]
+		}
+		t := c.value(expr.Args[0])
+		s, ok := t.(*Slice)
+		if !ok && !IsInvalid(t) {
+			c.error(NewArgumentError(expr.Args[0].Pos(), expr.Args[0], t, name).WithEnd(expr.Args[0].End()))
+		}
+		for _, arg := range expr.Args[1:] {
+			at := c.value(arg)
+			if ok {
+				c.assignable(arg, at, s.Elem, "argument to append")
+			}
+		}
+		if !ok {
+			return Typ[Invalid]
+		}
+		return s
+	case "print":
+		for _, arg := range expr.Args {
+			t := c.value(arg)
+			if IsUntyped(t) {
+				t = Default(t)
+				c.convertUntyped(arg, t)
+			}
+			switch {
+			case IsInvalid(t), IsString(t), IsInteger(t):
+			default:
+				c.error(NewArgumentError(arg.Pos(), arg, t, name).WithEnd(arg.End()))
+			}
+		}
+		return NewTuple(nil)
+	default:
+		c.error(c.undefined(expr.Func, false))
+		return Typ[Invalid]
+	}
+}
+
+// rangeExpr returns the element type of a range: the common integer type of
+// its bounds.
+func (c *Checker) rangeExpr(expr *syntax.RangeExpr) Type {
+	low := c.value(expr.Low)
+	high := c.value(expr.High)
+	if IsInvalid(low) || IsInvalid(high) {
+		return Typ[Invalid]
+	}
+
+	switch {
+	case IsUntyped(low) && IsUntyped(high):
+		low = Default(low)
+		if IsFloat(high) {
+			low = Default(high)
+		}
+		high = low
+		c.convertUntyped(expr.Low, low)
+		c.convertUntyped(expr.High, high)
+	case IsUntyped(low):
+		c.convertUntyped(expr.Low, high)
+		low = c.Types[expr.Low]
+	case IsUntyped(high):
+		c.convertUntyped(expr.High, low)
+		high = c.Types[expr.High]
+	}
+
+	if !Identical(low, high) {
+		c.error(NewMismatchedTypesError(expr.High.Pos(), "..", low, high).WithEnd(expr.High.End()))
+		return Typ[Invalid]
+	}
+
+	if !IsInteger(low) {
+		c.error(NewOperatorError(expr.High.Pos(), "..", low).WithEnd(expr.High.End()))
+		return Typ[Invalid]
+	}
+
+	expr.Kind = low.String()
+	return low
+}
+
+// isTerminating reports whether control can never flow past the end of stmt.
+func isTerminating(stmt syntax.Statement) bool {
+	switch stmt := stmt.(type) {
+	case *syntax.LabeledStmt:
+		return isTerminating(stmt.Stmt) && !breaks(loopBody(stmt.Stmt), stmt.Label.Name, false)
+	case *syntax.ReturnStmt:
+		return true
+	case *syntax.BlockStmt:
+		return len(stmt.Stmts) > 0 && isTerminating(stmt.Stmts[len(stmt.Stmts)-1])
+	case *syntax.IfStmt:
+		return stmt.Alternative != nil && isTerminating(stmt.Consequence) && isTerminating(stmt.Alternative)
+	case *syntax.WhileStmt:
+		ident, ok := stmt.Cond.(*syntax.Identifier)
+		return ok && ident.Obj == parse.Universe.Objects["true"] && !breaks(stmt.Body, "", false)
+	case *syntax.ForLoopStmt:
+		return stmt.Cond == nil && !breaks(stmt.Body, "", false)
+	default:
+		return false
+	}
+}
+
+// breaks reports whether stmt contains a break out of the loop labeled
+// label, or out of the innermost loop around stmt unless nested is set.
+func breaks(stmt syntax.Statement, label string, nested bool) bool {
+	switch stmt := stmt.(type) {
+	case *syntax.BranchStmt:
+		if stmt.Tok != syntax.TokenBreak {
+			return false
+		}
+		if stmt.Label == nil {
+			return !nested
+		}
+		return stmt.Label.Name == label
+	case *syntax.BlockStmt:
+		for _, s := range stmt.Stmts {
+			if breaks(s, label, nested) {
+				return true
+			}
+		}
+	case *syntax.IfStmt:
+		return breaks(stmt.Consequence, label, nested) ||
+			stmt.Alternative != nil && breaks(stmt.Alternative, label, nested)
+	case *syntax.LabeledStmt:
+		return breaks(stmt.Stmt, label, nested)
+	case *syntax.ForRangeStmt, *syntax.WhileStmt, *syntax.ForLoopStmt:
+		return label != "" && breaks(loopBody(stmt), label, true)
+	}
+	return false
+}
+
+// loopBody returns the body of the loop stmt, or nil if it is not a loop.
+func loopBody(stmt syntax.Statement) *syntax.BlockStmt {
+	switch stmt := stmt.(type) {
+	case *syntax.ForRangeStmt:
+		return stmt.Body
+	case *syntax.WhileStmt:
+		return stmt.Body
+	case *syntax.ForLoopStmt:
+		return stmt.Body
+	default:
+		return nil
+	}
+}
+
+func exprString(expr syntax.Expression) string {
+	switch expr := expr.(type) {
+	case *syntax.Identifier:
+		return expr.Name
+	case *syntax.BasicLit:
+		return expr.Value
+	case *syntax.CallExpr:
+		return expr.Func.Name + "(...)"
+	case *syntax.ParenExpr:
+		return "(" + exprString(expr.X) + ")"
+	case *syntax.SelectorExpr:
+		return exprString(expr.X) + "." + expr.Sel.Name
+	case *syntax.CompositeLit:
+		return expr.Type.Name + "{...}"
+	case *syntax.IndexExpr:
+		return exprString(expr.X) + "[" + exprString(expr.Index) + "]"
+	case *syntax.RangeExpr:
+		return exprString(expr.Low) + ".." + exprString(expr.High)
+	default:
+		return "expression"
+	}
+}
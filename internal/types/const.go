@@ -0,0 +1,195 @@
+package types
+
+import (
+	"math"
+	"math/big"
+
+	"github.com/danecwalker/hippo/internal/syntax"
+)
+
+// constant returns the value of expr if it is an integer constant.
+func (c *Checker) constant(expr syntax.Expression) (*big.Int, bool) {
+	v, ok := c.consts[expr]
+	return v, ok
+}
+
+func (c *Checker) basicLit(lit *syntax.BasicLit) Type {
+	if lit.Kind == "int" {
+		v, ok := syntax.IntValue(lit.Value)
+		if !ok {
+			c.error(NewError(lit.Pos(), "invalid integer literal "+lit.Value).WithEnd(lit.End()))
+			return Typ[Invalid]
+		}
+		c.consts[lit] = v
+		return Typ[UntypedInt]
+	}
+
+	if lit.Kind == "float" {
+		if _, ok := syntax.FloatValue(lit.Value); !ok {
+			c.error(NewError(lit.Pos(), "invalid floating-point literal "+lit.Value).WithEnd(lit.End()))
+			return Typ[Invalid]
+		}
+		return Typ[UntypedFloat]
+	}
+
+	if t := LookupBasic(lit.Kind); t != nil {
+		return t
+	}
+	c.error(NewError(lit.Pos(), "invalid literal "+lit.Value).WithEnd(lit.End()))
+	return Typ[Invalid]
+}
+
+// convertUntyped gives the untyped constant expr the numeric type target,
+// reporting an error if its value does not fit. Nothing happens if expr is
+// typed or cannot take the type target.
+func (c *Checker) convertUntyped(expr syntax.Expression, target Type) {
+	if !IsUntyped(c.Types[expr]) || !untypedFits(c.Types[expr], target) {
+		return
+	}
+
+	if v, ok := c.constant(expr); ok && IsInteger(target) && !Representable(v, target) {
+		c.error(NewOverflowError(expr.Pos(), v, target).WithEnd(expr.End()))
+	}
+
+	if lit, ok := floatLit(expr); ok && IsFloat(target) {
+		v, _ := syntax.FloatValue(lit.Value)
+		if math.IsInf(v, 0) || isBasic(target, F32) && math.Abs(v) > math.MaxFloat32 {
+			c.error(NewFloatOverflowError(expr.Pos(), lit.Value, target).WithEnd(expr.End()))
+		}
+	}
+
+	c.setType(expr, target)
+}
+
+// floatLit returns the literal an untyped float expression is made of when it
+// is a literal, possibly negated or parenthesised.
+func floatLit(expr syntax.Expression) (*syntax.BasicLit, bool) {
+	switch expr := expr.(type) {
+	case *syntax.BasicLit:
+		return expr, true
+	case *syntax.UnaryExpr:
+		if expr.Op.Type == syntax.TokenMinus {
+			return floatLit(expr.X)
+		}
+	case *syntax.ParenExpr:
+		return floatLit(expr.X)
+	}
+	return nil, false
+}
+
+// promoteUntyped gives the untyped integer operand of an operation on an
+// untyped integer and an untyped float the untyped float type.
+func (c *Checker) promoteUntyped(expr *syntax.BinaryExpr) {
+	for _, x := range []syntax.Expression{expr.X, expr.Y} {
+		if isBasic(c.Types[x], UntypedInt) {
+			c.setType(x, Typ[UntypedFloat])
+		}
+	}
+}
+
+// setType records t as the final type of an untyped expression and of the
+// untyped operands it was computed from. Literals are rewritten to carry the
+// name of their type, and uses of named constants are given a literal of it.
+func (c *Checker) setType(expr syntax.Expression, t Type) {
+	if !IsUntyped(c.Types[expr]) {
+		return
+	}
+	c.Types[expr] = t
+
+	switch expr := expr.(type) {
+	case *syntax.BasicLit:
+		expr.Kind = t.String()
+	case *syntax.Identifier:
+		if v, ok := c.constant(expr); ok {
+			expr.Const = syntax.NewBasicLit(expr.NamePos, t.String(), v.String())
+		}
+	case *syntax.BinaryExpr:
+		c.setType(expr.X, t)
+		c.setType(expr.Y, t)
+	case *syntax.UnaryExpr:
+		c.setType(expr.X, t)
+	case *syntax.ParenExpr:
+		c.setType(expr.X, t)
+	}
+}
+
+// fold evaluates the binary operation op on the constants x and y. It
+// reports false if the operation cannot be folded.
+func (c *Checker) fold(expr *syntax.BinaryExpr) (*big.Int, bool) {
+	x, ok := c.constant(expr.X)
+	if !ok {
+		return nil, false
+	}
+	y, ok := c.constant(expr.Y)
+	if !ok {
+		return nil, false
+	}
+
+	z := new(big.Int)
+	switch expr.Op.Type {
+	case syntax.TokenPlus:
+		return z.Add(x, y), true
+	case syntax.TokenMinus:
+		return z.Sub(x, y), true
+	case syntax.TokenStar:
+		return z.Mul(x, y), true
+	case syntax.TokenSlash:
+		if y.Sign() == 0 {
+			return nil, false
+		}
+		return z.Quo(x, y), true
+	case syntax.TokenPercent:
+		if y.Sign() == 0 {
+			return nil, false
+		}
+		return z.Rem(x, y), true
+	case syntax.TokenAnd:
+		return z.And(x, y), true
+	case syntax.TokenOr:
+		return z.Or(x, y), true
+	case syntax.TokenXor:
+		return z.Xor(x, y), true
+	case syntax.TokenAndNot:
+		return z.AndNot(x, y), true
+	case syntax.TokenShl, syntax.TokenShr:
+		// Counts this large could only shift out every bit of a
+		// representable value, or take huge amounts of memory.
+		if y.Sign() < 0 || y.Cmp(big.NewInt(maxShift)) > 0 {
+			return nil, false
+		}
+		if expr.Op.Type == syntax.TokenShl {
+			return z.Lsh(x, uint(y.Uint64())), true
+		}
+		return z.Rsh(x, uint(y.Uint64())), true
+	default:
+		return nil, false
+	}
+}
+
+// maxShift is the largest count by which constants are shifted at compile
+// time.
+const maxShift = 1023
+
+// foldUnary evaluates the unary operation of expr, whose operand has type t,
+// on a constant operand. The complement of an unsigned value has the bits of
+// its type flipped rather than its sign.
+func (c *Checker) foldUnary(expr *syntax.UnaryExpr, t Type) (*big.Int, bool) {
+	x, ok := c.constant(expr.X)
+	if !ok {
+		return nil, false
+	}
+
+	z := new(big.Int)
+	switch expr.Op.Type {
+	case syntax.TokenMinus:
+		return z.Neg(x), true
+	case syntax.TokenXor:
+		if IsUnsigned(t) {
+			mask := new(big.Int).Lsh(big.NewInt(1), uint(Size(t)))
+			return z.Xor(x, mask.Sub(mask, big.NewInt(1))), true
+		}
+		return z.Not(x), true
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,189 @@
+package types
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/danecwalker/hippo/internal/diag"
+	"github.com/danecwalker/hippo/internal/syntax"
+)
+
+// Error is a problem found while type checking a program.
+type Error = diag.Diagnostic
+
+func NewError(pos *syntax.Position, msg string) *Error {
+	return diag.NewError(diag.CodeType, pos, msg)
+}
+
+func NewUndefinedError(pos *syntax.Position, name string) *Error {
+	msg := "undefined: %s"
+	return NewError(pos, fmt.Sprintf(msg, name)).WithCode(diag.CodeUndefined).WithEnd(pos.After(name))
+}
+
+func NewRedeclaredError(pos *syntax.Position, name string, prev *syntax.Object) *Error {
+	msg := "%s redeclared in this block"
+	err := NewError(pos, fmt.Sprintf(msg, name)).WithCode(diag.CodeRedeclared).WithEnd(pos.After(name))
+	if ident := prev.Ident(); ident != nil {
+		err.WithLabel(ident.Pos(), ident.End(), "previously declared here")
+	}
+	return err
+}
+
+func NewNotATypeError(pos *syntax.Position, name string) *Error {
+	msg := "%s is not a type"
+	return NewError(pos, fmt.Sprintf(msg, name)).WithCode(diag.CodeNotAType).WithEnd(pos.After(name))
+}
+
+func NewNotAnExpressionError(pos *syntax.Position, name string) *Error {
+	msg := "%s is not an expression"
+	return NewError(pos, fmt.Sprintf(msg, name)).WithCode(diag.CodeNotAnExpression).WithEnd(pos.After(name))
+}
+
+func NewMismatchedTypesError(pos *syntax.Position, op string, x, y Type) *Error {
+	msg := "invalid operation: mismatched types %s and %s for %s"
+	return NewError(pos, fmt.Sprintf(msg, x, y, op)).WithCode(diag.CodeMismatchedTypes)
+}
+
+func NewOperatorError(pos *syntax.Position, op string, t Type) *Error {
+	msg := "invalid operation: operator %s not defined on %s"
+	return NewError(pos, fmt.Sprintf(msg, op, t)).WithCode(diag.CodeOperator)
+}
+
+func NewAssignmentError(pos *syntax.Position, t Type, target Type, context string) *Error {
+	msg := "cannot use value of type %s as %s value in %s"
+	return NewError(pos, fmt.Sprintf(msg, t, target, context)).WithCode(diag.CodeAssignment)
+}
+
+func NewCannotAssignError(pos *syntax.Position, what string) *Error {
+	msg := "cannot assign to %s"
+	return NewError(pos, fmt.Sprintf(msg, what)).WithCode(diag.CodeCannotAssign)
+}
+
+func NewNotCallableError(pos *syntax.Position, name string) *Error {
+	msg := "invalid operation: cannot call non-function %s"
+	return NewError(pos, fmt.Sprintf(msg, name)).WithCode(diag.CodeNotCallable).WithEnd(pos.After(name))
+}
+
+func NewArgumentCountError(pos *syntax.Position, name string, want, got int) *Error {
+	what := "not enough"
+	if got > want {
+		what = "too many"
+	}
+	msg := "%s arguments in call to %s: want %d, got %d"
+	return NewError(pos, fmt.Sprintf(msg, what, name, want, got)).WithCode(diag.CodeArgumentCount)
+}
+
+func NewReturnCountError(pos *syntax.Position, want, got int) *Error {
+	what := "not enough"
+	if got > want {
+		what = "too many"
+	}
+	msg := "%s return values: want %d, got %d"
+	return NewError(pos, fmt.Sprintf(msg, what, want, got)).WithCode(diag.CodeReturnCount)
+}
+
+func NewMissingReturnError(pos *syntax.Position) *Error {
+	return NewError(pos, "missing return").WithCode(diag.CodeMissingReturn)
+}
+
+func NewNoValueError(pos *syntax.Position, what string) *Error {
+	msg := "%s is used as a value but returns no value"
+	return NewError(pos, fmt.Sprintf(msg, what)).WithCode(diag.CodeNoValue)
+}
+
+func NewConditionError(pos *syntax.Position, t Type, stmt string) *Error {
+	msg := "non-boolean condition in %s statement: %s"
+	return NewError(pos, fmt.Sprintf(msg, stmt, t)).WithCode(diag.CodeCondition)
+}
+
+func NewAssignmentCountError(pos *syntax.Position, want, got int) *Error {
+	return NewError(pos, fmt.Sprintf("assignment mismatch: %s but %s", plural(want, "variable"), plural(got, "value"))).WithCode(diag.CodeAssignmentCount)
+}
+
+func NewOverflowError(pos *syntax.Position, v *big.Int, t Type) *Error {
+	msg := "constant %s overflows %s"
+	return NewError(pos, fmt.Sprintf(msg, v, t)).WithCode(diag.CodeOverflow)
+}
+
+func NewFloatOverflowError(pos *syntax.Position, lit string, t Type) *Error {
+	msg := "constant %s overflows %s"
+	return NewError(pos, fmt.Sprintf(msg, lit, t)).WithCode(diag.CodeOverflow)
+}
+
+func NewConversionError(pos *syntax.Position, x Type, t Type) *Error {
+	msg := "cannot convert value of type %s to type %s"
+	return NewError(pos, fmt.Sprintf(msg, x, t)).WithCode(diag.CodeConversion)
+}
+
+func NewShiftCountError(pos *syntax.Position, t Type) *Error {
+	msg := "invalid operation: shift count type %s, must be unsigned integer"
+	return NewError(pos, fmt.Sprintf(msg, t)).WithCode(diag.CodeOperator)
+}
+
+func NewNegativeShiftCountError(pos *syntax.Position, v *big.Int) *Error {
+	msg := "invalid operation: negative shift count %s"
+	return NewError(pos, fmt.Sprintf(msg, v)).WithCode(diag.CodeOperator)
+}
+
+func NewDivisionByZeroError(pos *syntax.Position) *Error {
+	return NewError(pos, "invalid operation: division by zero").WithCode(diag.CodeDivisionByZero)
+}
+
+func NewBuiltinValueError(pos *syntax.Position, name string) *Error {
+	msg := "%s (built-in function) must be called"
+	return NewError(pos, fmt.Sprintf(msg, name)).WithCode(diag.CodeBuiltinValue).WithEnd(pos.After(name))
+}
+
+func NewArgumentError(pos *syntax.Position, arg syntax.Expression, t Type, name string) *Error {
+	msg := "invalid argument: %s (value of type %s) for built-in %s"
+	return NewError(pos, fmt.Sprintf(msg, exprString(arg), t, name)).WithCode(diag.CodeArgument)
+}
+
+func NewMissingFieldError(pos *syntax.Position, x syntax.Expression, t Type, name string) *Error {
+	msg := "%s.%s undefined (type %s has no field %s)"
+	return NewError(pos, fmt.Sprintf(msg, exprString(x), name, t, name)).WithCode(diag.CodeUnknownField).WithEnd(pos.After(name))
+}
+
+func NewUnknownFieldError(pos *syntax.Position, name string, t Type) *Error {
+	msg := "unknown field %s in struct literal of type %s"
+	return NewError(pos, fmt.Sprintf(msg, name, t)).WithCode(diag.CodeUnknownField).WithEnd(pos.After(name))
+}
+
+func NewDuplicateFieldError(pos *syntax.Position, name string) *Error {
+	msg := "duplicate field %s"
+	return NewError(pos, fmt.Sprintf(msg, name)).WithCode(diag.CodeRedeclared).WithEnd(pos.After(name))
+}
+
+func NewCompositeLitError(pos *syntax.Position, msg string) *Error {
+	return NewError(pos, msg).WithCode(diag.CodeCompositeLit)
+}
+
+func NewRecursiveTypeError(pos *syntax.Position, name string) *Error {
+	msg := "invalid recursive type %s"
+	return NewError(pos, fmt.Sprintf(msg, name)).WithCode(diag.CodeRecursiveType).WithEnd(pos.After(name))
+}
+
+func NewArrayLengthError(pos *syntax.Position, msg string) *Error {
+	return NewError(pos, msg).WithCode(diag.CodeArrayLength)
+}
+
+func NewIndexError(pos *syntax.Position, msg string) *Error {
+	return NewError(pos, msg).WithCode(diag.CodeIndex)
+}
+
+func NewCannotIndexError(pos *syntax.Position, x syntax.Expression, t Type) *Error {
+	msg := "invalid operation: cannot index %s (value of type %s)"
+	return NewError(pos, fmt.Sprintf(msg, exprString(x), t)).WithCode(diag.CodeIndex)
+}
+
+func NewCannotRangeError(pos *syntax.Position, x syntax.Expression, t Type) *Error {
+	msg := "cannot range over %s (value of type %s)"
+	return NewError(pos, fmt.Sprintf(msg, exprString(x), t)).WithCode(diag.CodeInvalidRange)
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
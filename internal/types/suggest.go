@@ -0,0 +1,79 @@
+package types
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/danecwalker/hippo/internal/diag"
+	"github.com/danecwalker/hippo/internal/syntax"
+)
+
+// undefined reports ident as undefined, suggesting the most similar name
+// in scope as a fix. wantType selects whether types or values are
+// candidates.
+func (c *Checker) undefined(ident *syntax.Identifier, wantType bool) *Error {
+	err := NewUndefinedError(ident.Pos(), ident.Name)
+	if name := c.similarName(ident.Name, wantType); name != "" {
+		err.WithFix(fmt.Sprintf("did you mean %s?", name), diag.NewEdit(ident.Pos(), ident.End(), name))
+	}
+	return err
+}
+
+// similarName returns the name in scope closest to name by edit distance,
+// or "" if none is close enough to be a likely typo.
+func (c *Checker) similarName(name string, wantType bool) string {
+	seen := make(map[string]bool)
+	var names []string
+	for s := c.scope; s != nil; s = s.Parent {
+		for n, obj := range s.Objects {
+			if seen[n] || (obj.Kind == syntax.ObjKindType) != wantType {
+				continue
+			}
+			seen[n] = true
+			names = append(names, n)
+		}
+	}
+	sort.Strings(names)
+
+	best, bestDist := "", len([]rune(name))/3+1
+	for _, n := range names {
+		if d := editDistance(name, n); d < bestDist {
+			best, bestDist = n, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the number of characters that must be inserted,
+// deleted, replaced or swapped with a neighbour to turn a into b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = d[i-1][j-1] + cost
+			if d[i-1][j]+1 < d[i][j] {
+				d[i][j] = d[i-1][j] + 1
+			}
+			if d[i][j-1]+1 < d[i][j] {
+				d[i][j] = d[i][j-1] + 1
+			}
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] && d[i-2][j-2]+1 < d[i][j] {
+				d[i][j] = d[i-2][j-2] + 1
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
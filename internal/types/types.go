@@ -0,0 +1,353 @@
+package types
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/danecwalker/hippo/internal/syntax"
+)
+
+type Type interface {
+	String() string
+}
+
+type BasicKind int
+
+const (
+	Invalid BasicKind = iota
+	Bool
+	I8
+	I16
+	I32
+	I64
+	U8
+	U16
+	U32
+	U64
+	F32
+	F64
+	String
+
+	// UntypedInt and UntypedFloat are the types of numeric constants that
+	// have not yet been given a type by their context.
+	UntypedInt
+	UntypedFloat
+)
+
+type Basic struct {
+	Kind BasicKind
+	Name string
+}
+
+func (b *Basic) String() string {
+	return b.Name
+}
+
+var Typ = map[BasicKind]*Basic{
+	Invalid:      {Invalid, "invalid type"},
+	Bool:         {Bool, "bool"},
+	I8:           {I8, "i8"},
+	I16:          {I16, "i16"},
+	I32:          {I32, "i32"},
+	I64:          {I64, "i64"},
+	U8:           {U8, "u8"},
+	U16:          {U16, "u16"},
+	U32:          {U32, "u32"},
+	U64:          {U64, "u64"},
+	F32:          {F32, "f32"},
+	F64:          {F64, "f64"},
+	String:       {String, "string"},
+	UntypedInt:   {UntypedInt, "untyped int"},
+	UntypedFloat: {UntypedFloat, "untyped float"},
+}
+
+// LookupBasic returns the basic type called name, or nil.
+func LookupBasic(name string) *Basic {
+	for _, t := range Typ {
+		if t.Kind != Invalid && !IsUntyped(t) && t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// Default returns the type an untyped constant takes when nothing else
+// determines it.
+func Default(t Type) Type {
+	switch {
+	case isBasic(t, UntypedInt):
+		return Typ[I32]
+	case isBasic(t, UntypedFloat):
+		return Typ[F64]
+	}
+	return t
+}
+
+// untypedFits reports whether an untyped constant of type t can take the
+// typed numeric type target. Integer constants become any number, but
+// floating-point constants only floats.
+func untypedFits(t Type, target Type) bool {
+	switch {
+	case IsUntyped(target):
+		return false
+	case isBasic(t, UntypedInt):
+		return IsNumeric(target)
+	case isBasic(t, UntypedFloat):
+		return IsFloat(target)
+	default:
+		return false
+	}
+}
+
+// Size returns the width in bits of the integer type t, or 0.
+func Size(t Type) int {
+	b, ok := t.(*Basic)
+	if !ok {
+		return 0
+	}
+	switch b.Kind {
+	case I8, U8:
+		return 8
+	case I16, U16:
+		return 16
+	case I32, U32:
+		return 32
+	case I64, U64:
+		return 64
+	default:
+		return 0
+	}
+}
+
+// Representable reports whether the constant v fits in the integer type t.
+// Every value is representable as an untyped constant.
+func Representable(v *big.Int, t Type) bool {
+	if IsUntyped(t) {
+		return true
+	}
+
+	bits := Size(t)
+	if bits == 0 {
+		return false
+	}
+
+	if IsUnsigned(t) {
+		return v.Sign() >= 0 && v.BitLen() <= bits
+	}
+
+	min := new(big.Int).Lsh(big.NewInt(-1), uint(bits-1))
+	max := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), uint(bits-1)), big.NewInt(1))
+	return v.Cmp(min) >= 0 && v.Cmp(max) <= 0
+}
+
+type Signature struct {
+	Params  []Type
+	Results []Type
+}
+
+func NewSignature(params []Type, results []Type) *Signature {
+	return &Signature{
+		Params:  params,
+		Results: results,
+	}
+}
+
+func (s *Signature) String() string {
+	var w strings.Builder
+	w.WriteString("fn")
+	if len(s.Params) > 0 {
+		w.WriteString(" : ")
+		w.WriteString(typeList(s.Params))
+	}
+	if len(s.Results) > 0 {
+		w.WriteString(" -> ")
+		w.WriteString(typeList(s.Results))
+	}
+	return w.String()
+}
+
+// Struct is a struct type declared by a type statement. Every declaration
+// is a distinct type, so two structs are identical only if they are the same
+// one.
+type Struct struct {
+	Name   string
+	Fields []*Field
+}
+
+// Field is a member of a struct.
+type Field struct {
+	Name string
+	Type Type
+}
+
+func NewStruct(name string) *Struct {
+	return &Struct{
+		Name: name,
+	}
+}
+
+func (s *Struct) String() string {
+	return s.Name
+}
+
+// Lookup returns the field called name, or nil.
+func (s *Struct) Lookup(name string) *Field {
+	for _, f := range s.Fields {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// Array is a fixed-length sequence of elements, a value like any other that
+// is copied whenever it is stored.
+type Array struct {
+	Len  int64
+	Elem Type
+}
+
+func NewArray(len_ int64, elem Type) *Array {
+	return &Array{
+		Len:  len_,
+		Elem: elem,
+	}
+}
+
+func (a *Array) String() string {
+	return syntax.ArrayTypeName(a.Len, a.Elem.String())
+}
+
+// Slice is a view of a run of elements of an underlying array, which it
+// shares with every other slice of it.
+type Slice struct {
+	Elem Type
+}
+
+func NewSlice(elem Type) *Slice {
+	return &Slice{
+		Elem: elem,
+	}
+}
+
+func (s *Slice) String() string {
+	return syntax.ArrayTypeName(-1, s.Elem.String())
+}
+
+// Tuple is the type of a call returning more than one value.
+type Tuple struct {
+	Types []Type
+}
+
+func NewTuple(types []Type) *Tuple {
+	return &Tuple{
+		Types: types,
+	}
+}
+
+func (t *Tuple) String() string {
+	return "(" + typeList(t.Types) + ")"
+}
+
+func typeList(list []Type) string {
+	names := make([]string, len(list))
+	for i, t := range list {
+		names[i] = t.String()
+	}
+	return strings.Join(names, ", ")
+}
+
+func isBasic(t Type, kinds ...BasicKind) bool {
+	b, ok := t.(*Basic)
+	if !ok {
+		return false
+	}
+	for _, k := range kinds {
+		if b.Kind == k {
+			return true
+		}
+	}
+	return false
+}
+
+func IsInvalid(t Type) bool {
+	return t == nil || isBasic(t, Invalid)
+}
+
+func IsBoolean(t Type) bool {
+	return isBasic(t, Bool)
+}
+
+func IsInteger(t Type) bool {
+	return isBasic(t, I8, I16, I32, I64, U8, U16, U32, U64, UntypedInt)
+}
+
+func IsUnsigned(t Type) bool {
+	return isBasic(t, U8, U16, U32, U64)
+}
+
+func IsFloat(t Type) bool {
+	return isBasic(t, F32, F64, UntypedFloat)
+}
+
+func IsUntyped(t Type) bool {
+	return isBasic(t, UntypedInt, UntypedFloat)
+}
+
+func IsString(t Type) bool {
+	return isBasic(t, String)
+}
+
+func IsNumeric(t Type) bool {
+	return IsInteger(t) || IsFloat(t)
+}
+
+func IsOrdered(t Type) bool {
+	return IsNumeric(t)
+}
+
+// IsConcatenable reports whether values of t can be joined with +.
+func IsConcatenable(t Type) bool {
+	return IsString(t)
+}
+
+// IsComparable reports whether values of t can be compared with == and !=.
+func IsComparable(t Type) bool {
+	_, ok := t.(*Basic)
+	return ok && !IsInvalid(t)
+}
+
+func Identical(x, y Type) bool {
+	if x == y {
+		return true
+	}
+
+	switch x := x.(type) {
+	case *Signature:
+		y, ok := y.(*Signature)
+		return ok && identicalList(x.Params, y.Params) && identicalList(x.Results, y.Results)
+	case *Tuple:
+		y, ok := y.(*Tuple)
+		return ok && identicalList(x.Types, y.Types)
+	case *Array:
+		y, ok := y.(*Array)
+		return ok && x.Len == y.Len && Identical(x.Elem, y.Elem)
+	case *Slice:
+		y, ok := y.(*Slice)
+		return ok && Identical(x.Elem, y.Elem)
+	}
+
+	return false
+}
+
+func identicalList(x, y []Type) bool {
+	if len(x) != len(y) {
+		return false
+	}
+	for i := range x {
+		if !Identical(x[i], y[i]) {
+			return false
+		}
+	}
+	return true
+}